@@ -3,6 +3,7 @@ package cache_test
 import (
 	"context"
 	"encoding/binary"
+	"math/rand"
 	"strconv"
 	"sync"
 	"testing"
@@ -112,3 +113,32 @@ func Benchmark_FailoverAlwaysBuild(b *testing.B) {
 		})
 	}
 }
+
+func Benchmark_Trait_TTL_concurrent(b *testing.B) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		cfg  cache.Config
+	}{
+		{name: "default"},
+		{name: "rand_source", cfg: cache.Config{RandSource: rand.NewSource(1)}},
+	} {
+		tc := tc
+
+		b.Run(tc.name, func(b *testing.B) {
+			t := cache.NewTrait(tc.cfg)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if t.TTL(ctx) == 0 {
+						b.Fail()
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,305 @@
+// Package boltcache provides persistent cache backend on top of bbolt database.
+package boltcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"time"
+
+	"github.com/bool64/cache"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	_ cache.ReadWriter = &Bolt{}
+	_ cache.Deleter    = &Bolt{}
+)
+
+// headerSize is a size of expiration timestamp and grace period that precede gob-encoded entry.
+const headerSize = 16
+
+// Bolt is a persistent cache backend that stores gob-encoded entries in a bbolt bucket.
+//
+// Please use NewBolt to create it.
+type Bolt struct {
+	db     *bbolt.DB
+	bucket []byte
+
+	t *cache.Trait
+}
+
+// NewBolt creates an instance of cache backed by bbolt bucket, bucket is created if it does not exist.
+//
+// Values are serialized with encoding/gob, therefore it is necessary to
+// register cached types in advance with cache.GobRegister.
+//
+// Expired entries are deleted in background after Config.DeleteExpiredAfter or grace period
+// (see cache.WithGracePeriod), unless pinned with Config.CanEvict.
+// Eviction options (Config.CountSoftLimit, Config.MaxBytes, etc.) are not supported.
+func NewBolt(db *bbolt.DB, bucket []byte, options ...func(cfg *cache.Config)) (*Bolt, error) {
+	cfg := cache.Config{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	c := &Bolt{
+		db:     db,
+		bucket: bucket,
+	}
+
+	c.t = cache.NewTrait(cfg, func(t *cache.Trait) {
+		t.DeleteExpired = c.deleteExpired
+		t.Len = c.Len
+	})
+
+	return c, nil
+}
+
+// Close stops background jobs, it does not close the database.
+func (c *Bolt) Close() error {
+	c.t.Close()
+
+	return nil
+}
+
+// Read gets value.
+func (c *Bolt) Read(ctx context.Context, key []byte) (interface{}, error) {
+	if cache.SkipRead(ctx) {
+		return nil, cache.ErrNotFound
+	}
+
+	var (
+		e     cache.TraitEntry
+		found bool
+	)
+
+	key = c.t.Key(ctx, key)
+
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(c.bucket).Get(key)
+		if v == nil {
+			return nil
+		}
+
+		found = true
+
+		return decode(v, &e)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return c.t.PrepareRead(ctx, nil, false)
+	}
+
+	return c.t.PrepareRead(ctx, &e, true)
+}
+
+// Write sets value by the key.
+func (c *Bolt) Write(ctx context.Context, key []byte, v interface{}) error {
+	key, ok, err := c.t.PrepareWrite(ctx, key, v)
+	if !ok {
+		return err
+	}
+
+	e, ttl := c.t.NewEntry(ctx, key, v)
+	buf := bytes.NewBuffer(make([]byte, headerSize))
+
+	if err := gob.NewEncoder(buf).Encode(e); err != nil {
+		return err
+	}
+
+	b := buf.Bytes()
+	binary.BigEndian.PutUint64(b[:8], uint64(e.E))
+	binary.BigEndian.PutUint64(b[8:headerSize], uint64(e.G))
+
+	if err := c.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(c.bucket).Put(key, b)
+	}); err != nil {
+		return err
+	}
+
+	c.t.NotifyWritten(ctx, key, v, ttl)
+
+	return nil
+}
+
+// Delete removes value by the key.
+//
+// It fails with ErrNotFound if key does not exist, unless Config.IgnoreMissingDelete is set.
+func (c *Bolt) Delete(ctx context.Context, key []byte) error {
+	var e *cache.TraitEntry
+
+	key = c.t.Key(ctx, key)
+
+	if err := c.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(c.bucket)
+
+		v := b.Get(key)
+		if v == nil {
+			return cache.ErrNotFound
+		}
+
+		if c.t.Config.OnEvicted != nil {
+			e = &cache.TraitEntry{}
+			if err := decode(v, e); err != nil {
+				e = nil
+			}
+		}
+
+		return b.Delete(key)
+	}); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return c.t.MissingDelete()
+		}
+
+		return err
+	}
+
+	c.t.NotifyDeleted(ctx, key)
+
+	if e != nil {
+		c.t.NotifyRemoved(e.K, e.V, cache.EvictReasonDeleted)
+	}
+
+	return nil
+}
+
+// Len returns number of entries including expired.
+func (c *Bolt) Len() int {
+	n := 0
+
+	_ = c.db.View(func(tx *bbolt.Tx) error { //nolint:errcheck // Counting does not fail.
+		n = tx.Bucket(c.bucket).Stats().KeyN
+
+		return nil
+	})
+
+	return n
+}
+
+// Stats returns a snapshot of cache activity counters.
+func (c *Bolt) Stats() cache.CacheStats {
+	return c.t.Stats()
+}
+
+// expiredEntry is a candidate for deletion, header is used to skip entries that were replaced meanwhile.
+type expiredEntry struct {
+	key    []byte
+	header []byte
+	e      *cache.TraitEntry
+}
+
+// deleteExpired removes entries that expired before a boundary with respect to grace period.
+//
+// Candidates are collected in a read transaction, Config.CanEvict and Config.OnEvicted are called
+// outside of transactions, so that they can read cache.
+func (c *Bolt) deleteExpired(before time.Time) {
+	var expired []expiredEntry
+
+	err := c.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(c.bucket).ForEach(func(k, v []byte) error {
+			if len(v) < headerSize {
+				return nil
+			}
+
+			expireAt := int64(binary.BigEndian.Uint64(v[:8]))
+			grace := int64(binary.BigEndian.Uint64(v[8:headerSize]))
+
+			if !c.t.DeleteExpiredBefore(expireAt, grace, before) {
+				return nil
+			}
+
+			x := expiredEntry{
+				key:    append([]byte(nil), k...),
+				header: append([]byte(nil), v[:headerSize]...),
+			}
+
+			if c.t.Config.OnEvicted != nil || c.t.Config.CanEvict != nil {
+				x.e = &cache.TraitEntry{}
+				if err := decode(v, x.e); err != nil {
+					x.e = nil
+				}
+			}
+
+			expired = append(expired, x)
+
+			return nil
+		})
+	})
+
+	if err == nil && len(expired) > 0 {
+		err = c.deleteEntries(expired)
+	}
+
+	if err != nil && c.t.Config.Logger != nil {
+		c.t.Config.Logger.Error(context.Background(), "failed to delete expired cache entries",
+			"name", c.t.Config.Name, "error", err)
+	}
+}
+
+// deleteEntries removes expired entries that are not pinned by Config.CanEvict and not replaced meanwhile.
+func (c *Bolt) deleteEntries(expired []expiredEntry) error {
+	candidates := expired[:0]
+
+	for _, x := range expired {
+		if x.e != nil && !c.t.CanEvict(x.e) {
+			continue
+		}
+
+		candidates = append(candidates, x)
+	}
+
+	deleted := candidates[:0]
+
+	if err := c.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(c.bucket)
+
+		for _, x := range candidates {
+			// Entry that was replaced meanwhile is kept.
+			if v := b.Get(x.key); v == nil || len(v) < headerSize || !bytes.Equal(v[:headerSize], x.header) {
+				continue
+			}
+
+			if err := b.Delete(x.key); err != nil {
+				return err
+			}
+
+			deleted = append(deleted, x)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	c.t.NotifyDeletedExpired(len(deleted))
+
+	for _, x := range deleted {
+		if x.e != nil {
+			c.t.NotifyRemoved(x.e.K, x.e.V, cache.EvictReasonExpired)
+		}
+	}
+
+	return nil
+}
+
+func decode(v []byte, e *cache.TraitEntry) error {
+	if len(v) < headerSize {
+		return errors.New("malformed cache entry")
+	}
+
+	return gob.NewDecoder(bytes.NewReader(v[headerSize:])).Decode(e)
+}
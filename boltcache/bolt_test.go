@@ -0,0 +1,189 @@
+package boltcache_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/cache/boltcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestNewBolt(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	db, err := bbolt.Open(path, 0o600, nil)
+	require.NoError(t, err)
+
+	c, err := boltcache.NewBolt(db, []byte("cache"), func(cfg *cache.Config) {
+		cfg.TimeToLive = time.Hour
+		cfg.ExpirationJitter = -1
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	require.NoError(t, c.Write(cache.WithTTL(ctx, time.Millisecond, false), []byte("short"), 123))
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	_, err = c.Read(ctx, []byte("baz"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+	time.Sleep(2 * time.Millisecond)
+
+	_, err = c.Read(ctx, []byte("short"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+
+	var errExpired cache.ErrWithExpiredItem
+
+	require.True(t, errors.As(err, &errExpired))
+	assert.Equal(t, 123, errExpired.Value())
+
+	assert.True(t, errors.Is(c.Delete(ctx, []byte("baz")), cache.ErrNotFound))
+	assert.Equal(t, 2, c.Len())
+
+	require.NoError(t, c.Close())
+	require.NoError(t, db.Close())
+
+	// Entries survive reopening of database.
+	db, err = bbolt.Open(path, 0o600, nil)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	c, err = boltcache.NewBolt(db, []byte("cache"), func(cfg *cache.Config) {
+		cfg.DeleteExpiredAfter = time.Nanosecond
+		cfg.DeleteExpiredJobInterval = time.Millisecond
+	})
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, c.Close())
+	}()
+
+	v, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 1
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, c.Delete(ctx, []byte("foo")))
+	assert.Equal(t, 0, c.Len())
+}
+
+func newBolt(t *testing.T, options ...func(cfg *cache.Config)) *boltcache.Bolt {
+	t.Helper()
+
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "cache.db"), 0o600, nil)
+	require.NoError(t, err)
+
+	c, err := boltcache.NewBolt(db, []byte("cache"), options...)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, c.Close())
+		require.NoError(t, db.Close())
+	})
+
+	return c
+}
+
+func TestBolt_keys(t *testing.T) {
+	ctx := context.Background()
+	c := newBolt(t, func(cfg *cache.Config) {
+		cfg.KeyFunc = func(key []byte) []byte { return append([]byte("k:"), key...) }
+		cfg.MaxKeySize = 5
+		cfg.MaxValueSize = 10
+		cfg.IgnoreMissingDelete = true
+	})
+
+	ctxA := cache.WithKeyPrefix(ctx, []byte("a:"))
+	ctxB := cache.WithKeyPrefix(ctx, []byte("b:"))
+
+	require.NoError(t, c.Write(ctxA, []byte("foo"), "a"))
+	require.NoError(t, c.Write(ctxB, []byte("foo"), "b"))
+	require.NoError(t, c.Write(cache.WithSkipWrite(ctx), []byte("foo"), "skipped"))
+
+	assert.True(t, errors.Is(c.Write(ctx, []byte("foobar"), "v"), cache.ErrKeyTooLarge))
+	assert.True(t, errors.Is(c.Write(ctx, []byte("foo"), strings.Repeat("v", 100)), cache.ErrValueTooLarge))
+
+	v, err := c.Read(ctxA, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", v)
+
+	v, err = c.Read(ctxB, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", v)
+
+	_, err = c.Read(ctx, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+	require.NoError(t, c.Delete(ctxA, []byte("foo")))
+	require.NoError(t, c.Delete(ctxA, []byte("foo")))
+	assert.Equal(t, 1, c.Len())
+
+	v, err = c.Read(ctxB, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", v)
+}
+
+func TestBolt_deleteExpired(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mu      sync.Mutex
+		evicted []string
+	)
+
+	c := newBolt(t, func(cfg *cache.Config) {
+		cfg.TimeToLive = time.Millisecond
+		cfg.ExpirationJitter = -1
+		cfg.DeleteExpiredAfter = time.Nanosecond
+		cfg.DeleteExpiredJobInterval = time.Millisecond
+		cfg.CanEvict = func(e cache.Entry) bool {
+			return string(e.Key()) != "pinned"
+		}
+		cfg.OnEvicted = func(key []byte, value interface{}, reason cache.EvictReason) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			assert.Equal(t, cache.EvictReasonExpired, reason)
+			evicted = append(evicted, string(key))
+		}
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("expired"), 1))
+	require.NoError(t, c.Write(ctx, []byte("pinned"), 2))
+	require.NoError(t, c.Write(cache.WithGracePeriod(ctx, time.Hour), []byte("grace"), 3))
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 2
+	}, time.Second, time.Millisecond)
+
+	// Expired entries are kept during grace period and while pinned.
+	_, err := c.Read(ctx, []byte("grace"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+
+	_, err = c.Read(ctx, []byte("pinned"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, []string{"expired"}, evicted)
+	assert.Equal(t, int64(1), c.Stats().Deletes)
+}
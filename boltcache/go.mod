@@ -0,0 +1,19 @@
+module github.com/bool64/cache/boltcache
+
+go 1.21
+
+replace github.com/bool64/cache => ../
+
+require (
+	github.com/bool64/cache v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.1
+	go.etcd.io/bbolt v1.3.10
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.9.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -17,6 +17,8 @@ const (
 // Reader reads from cache.
 type Reader interface {
 	// Read returns cached value or error.
+	//
+	// Nil is a valid cached value, it is returned with nil error, while missing value fails with ErrNotFound.
 	Read(ctx context.Context, key []byte) (interface{}, error)
 }
 
@@ -44,6 +46,7 @@ type Entry interface {
 	Key() []byte
 	Value() interface{}
 	ExpireAt() time.Time
+	CreatedAt() time.Time
 }
 
 // Walker calls function for every entry in cache and fails on first error returned by that function.
@@ -38,4 +38,5 @@ type EntryOf[V any] interface {
 	Key() []byte
 	Value() V
 	ExpireAt() time.Time
+	CreatedAt() time.Time
 }
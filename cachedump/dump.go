@@ -0,0 +1,100 @@
+// Package cachedump provides warm restart of cache with dump to a file on shutdown signal.
+package cachedump
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/bool64/cache"
+)
+
+// DumpOnSignal writes cache dump to a file when process receives one of signals.
+//
+// If no signals are provided, os.Interrupt and syscall.SIGTERM are used.
+// Returned channel receives result of dump and is closed after that, so that shutdown can wait for dump
+// to complete. Signal handler is unregistered after first signal.
+func DumpOnSignal(c cache.Dumper, path string, sig ...os.Signal) <-chan error {
+	if len(sig) == 0 {
+		sig = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+
+	signal.Notify(signals, sig...)
+
+	go func() {
+		<-signals
+		signal.Stop(signals)
+
+		_, err := DumpToFile(c, path)
+		done <- err
+
+		close(done)
+	}()
+
+	return done
+}
+
+// DumpToFile writes cache dump to a file and returns number of dumped entries.
+//
+// Dump is written to a temporary file in the same directory that replaces destination with atomic rename,
+// so that a broken dump never replaces a previous one.
+func DumpToFile(c cache.Dumper, path string) (int, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+
+	w := bufio.NewWriter(f)
+
+	n, err := c.Dump(w)
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// RestoreFromFile loads cache dump from a file and returns number of restored entries.
+//
+// Missing file is not an error, for example on the first start of application, zero entries are restored then.
+func RestoreFromFile(c cache.Restorer, path string) (int, error) {
+	f, err := os.Open(path) //nolint:gosec // Path is provided by application.
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return c.Restore(bufio.NewReader(f))
+}
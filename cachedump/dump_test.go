@@ -0,0 +1,63 @@
+package cachedump_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/cache/cachedump"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpOnSignal(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.dump")
+
+	n, err := cachedump.RestoreFromFile(cache.NewSyncMap(), path)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	c1 := cache.NewSyncMap()
+	require.NoError(t, c1.Write(ctx, []byte("foo"), "bar"))
+	require.NoError(t, c1.Write(ctx, []byte("baz"), 123))
+
+	done := cachedump.DumpOnSignal(c1, path, os.Interrupt)
+
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, p.Signal(os.Interrupt))
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("dump timeout")
+	}
+
+	c2 := cache.NewSyncMap()
+
+	n, err = cachedump.RestoreFromFile(c2, path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	v, err := c2.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	// Temporary files are not left behind.
+	files, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestRestoreFromFile_broken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.dump")
+	require.NoError(t, os.WriteFile(path, []byte("broken"), 0o600))
+
+	_, err := cachedump.RestoreFromFile(cache.NewSyncMap(), path)
+	assert.ErrorIs(t, err, cache.ErrBadDumpFormat)
+}
@@ -0,0 +1,115 @@
+// Package cachehttp provides HTTP handler to inspect and manage cache in a running application
+// and caching http.RoundTripper for upstream responses.
+package cachehttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bool64/cache"
+)
+
+// KeysPrefix is a path prefix to access cache entries, it is followed by a key.
+const KeysPrefix = "/keys/"
+
+// Entry is a JSON representation of cache entry.
+type Entry struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	ExpireAt *time.Time  `json:"expireAt,omitempty"`
+	Expired  bool        `json:"expired"`
+}
+
+// HTTPHandler creates HTTP handler to inspect and manage cache.
+//
+// Handler serves paths relative to its mount point (use http.StripPrefix if needed):
+//   - GET /keys/{key} to read entry without affecting stats,
+//   - DELETE /keys/{key} to delete entry,
+//   - GET /stats to read cache stats,
+//   - POST /expire-all to expire all entries.
+func HTTPHandler(c *cache.SyncMap) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, KeysPrefix):
+			key := []byte(strings.TrimPrefix(r.URL.Path, KeysPrefix))
+
+			switch r.Method {
+			case http.MethodGet:
+				get(rw, r, c, key)
+			case http.MethodDelete:
+				if err := c.Delete(r.Context(), key); err != nil {
+					status := http.StatusInternalServerError
+					if errors.Is(err, cache.ErrNotFound) {
+						status = http.StatusNotFound
+					}
+
+					http.Error(rw, err.Error(), status)
+
+					return
+				}
+
+				rw.WriteHeader(http.StatusNoContent)
+			default:
+				http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			}
+		case r.URL.Path == "/stats":
+			if r.Method != http.MethodGet {
+				http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+
+				return
+			}
+
+			writeJSON(rw, c.Stats())
+		case r.URL.Path == "/expire-all":
+			if r.Method != http.MethodPost {
+				http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+
+				return
+			}
+
+			writeJSON(rw, map[string]int{"expired": c.ExpireAllCount(r.Context())})
+		default:
+			http.NotFound(rw, r)
+		}
+	})
+}
+
+func get(rw http.ResponseWriter, r *http.Request, c *cache.SyncMap, key []byte) {
+	v, expireAt, err := c.Peek(r.Context(), key)
+	e := Entry{Key: string(key), Value: v}
+
+	var errExpired cache.ErrWithExpiredItem
+
+	switch {
+	case err == nil:
+	case errors.As(err, &errExpired):
+		e.Value = errExpired.Value()
+		e.Expired = true
+		expireAt = errExpired.ExpiredAt()
+	case errors.Is(err, cache.ErrNotFound):
+		http.Error(rw, err.Error(), http.StatusNotFound)
+
+		return
+	default:
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if !expireAt.IsZero() {
+		e.ExpireAt = &expireAt
+	}
+
+	writeJSON(rw, e)
+}
+
+func writeJSON(rw http.ResponseWriter, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+	}
+}
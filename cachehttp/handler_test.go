@@ -0,0 +1,59 @@
+package cachehttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/cache/cachehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewSyncMap()
+
+	require.NoError(t, c.Write(cache.WithTTL(ctx, time.Hour, false), []byte("foo"), "bar"))
+
+	h := cachehttp.HTTPHandler(c)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req, err := http.NewRequest(method, path, nil)
+		require.NoError(t, err)
+
+		h.ServeHTTP(rw, req)
+
+		return rw
+	}
+
+	rw := do(http.MethodGet, "/keys/foo")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), `"key":"foo","value":"bar","expireAt":"`)
+	assert.Contains(t, rw.Body.String(), `"expired":false`)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/keys/baz").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPut, "/keys/foo").Code)
+
+	rw = do(http.MethodPost, "/expire-all")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, `{"expired":1}`+"\n", rw.Body.String())
+
+	rw = do(http.MethodGet, "/keys/foo")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), `"expired":true`)
+
+	rw = do(http.MethodGet, "/stats")
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), `"writes":1`)
+	assert.Contains(t, rw.Body.String(), `"items":1`)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/keys/foo").Code)
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/keys/foo").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/unknown").Code)
+}
@@ -0,0 +1,253 @@
+package cachehttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bool64/cache"
+)
+
+// HTTPCacheConfig is optional configuration for NewHTTPCache.
+type HTTPCacheConfig struct {
+	// Transport performs upstream requests, default http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// StaleIfError enables serving of stale response if upstream request fails or responds with 5xx status.
+	StaleIfError bool
+
+	// Logger collects messages with context.
+	Logger cache.Logger
+}
+
+// Use is a functional option for NewHTTPCache to apply configuration.
+func (hc HTTPCacheConfig) Use(cfg *HTTPCacheConfig) {
+	*cfg = hc
+}
+
+// Response is a cached HTTP response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// HTTPCache is a cache-aside http.RoundTripper for upstream GET responses.
+//
+// Please use NewHTTPCache to create instance.
+type HTTPCache struct {
+	backend cache.ReadWriter
+	config  HTTPCacheConfig
+}
+
+var _ http.RoundTripper = &HTTPCache{}
+
+// NewHTTPCache creates http.RoundTripper that caches GET responses in backend by request URL.
+//
+// Responses with status 200 and positive max-age in Cache-Control are stored with max-age (minus Age) as TTL.
+// Responses with no-store, no-cache, private or Vary are not cached. Requests with Cache-Control or
+// Authorization headers bypass cache.
+//
+// Expired response is revalidated with If-None-Match and If-Modified-Since if it has ETag or Last-Modified,
+// so backend should keep expired entries (see cache.Config.DeleteExpiredAfter). Cached values are of
+// *Response type, please register it with cache.GobRegister if backend is dumped.
+func NewHTTPCache(backend cache.ReadWriter, options ...func(cfg *HTTPCacheConfig)) *HTTPCache {
+	cfg := HTTPCacheConfig{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+
+	return &HTTPCache{
+		backend: backend,
+		config:  cfg,
+	}
+}
+
+// RoundTrip serves cached response or performs upstream request.
+func (c *HTTPCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Cache-Control") != "" || req.Header.Get("Authorization") != "" ||
+		req.Header.Get("Range") != "" {
+		return c.config.Transport.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	key := []byte(req.URL.String())
+
+	var stale *Response
+
+	v, err := c.backend.Read(ctx, key)
+
+	// Soft expired response is still fresh.
+	if errors.Is(err, cache.ErrSoftExpired) {
+		v, _, _ = cache.StaleValue(err)
+		err = nil
+	}
+
+	if err == nil {
+		if cached, ok := v.(*Response); ok {
+			return cached.response(req), nil
+		}
+	} else if sv, _, ok := cache.StaleValue(err); ok {
+		stale, _ = sv.(*Response)
+	}
+
+	upstream := req
+
+	if stale != nil {
+		upstream = revalidation(req, stale)
+	}
+
+	resp, err := c.config.Transport.RoundTrip(upstream)
+
+	if stale != nil {
+		if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+			if c.config.StaleIfError {
+				if resp != nil {
+					_ = resp.Body.Close()
+				}
+
+				return stale.response(req), nil
+			}
+
+			return resp, err
+		}
+
+		if resp.StatusCode == http.StatusNotModified {
+			_ = resp.Body.Close()
+
+			header := stale.Header.Clone()
+			if header == nil {
+				header = make(http.Header)
+			}
+
+			for k, vv := range resp.Header {
+				header[k] = vv
+			}
+
+			revalidated := &Response{StatusCode: stale.StatusCode, Header: header, Body: stale.Body}
+
+			c.store(ctx, key, revalidated)
+
+			return revalidated.response(req), nil
+		}
+	}
+
+	if err != nil || resp.StatusCode != http.StatusOK || ttl(resp.Header) <= 0 {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(ctx, key, &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body})
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+// store writes response with TTL derived from its headers.
+func (c *HTTPCache) store(ctx context.Context, key []byte, r *Response) {
+	t := ttl(r.Header)
+	if t <= 0 {
+		return
+	}
+
+	ctx = cache.WithNoJitter(ctx)
+
+	var err error
+
+	if w, ok := c.backend.(interface {
+		WriteWithTTL(ctx context.Context, k []byte, v interface{}, ttl time.Duration) error
+	}); ok {
+		err = w.WriteWithTTL(ctx, key, r, t)
+	} else {
+		err = c.backend.Write(cache.WithTTL(ctx, t, false), key, r)
+	}
+
+	if err != nil && c.config.Logger != nil {
+		c.config.Logger.Error(ctx, "failed to cache http response",
+			"error", err,
+			"url", string(key))
+	}
+}
+
+// revalidation creates conditional request from validators of stale response.
+func revalidation(req *http.Request, stale *Response) *http.Request {
+	etag, lastModified := stale.Header.Get("ETag"), stale.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return req
+	}
+
+	r := req.Clone(req.Context())
+
+	if etag != "" {
+		r.Header.Set("If-None-Match", etag)
+	}
+
+	if lastModified != "" {
+		r.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	return r
+}
+
+// response creates http.Response for request.
+func (r *Response) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(r.StatusCode) + " " + http.StatusText(r.StatusCode),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// ttl returns freshness lifetime of cacheable response, zero is returned for responses that should not be cached.
+func ttl(h http.Header) time.Duration {
+	if h.Get("Vary") != "" {
+		return 0
+	}
+
+	maxAge := -1
+
+	for _, d := range strings.Split(h.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(d), "=")
+
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			return 0
+		case "max-age":
+			if v, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				maxAge = v
+			}
+		}
+	}
+
+	if age, err := strconv.Atoi(h.Get("Age")); err == nil && age > 0 {
+		maxAge -= age
+	}
+
+	if maxAge <= 0 {
+		return 0
+	}
+
+	return time.Duration(maxAge) * time.Second
+}
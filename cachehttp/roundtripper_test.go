@@ -0,0 +1,140 @@
+package cachehttp_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/cache/cachehttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPCache(t *testing.T) {
+	var (
+		hits, notModified int64
+		fail              int32
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+
+		if atomic.LoadInt32(&fail) == 1 {
+			http.Error(rw, "failed", http.StatusBadGateway)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/no-store":
+			rw.Header().Set("Cache-Control", "no-store")
+		default:
+			rw.Header().Set("Cache-Control", "public, max-age=60")
+			rw.Header().Set("ETag", `"v1"`)
+
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddInt64(&notModified, 1)
+				rw.WriteHeader(http.StatusNotModified)
+
+				return
+			}
+		}
+
+		_, _ = rw.Write([]byte("hello " + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := cache.NewSyncMap()
+	hc := cachehttp.NewHTTPCache(c, cachehttp.HTTPCacheConfig{StaleIfError: true}.Use)
+	client := http.Client{Transport: hc}
+
+	get := func(path string) string {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, resp.Body.Close())
+		}()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return string(body)
+	}
+
+	assert.Equal(t, "hello /foo", get("/foo"))
+	assert.Equal(t, "hello /foo", get("/foo"))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&hits))
+
+	_, expireAt, err := c.Peek(ctx, []byte(srv.URL+"/foo"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expireAt, 5*time.Second)
+
+	assert.Equal(t, "hello /no-store", get("/no-store"))
+	assert.Equal(t, "hello /no-store", get("/no-store"))
+	assert.Equal(t, int64(3), atomic.LoadInt64(&hits))
+
+	// Stale entry is revalidated with ETag.
+	c.ExpireAll(ctx)
+
+	assert.Equal(t, "hello /foo", get("/foo"))
+	assert.Equal(t, int64(4), atomic.LoadInt64(&hits))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&notModified))
+
+	assert.Equal(t, "hello /foo", get("/foo"))
+	assert.Equal(t, int64(4), atomic.LoadInt64(&hits))
+
+	// Stale entry is served on upstream failure.
+	c.ExpireAll(ctx)
+	atomic.StoreInt32(&fail, 1)
+
+	assert.Equal(t, "hello /foo", get("/foo"))
+	assert.Equal(t, int64(5), atomic.LoadInt64(&hits))
+}
+
+func TestNewHTTPCache_softExpired(t *testing.T) {
+	var hits int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		rw.Header().Set("Cache-Control", "public, max-age=60")
+		_, _ = rw.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.SoftTTLFraction = 0.0001
+	})
+	client := http.Client{Transport: cachehttp.NewHTTPCache(c)}
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, "hello", string(body))
+
+		// Response passes soft expiration of 6ms.
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&hits))
+}
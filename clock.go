@@ -0,0 +1,38 @@
+package cache
+
+import "time"
+
+// Clock provides current time.
+//
+// If Clock also implements After(d time.Duration) <-chan time.Time, it is used for timers of background jobs.
+type Clock interface {
+	Now() time.Time
+}
+
+type afterClock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// now returns current time of Config.Clock.
+func (c *Trait) now() time.Time {
+	if c.Config.Clock == nil {
+		return time.Now()
+	}
+
+	return c.Config.Clock.Now()
+}
+
+// after waits for duration to elapse with Config.Clock.
+func (c *Trait) after(d time.Duration) <-chan time.Time {
+	if ac, ok := c.Config.Clock.(afterClock); ok {
+		return ac.After(d)
+	}
+
+	return time.After(d)
+}
@@ -0,0 +1,165 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), ch: ch})
+
+	return ch
+}
+
+// Add advances the clock and fires due timers.
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	pending := c.timers[:0]
+
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+
+			continue
+		}
+
+		t.ch <- c.now
+	}
+
+	c.timers = pending
+}
+
+func TestConfig_Clock(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+		cfg.DeleteExpiredAfter = time.Hour
+		cfg.DeleteExpiredJobInterval = time.Minute
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+
+	clock.Add(59 * time.Second)
+
+	v, err := c.Read(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	clock.Add(2 * time.Second)
+
+	_, err = c.Read(ctx, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+	assert.Equal(t, 1, c.Len())
+
+	// Janitor removes entry after DeleteExpiredAfter.
+	assert.Eventually(t, func() bool {
+		clock.Add(time.Minute)
+
+		return c.Len() == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestConfig_ExpirationIndexBucket(t *testing.T) {
+	ctx := context.Background()
+
+	for name, newCache := range map[string]func(cfg func(cfg *cache.Config)) interface {
+		cache.ReadWriter
+		Len() int
+	}{
+		"sync_map": func(cfg func(cfg *cache.Config)) interface {
+			cache.ReadWriter
+			Len() int
+		} {
+			return cache.NewSyncMap(cfg)
+		},
+		"sharded_map": func(cfg func(cfg *cache.Config)) interface {
+			cache.ReadWriter
+			Len() int
+		} {
+			return cache.NewShardedMap(cfg)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			clock := newFakeClock()
+
+			c := newCache(func(cfg *cache.Config) {
+				cfg.Clock = clock
+				cfg.TimeToLive = time.Minute
+				cfg.ExpirationJitter = -1
+				cfg.DeleteExpiredAfter = time.Minute
+				cfg.DeleteExpiredJobInterval = 10 * time.Second
+				cfg.ExpirationIndexBucket = 10 * time.Second
+				cfg.IntervalJitter = -1
+			})
+
+			assert.NoError(t, c.Write(ctx, []byte("foo"), 1))
+			assert.NoError(t, c.Write(ctx, []byte("bar"), 1))
+			assert.NoError(t, c.Write(cache.WithTTL(ctx, time.Hour, false), []byte("baz"), 1))
+
+			// Updated entry is moved to a later bucket.
+			clock.Add(30 * time.Second)
+			assert.NoError(t, c.Write(cache.WithTTL(ctx, 10*time.Minute, false), []byte("bar"), 2))
+
+			assert.Eventually(t, func() bool {
+				clock.Add(time.Second)
+
+				return c.Len() <= 2
+			}, time.Second, time.Millisecond)
+
+			v, err := c.Read(ctx, []byte("bar"))
+			assert.NoError(t, err)
+			assert.Equal(t, 2, v)
+
+			assert.Eventually(t, func() bool {
+				clock.Add(10 * time.Second)
+
+				return c.Len() == 1
+			}, time.Second, time.Millisecond)
+
+			v, err = c.Read(ctx, []byte("baz"))
+			assert.NoError(t, err)
+			assert.Equal(t, 1, v)
+		})
+	}
+}
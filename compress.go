@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+)
+
+// Headers of values stored by Compress middleware.
+const (
+	compressNone    = byte(0)
+	compressDeflate = byte(1)
+)
+
+// Compress creates middleware that compresses large values with deflate.
+//
+// Values are gob-encoded on Write, please register cached types in advance with GobRegister.
+// Encoded values of at least minSize bytes are compressed, smaller values are stored uncompressed
+// to avoid overhead. Stored value starts with a header byte that indicates compression.
+func Compress(minSize int) Middleware {
+	return func(next ReadWriter) ReadWriter {
+		return codec{
+			next: next,
+			encode: func(v interface{}) (interface{}, error) {
+				return compress(v, minSize)
+			},
+			decode: decompress,
+		}
+	}
+}
+
+func compress(v interface{}, minSize int) (interface{}, error) {
+	b, err := gobEncodeValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) < minSize {
+		return append([]byte{compressNone}, b...), nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(b)/2))
+	buf.WriteByte(compressDeflate)
+
+	w, err := flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decompress(v interface{}) (interface{}, error) {
+	b, ok := v.([]byte)
+	if !ok || len(b) == 0 {
+		return nil, fmt.Errorf("%w: %T", ErrUnexpectedType, v)
+	}
+
+	switch b[0] {
+	case compressNone:
+		return gobDecodeValue(b[1:])
+	case compressDeflate:
+		r := flate.NewReader(bytes.NewReader(b[1:]))
+
+		plain, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return gobDecodeValue(plain)
+	}
+
+	return nil, fmt.Errorf("%w: compression header %d", ErrUnexpectedType, b[0])
+}
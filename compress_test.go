@@ -0,0 +1,44 @@
+package cache_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bool64/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	ctx := context.Background()
+	base := cache.NewShardedMap()
+	c := cache.Chain(base, cache.Compress(100))
+
+	large := strings.Repeat(`{"name":"foo","value":"bar"},`, 100)
+
+	require.NoError(t, c.Write(ctx, []byte("small"), "foo"))
+	require.NoError(t, c.Write(ctx, []byte("large"), large))
+
+	v, err := c.Read(ctx, []byte("small"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", v)
+
+	v, err = c.Read(ctx, []byte("large"))
+	require.NoError(t, err)
+	assert.Equal(t, large, v)
+
+	raw, err := base.Read(ctx, []byte("small"))
+	require.NoError(t, err)
+	assert.Equal(t, byte(0), raw.([]byte)[0])
+
+	raw, err = base.Read(ctx, []byte("large"))
+	require.NoError(t, err)
+	assert.Equal(t, byte(1), raw.([]byte)[0])
+	assert.Less(t, len(raw.([]byte)), len(large)/5)
+
+	require.NoError(t, base.Write(ctx, []byte("bad"), []byte{7}))
+
+	_, err = c.Read(ctx, []byte("bad"))
+	assert.ErrorIs(t, err, cache.ErrUnexpectedType)
+}
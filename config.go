@@ -1,6 +1,9 @@
 package cache
 
-import "time"
+import (
+	"math/rand"
+	"time"
+)
 
 // Config controls cache instance.
 type Config struct {
@@ -16,23 +19,97 @@ type Config struct {
 	// ItemsCountReportInterval is items count metric report interval, default 1m.
 	ItemsCountReportInterval time.Duration
 
+	// HitRatioWindow is a duration of rolling window to calculate hit ratio, default 5m.
+	// Hit ratio is sampled with items count report and on Stats calls, so window precision
+	// is bounded by ItemsCountReportInterval.
+	HitRatioWindow time.Duration
+
+	// TTLSampleSize is a number of entries to sample with items count report to report MetricAvgTTL
+	// and MetricTTLRemaining, default 0 (disabled). Sampling avoids full scan of a large cache.
+	TTLSampleSize int
+
 	// Expiration controls.
 
 	// TimeToLive is delay before entry expiration, default 5m.
 	// Use UnlimitedTTL value to set up unlimited TTL.
 	TimeToLive time.Duration
 
+	// MissTTL is delay before expiration of a cached miss written with WriteMiss, default TimeToLive.
+	MissTTL time.Duration
+
 	// DeleteExpiredAfter is delay before expired entry is deleted from cache, default 24h.
 	DeleteExpiredAfter time.Duration
 
 	// DeleteExpiredJobInterval is delay between two consecutive cleanups, default 1h.
 	DeleteExpiredJobInterval time.Duration
 
+	// ExpirationIndexBucket enables index of expiration time with buckets of this width, default 0 (disabled).
+	// With index, delete expired job only checks entries of passed buckets instead of scanning whole cache,
+	// at cost of additional work on writes. It is useful for large caches where few entries expire per job.
+	ExpirationIndexBucket time.Duration
+
+	// SoftTTLFraction is a fraction of entry time to live after which entry is soft expired, default 0 (disabled).
+	// Read of soft expired entry fails with ErrSoftExpired that carries the value, it is still valid till
+	// hard expiration. Failover serves such value and refreshes it in background (refresh-ahead).
+	SoftTTLFraction float64
+
 	// ExpirationJitter is a fraction of TTL to randomize, default 0.1.
 	// Use -1 to disable.
 	// If enabled, entry TTL will be randomly altered in bounds of ±(ExpirationJitter * TTL / 2).
+	// Jitter applies to all writes with default or context TTL and to WriteWithTTL,
+	// it can be disabled for a particular write with WithNoJitter context.
 	ExpirationJitter float64
 
+	// IntervalJitter is a fraction of DeleteExpiredJobInterval and ItemsCountReportInterval to randomize, default 0.1.
+	// Use -1 to disable.
+	// Jitter spreads background jobs of multiple instances that were started simultaneously.
+	IntervalJitter float64
+
+	// Clock provides current time for expiration, default is system clock.
+	Clock Clock
+
+	// RandSource is a source of randomness for ExpirationJitter, IntervalJitter and LogSampleRate,
+	// default is internal generator.
+	// Seeded source allows reproducible expiration times.
+	RandSource rand.Source
+
+	// Generation is stamped on written entries, entries of a different generation are considered missing
+	// on read and are skipped on restore. Bumping generation invalidates entries of previous dumps, for example
+	// after a change of cached type. Zero generation is not serialized for backwards compatibility.
+	Generation uint64
+
+	// Serializer encodes entries of Dump and decodes entries of Restore, default nil (encoding/gob).
+	// Dumps of different serializers are not compatible.
+	Serializer Serializer
+
+	// MaxValueSize is a maximum size of a value in bytes, writes of larger values fail with ErrValueTooLarge,
+	// default 0 (no limit).
+	MaxValueSize int
+
+	// MaxKeySize is a maximum length of a key in bytes, writes of longer keys fail with ErrKeyTooLarge,
+	// default 0 (no limit). Limit applies to a key before Config.KeyFunc and context key prefix.
+	MaxKeySize int
+
+	// MaxKeySizeAfterKeyFunc makes MaxKeySize apply to a resulting key after Config.KeyFunc and context key prefix.
+	MaxKeySizeAfterKeyFunc bool
+
+	// Sizer is a function to estimate value size for MaxValueSize and MaxBytes checks,
+	// default returns length of []byte and string values and length of gob encoding for others.
+	Sizer func(value interface{}) int
+
+	// SkipEqualWrites makes Write of a value equal to the existing one only refresh expiration of the entry
+	// (like Touch) instead of replacing it, such writes are not counted as MetricWrite.
+	SkipEqualWrites bool
+
+	// Equal is a function to compare values for SkipEqualWrites, default compares strings and []byte directly
+	// and uses reflect.DeepEqual for others.
+	Equal func(a, b interface{}) bool
+
+	// AutoGobRegister enables automatic registration of value types with encoding/gob on first write,
+	// so that Dump does not fail for types that were not registered with GobRegister.
+	// This adds a reflection lookup on every write and does not contribute to GobTypesHash.
+	AutoGobRegister bool
+
 	// Eviction controls.
 	//
 	// Eviction is a part of delete expired job, eviction runs at most once per delete expired job and
@@ -45,21 +122,100 @@ type Config struct {
 	// SysMemSoftLimit sets system memory (runtime.MemStats).Sys threshold when eviction will be triggered.
 	SysMemSoftLimit uint64
 
+	// MemStatsCacheTTL is a duration to reuse last reading of runtime.MemStats for memory soft limits, default 1s.
+	// Use -1 to read fresh stats on every check.
+	// Reading runtime.MemStats stops the world briefly, caching reduces latency impact of successive checks.
+	MemStatsCacheTTL time.Duration
+
 	// CountSoftLimit sets count threshold when eviction will be triggered.
 	// As opposed to memory soft limits, when count limit is exceeded, eviction will remove items to achieve
 	// the level of CountSoftLimit*(1-EvictFraction), which may be more items that EvictFraction defines.
 	CountSoftLimit uint64
 
+	// MaxBytes sets threshold of approximate total size of stored values when eviction will be triggered.
+	// Sizes are estimated with Sizer on writes and tracked without reading runtime.MemStats.
+	// As with CountSoftLimit, eviction removes entries to achieve the level of MaxBytes*(1-EvictFraction).
+	MaxBytes int64
+
+	// CountHardLimit sets maximum count of entries, it is enforced on writes of new entries.
+	// When limit is exceeded, excessive entries are evicted synchronously with EvictionStrategy,
+	// or write fails with ErrCacheFull if RejectOnFull is enabled.
+	// CountHardLimit is expected to be larger than CountSoftLimit, so that soft limit eviction in background
+	// usually keeps count below hard limit.
+	CountHardLimit uint64
+
+	// RejectOnFull makes writes of new entries fail with ErrCacheFull when CountHardLimit is reached.
+	RejectOnFull bool
+
+	// IgnoreMissingDelete makes Delete of a non-existent key succeed instead of failing with ErrNotFound.
+	IgnoreMissingDelete bool
+
 	// EvictionNeeded is a user-defined function to decide whether eviction is necessary.
 	// If true is returned, eviction cycle will happen.
 	EvictionNeeded func() bool
 
+	// OnEvict is called synchronously after eviction with a trigger (EvictTriggerHeap, EvictTriggerCount, etc.)
+	// and a number of evicted entries, it should be fast.
+	OnEvict func(trigger string, count int)
+
+	// CanEvict is consulted before deletion of expired entries and before eviction, returning false pins entry
+	// in memory regardless of age, so that it keeps serving stale value (for example last known good).
+	// It is called outside of map locks, so it can read cache, but it must not write to cache: eviction is
+	// serialized and a write that needs eviction (for example with CountHardLimit) would wait for the callback.
+	// It should be fast, as it is called for every expired entry during cleanup and for eviction candidates.
+	CanEvict func(e Entry) bool
+
+	// PinnedWarnThreshold is a number of expired entries pinned by CanEvict to log a warning after
+	// expiration cleanup, default 1000. Pinned entries are not limited by eviction and may grow unbounded.
+	PinnedWarnThreshold int
+
+	// OnEvicted is called for every entry removed from cache by Delete, DeleteAll, expiration cleanup or eviction.
+	// It is invoked outside of internal locks, so it is safe to access cache from the callback.
+	OnEvicted func(key []byte, value interface{}, reason EvictReason)
+
+	// LogSampleRate is a fraction (0, 1) of cache reads to be logged at debug level, default is to log all reads.
+	// Other logs are not sampled.
+	LogSampleRate float64
+
+	// MetricKeyGroupFunc maps a key to a "group" label of MetricHit and MetricMiss, no label is added by default.
+	//
+	// WARNING: every distinct group creates a separate time series, function must return a small fixed set of
+	// values (e.g. key namespace), never return key itself or other unbounded values.
+	MetricKeyGroupFunc func(key []byte) string
+
+	// KeyFunc transforms keys before access, it can be used to normalize or hash keys.
+	// Key prefix from context (see WithKeyPrefix) is added before transformation.
+	// Resulting key is stored in cache entry, so that Walk and Dump expose transformed keys.
+	// Function must be deterministic and safe for concurrent use.
+	KeyFunc func(key []byte) []byte
+
+	// RecoverCallbacks enables recovery of panics in user callbacks, panics are logged as important and
+	// cache continues with a fallback: OnEvicted and OnEvict calls are skipped, KeyFunc keeps original key,
+	// Sizer counts zero size, EvictionNeeded and Equal return false, CanEvict returns true and MetricKeyGroupFunc
+	// returns empty group.
+	// Panic of Loading loader is returned as ErrCallbackPanic error.
+	// By default, panics are not recovered and may crash background jobs.
+	RecoverCallbacks bool
+
 	// EvictFraction is a fraction (0, 1] of total count of items to be evicted when resource is overused,
 	// default 0.1 (10% of items).
 	EvictFraction float64
 
+	// EvictMinItems is a minimal number of entries to evict when resource is overused, default 1.
+	// It ensures eviction progress for small caches where EvictFraction of items rounds down to zero.
+	EvictMinItems int
+
+	// EvictMinAge protects entries created less than EvictMinAge ago from eviction, default 0 (no protection).
+	// If all entries are younger, eviction falls back to regular candidates to make progress.
+	EvictMinAge time.Duration
+
 	// EvictionStrategy is EvictMostExpired by default.
 	EvictionStrategy EvictionStrategy
+
+	// LFUDecayInterval is a period to halve usage counters of entries with EvictLeastFrequentlyUsed strategy,
+	// default 0 (no decay).
+	// Decay prevents entries that were popular long ago from staying in cache forever.
+	LFUDecayInterval time.Duration
 }
 
 // EvictionStrategy defines eviction behavior when soft limit is met during cleanup job.
@@ -80,6 +236,61 @@ const (
 	EvictLeastFrequentlyUsed
 )
 
+// Eviction triggers, also used as "trigger" label of MetricEvict.
+const (
+	// EvictTriggerHeap indicates eviction caused by HeapInUseSoftLimit.
+	EvictTriggerHeap = "heap"
+
+	// EvictTriggerSys indicates eviction caused by SysMemSoftLimit.
+	EvictTriggerSys = "sys"
+
+	// EvictTriggerCount indicates eviction caused by CountSoftLimit.
+	EvictTriggerCount = "count"
+
+	// EvictTriggerBytes indicates eviction caused by MaxBytes.
+	EvictTriggerBytes = "bytes"
+
+	// EvictTriggerCustom indicates eviction caused by EvictionNeeded.
+	EvictTriggerCustom = "custom"
+
+	// EvictTriggerWrite indicates synchronous eviction on write caused by CountHardLimit.
+	EvictTriggerWrite = "write"
+)
+
+// EvictReason explains why entry was removed from cache.
+type EvictReason int
+
+// Removal reasons.
+const (
+	// EvictReasonDeleted indicates explicit removal with Delete or DeleteAll.
+	EvictReasonDeleted = EvictReason(iota)
+
+	// EvictReasonExpired indicates removal of expired entry by background cleanup.
+	EvictReasonExpired
+
+	// EvictReasonCapacity indicates eviction caused by count limits or EvictionNeeded.
+	EvictReasonCapacity
+
+	// EvictReasonMemory indicates eviction caused by HeapInUseSoftLimit, SysMemSoftLimit or MaxBytes.
+	EvictReasonMemory
+)
+
+// String returns reason name.
+func (r EvictReason) String() string {
+	switch r {
+	case EvictReasonDeleted:
+		return "deleted"
+	case EvictReasonExpired:
+		return "expired"
+	case EvictReasonCapacity:
+		return "capacity"
+	case EvictReasonMemory:
+		return "memory"
+	default:
+		return "unknown"
+	}
+}
+
 // Use is a functional option to apply configuration.
 func (c Config) Use(cfg *Config) {
 	*cfg = c
@@ -6,8 +6,16 @@ import (
 )
 
 type (
-	skipReadCtxKey struct{}
-	ttlCtxKey      struct{}
+	skipReadCtxKey      struct{}
+	skipWriteCtxKey     struct{}
+	ttlCtxKey           struct{}
+	forceRefreshCtxKey  struct{}
+	keyPrefixCtxKey     struct{}
+	gracePeriodCtxKey   struct{}
+	noJitterCtxKey      struct{}
+	ttlMultiplierCtxKey struct{}
+	maxStalenessCtxKey  struct{}
+	logFieldsCtxKey     struct{}
 )
 
 // WithTTL adds cache time to live information to context.
@@ -63,6 +71,148 @@ func SkipRead(ctx context.Context) bool {
 	return ok && v
 }
 
+// WithSkipWrite returns context with cache write ignored.
+//
+// With such context cache.Writer should not store value and return nil error.
+func WithSkipWrite(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipWriteCtxKey{}, true)
+}
+
+// SkipWrite returns true if cache write is ignored in context.
+func SkipWrite(ctx context.Context) bool {
+	v, ok := ctx.Value(skipWriteCtxKey{}).(bool)
+
+	return ok && v
+}
+
+// WithForceRefresh returns context with forced cache refresh.
+//
+// With such context Failover.Get builds value synchronously and writes it to cache
+// discarding cached value and recent build failures.
+func WithForceRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRefreshCtxKey{}, true)
+}
+
+// ForceRefresh returns true if cache refresh is forced in context.
+func ForceRefresh(ctx context.Context) bool {
+	v, ok := ctx.Value(forceRefreshCtxKey{}).(bool)
+
+	return ok && v
+}
+
+// WithKeyPrefix returns context with a prefix that is prepended to cache keys.
+//
+// It can be used to isolate keys of different tenants that share a cache instance.
+// Nested prefixes are concatenated.
+func WithKeyPrefix(ctx context.Context, prefix []byte) context.Context {
+	if existing := KeyPrefix(ctx); len(existing) > 0 {
+		prefix = append(existing[:len(existing):len(existing)], prefix...)
+	}
+
+	return context.WithValue(ctx, keyPrefixCtxKey{}, prefix)
+}
+
+// KeyPrefix returns key prefix from context, nil is returned by default.
+func KeyPrefix(ctx context.Context) []byte {
+	p, _ := ctx.Value(keyPrefixCtxKey{}).([]byte)
+
+	return p
+}
+
+// WithGracePeriod returns context with a delay of deletion after expiration for written entries.
+//
+// Grace period overrides Config.DeleteExpiredAfter for entries written with such context,
+// it can be shorter to reclaim memory of large values promptly or longer to serve stale values longer.
+func WithGracePeriod(ctx context.Context, grace time.Duration) context.Context {
+	return context.WithValue(ctx, gracePeriodCtxKey{}, grace)
+}
+
+// GracePeriod returns grace period from context, zero value is returned by default.
+func GracePeriod(ctx context.Context) time.Duration {
+	g, _ := ctx.Value(gracePeriodCtxKey{}).(time.Duration)
+
+	return g
+}
+
+// WithNoJitter returns context with expiration jitter disabled for written entries.
+//
+// It is useful for entries that must expire exactly at a known time, for example cached credentials,
+// so that jitter does not extend their lifetime beyond validity.
+func WithNoJitter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noJitterCtxKey{}, true)
+}
+
+// NoJitter returns true if expiration jitter is disabled in context.
+func NoJitter(ctx context.Context) bool {
+	v, ok := ctx.Value(noJitterCtxKey{}).(bool)
+
+	return ok && v
+}
+
+// WithTTLMultiplier returns context with a factor to scale time to live of written entries.
+//
+// It can be used to temporarily extend TTL of all entries, for example to shield a struggling backend
+// during an incident. Factor applies to default and explicit TTL, non-positive factor is ignored.
+func WithTTLMultiplier(ctx context.Context, factor float64) context.Context {
+	return context.WithValue(ctx, ttlMultiplierCtxKey{}, factor)
+}
+
+// TTLMultiplier returns TTL factor from context, 1 is returned by default.
+func TTLMultiplier(ctx context.Context) float64 {
+	f, ok := ctx.Value(ttlMultiplierCtxKey{}).(float64)
+	if !ok || f <= 0 {
+		return 1
+	}
+
+	return f
+}
+
+// WithMaxStaleness returns context with maximum age of entries to read.
+//
+// Entry that was created more than maxStaleness ago is treated as missing and read fails with ErrNotFound,
+// even if it is not expired by TTL. It allows call sites to require fresher values than stored TTL.
+// Entries without creation time, for example restored from a dump of an older version, are not constrained.
+func WithMaxStaleness(ctx context.Context, maxStaleness time.Duration) context.Context {
+	return context.WithValue(ctx, maxStalenessCtxKey{}, maxStaleness)
+}
+
+// MaxStaleness returns maximum age of entries to read from context, zero value is returned by default.
+func MaxStaleness(ctx context.Context) time.Duration {
+	d, _ := ctx.Value(maxStalenessCtxKey{}).(time.Duration)
+
+	return d
+}
+
+// tooStale checks if entry created at timestamp exceeds maximum age from context.
+func tooStale(ctx context.Context, created, now int64) bool {
+	if created == 0 {
+		return false
+	}
+
+	d := MaxStaleness(ctx)
+
+	return d > 0 && now-created > int64(d)
+}
+
+// WithLogFields returns context with key-value pairs to add to debug and important logs of cache operations.
+//
+// It allows correlation of cache logs with request, for example by request or trace id.
+// Nested fields are appended to existing ones.
+func WithLogFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	if existing := LogFields(ctx); len(existing) > 0 {
+		keysAndValues = append(existing[:len(existing):len(existing)], keysAndValues...)
+	}
+
+	return context.WithValue(ctx, logFieldsCtxKey{}, keysAndValues)
+}
+
+// LogFields returns key-value pairs for logs from context, nil is returned by default.
+func LogFields(ctx context.Context) []interface{} {
+	f, _ := ctx.Value(logFieldsCtxKey{}).([]interface{})
+
+	return f
+}
+
 // detachedContext exposes parent values, but suppresses parent cancellation.
 type detachedContext struct {
 	parent context.Context //nolint:containedctx // This wrapping is here on purpose.
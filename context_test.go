@@ -2,7 +2,9 @@ package cache_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/bool64/cache"
 	"github.com/stretchr/testify/assert"
@@ -14,3 +16,218 @@ func TestIsSkipCache(t *testing.T) {
 	assert.True(t, cache.SkipRead(cache.WithSkipRead(ctx)))
 	assert.False(t, cache.SkipRead(ctx))
 }
+
+func TestForceRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	assert.True(t, cache.ForceRefresh(cache.WithForceRefresh(ctx)))
+	assert.False(t, cache.ForceRefresh(ctx))
+}
+
+func TestWithKeyPrefix(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Nil(t, cache.KeyPrefix(ctx))
+
+	tenant := cache.WithKeyPrefix(ctx, []byte("t1:"))
+	assert.Equal(t, "t1:", string(cache.KeyPrefix(tenant)))
+	assert.Equal(t, "t1:users:", string(cache.KeyPrefix(cache.WithKeyPrefix(tenant, []byte("users:")))))
+
+	for _, c := range []interface {
+		cache.ReadWriter
+		cache.Deleter
+		cache.Walker
+	}{cache.NewShardedMap(), cache.NewSyncMap()} {
+		assert.NoError(t, c.Write(tenant, []byte("foo"), 1))
+		assert.NoError(t, c.Write(cache.WithKeyPrefix(ctx, []byte("t2:")), []byte("foo"), 2))
+
+		v, err := c.Read(tenant, []byte("foo"))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+
+		_, err = c.Read(ctx, []byte("foo"))
+		assert.Error(t, err)
+
+		v, err = c.Read(ctx, []byte("t2:foo"))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, v)
+
+		assert.NoError(t, c.Delete(tenant, []byte("foo")))
+
+		keys := 0
+		_, err = c.Walk(func(e cache.Entry) error {
+			keys++
+
+			assert.Equal(t, "t2:foo", string(e.Key()))
+
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, keys)
+	}
+}
+
+func TestWithSkipWrite(t *testing.T) {
+	ctx := context.Background()
+
+	assert.True(t, cache.SkipWrite(cache.WithSkipWrite(ctx)))
+	assert.False(t, cache.SkipWrite(ctx))
+
+	for _, c := range []interface {
+		cache.ReadWriter
+		Len() int
+	}{cache.NewShardedMap(), cache.NewSyncMap()} {
+		assert.NoError(t, c.Write(cache.WithSkipWrite(ctx), []byte("foo"), 1))
+		assert.Equal(t, 0, c.Len())
+
+		_, err := c.Read(ctx, []byte("foo"))
+		assert.Error(t, err)
+	}
+}
+
+func TestWithGracePeriod(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Equal(t, time.Duration(0), cache.GracePeriod(ctx))
+	assert.Equal(t, time.Minute, cache.GracePeriod(cache.WithGracePeriod(ctx, time.Minute)))
+
+	type lenReadWriter interface {
+		cache.ReadWriter
+		Len() int
+	}
+
+	for _, newCache := range []func(options ...func(cfg *cache.Config)) lenReadWriter{
+		func(options ...func(cfg *cache.Config)) lenReadWriter { return cache.NewShardedMap(options...) },
+		func(options ...func(cfg *cache.Config)) lenReadWriter { return cache.NewSyncMap(options...) },
+	} {
+		clock := newFakeClock()
+		c := newCache(func(cfg *cache.Config) {
+			cfg.Clock = clock
+			cfg.TimeToLive = time.Minute
+			cfg.ExpirationJitter = -1
+			cfg.DeleteExpiredAfter = time.Hour
+			cfg.DeleteExpiredJobInterval = time.Minute
+		})
+
+		assert.NoError(t, c.Write(cache.WithGracePeriod(ctx, time.Minute), []byte("short"), 1))
+		assert.NoError(t, c.Write(ctx, []byte("default"), 2))
+		assert.NoError(t, c.Write(cache.WithGracePeriod(ctx, 3*time.Hour), []byte("long"), 3))
+
+		for _, key := range []string{"short", "default", "long"} {
+			assert.Eventually(t, func() bool {
+				clock.Add(time.Minute)
+
+				_, err := c.Read(ctx, []byte(key))
+
+				return errors.Is(err, cache.ErrNotFound)
+			}, time.Second, time.Millisecond, key)
+
+			// Entries with longer grace period are still available as stale.
+			switch key {
+			case "short":
+				assert.Equal(t, 2, c.Len())
+			case "default":
+				assert.Equal(t, 1, c.Len())
+			}
+		}
+	}
+}
+
+func TestWithNoJitter(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	assert.False(t, cache.NoJitter(ctx))
+	assert.True(t, cache.NoJitter(cache.WithNoJitter(ctx)))
+
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Hour
+		cfg.ExpirationJitter = 0.5
+	})
+
+	noJitter := cache.WithNoJitter(ctx)
+
+	assert.NoError(t, c.Write(noJitter, []byte("foo"), 1))
+	assert.NoError(t, c.WriteWithTTL(noJitter, []byte("bar"), 2, time.Minute))
+	assert.NoError(t, c.Write(cache.WithTTL(noJitter, 2*time.Minute, false), []byte("baz"), 3))
+
+	for key, ttl := range map[string]time.Duration{"foo": time.Hour, "bar": time.Minute, "baz": 2 * time.Minute} {
+		_, exp, err := c.Peek(ctx, []byte(key))
+		assert.NoError(t, err)
+		assert.True(t, clock.Now().Add(ttl).Equal(exp), key)
+	}
+}
+
+func TestWithTTLMultiplier(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	assert.Equal(t, 1.0, cache.TTLMultiplier(ctx))
+	assert.Equal(t, 1.0, cache.TTLMultiplier(cache.WithTTLMultiplier(ctx, 0)))
+	assert.Equal(t, 1.0, cache.TTLMultiplier(cache.WithTTLMultiplier(ctx, -2)))
+	assert.Equal(t, 3.0, cache.TTLMultiplier(cache.WithTTLMultiplier(ctx, 3)))
+
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Hour
+		cfg.ExpirationJitter = -1
+	})
+
+	degraded := cache.WithTTLMultiplier(ctx, 3)
+
+	assert.NoError(t, c.Write(degraded, []byte("foo"), 1))
+	assert.NoError(t, c.WriteWithTTL(degraded, []byte("bar"), 2, time.Minute))
+	assert.NoError(t, c.Write(cache.WithTTL(degraded, 2*time.Minute, false), []byte("baz"), 3))
+	assert.NoError(t, c.Write(cache.WithTTLMultiplier(ctx, 0), []byte("qux"), 4))
+	assert.NoError(t, c.WriteWithTTL(cache.WithTTLMultiplier(ctx, 1e20), []byte("quux"), 5, time.Hour))
+
+	for key, ttl := range map[string]time.Duration{
+		"foo": 3 * time.Hour,
+		"bar": 3 * time.Minute,
+		"baz": 6 * time.Minute,
+		"qux": time.Hour,
+	} {
+		_, exp, err := c.Peek(ctx, []byte(key))
+		assert.NoError(t, err)
+		assert.True(t, clock.Now().Add(ttl).Equal(exp), key)
+	}
+
+	_, exp, err := c.Peek(ctx, []byte("quux"))
+	assert.NoError(t, err)
+	assert.True(t, exp.After(clock.Now().Add(100*365*24*time.Hour)))
+}
+
+func TestWithMaxStaleness(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	assert.Equal(t, time.Duration(0), cache.MaxStaleness(ctx))
+	assert.Equal(t, time.Minute, cache.MaxStaleness(cache.WithMaxStaleness(ctx, time.Minute)))
+
+	cfg := func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Hour
+	}
+
+	for name, c := range map[string]cache.ReadWriter{
+		"sync_map":    cache.NewSyncMap(cfg),
+		"sharded_map": cache.NewShardedMap(cfg),
+	} {
+		assert.NoError(t, c.Write(ctx, []byte("foo"), 1), name)
+
+		clock.Add(2 * time.Minute)
+
+		v, err := c.Read(cache.WithMaxStaleness(ctx, 5*time.Minute), []byte("foo"))
+		assert.NoError(t, err, name)
+		assert.Equal(t, 1, v, name)
+
+		// Entry is not expired, but it is too old for the caller.
+		_, err = c.Read(cache.WithMaxStaleness(ctx, time.Minute), []byte("foo"))
+		assert.ErrorIs(t, err, cache.ErrNotFound, name)
+
+		v, err = c.Read(ctx, []byte("foo"))
+		assert.NoError(t, err, name)
+		assert.Equal(t, 1, v, name)
+	}
+}
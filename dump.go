@@ -0,0 +1,332 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"reflect"
+)
+
+// Dump format is a header of magic bytes and format version, followed by gob stream of entries and
+// CRC32 (IEEE) of the gob stream.
+const (
+	dumpMagic   = "BCD"
+	dumpVersion = byte(1)
+	crcSize     = 4
+)
+
+// DumpOptions configures dump encoding.
+type DumpOptions struct {
+	// Compress enables gzip compression of dump stream.
+	// Restore detects compressed dumps automatically.
+	Compress bool
+
+	// Sorted enables ordering of entries by key to have reproducible dumps.
+	Sorted bool
+
+	// Snapshot makes SyncMap dump entries that were in cache at the beginning of dump (see SyncMap.WalkSnapshot),
+	// it needs memory for references to all entries. Sorted dumps are always taken from a snapshot.
+	Snapshot bool
+}
+
+// Serializer encodes and decodes cache entries of dump payload, see Config.Serializer.
+//
+// Dump header, compression and checksum are handled by cache, so Serializer only deals with entries.
+type Serializer interface {
+	// Encode writes entry to dump stream.
+	Encode(w io.Writer, e Entry) error
+
+	// Decode reads next entry from dump stream, it must return io.EOF at the end of stream.
+	//
+	// Reader implements io.ByteReader, so that Decode can read a single entry without buffering.
+	// Decoded *TraitEntry (or TraitEntry) keeps generation and grace period of the original entry,
+	// other implementations of Entry are restored with key, value, expiration and creation time.
+	Decode(r io.Reader) (Entry, error)
+}
+
+// entryEncoder encodes entries of dump payload, implemented by *gob.Encoder.
+type entryEncoder interface {
+	Encode(e interface{}) error
+}
+
+// entryDecoder decodes entries of dump payload into a pointer, implemented by *gob.Decoder.
+type entryDecoder interface {
+	Decode(e interface{}) error
+}
+
+// serializerCodec adapts Serializer to entryEncoder and entryDecoder.
+type serializerCodec struct {
+	s Serializer
+	w io.Writer
+	r io.Reader
+}
+
+func (c serializerCodec) Encode(v interface{}) error {
+	switch e := v.(type) {
+	case interface{ untyped() TraitEntry }:
+		te := e.untyped()
+
+		return c.s.Encode(c.w, &te)
+	case Entry:
+		return c.s.Encode(c.w, e)
+	default:
+		return fmt.Errorf("%w: unexpected entry type %T", ErrUnexpectedType, v)
+	}
+}
+
+func (c serializerCodec) Decode(v interface{}) error {
+	e, err := c.s.Decode(c.r)
+	if err != nil {
+		return err
+	}
+
+	te := traitEntry(e)
+
+	switch t := v.(type) {
+	case *TraitEntry:
+		*t = te
+
+		return nil
+	case interface{ setUntyped(te TraitEntry) error }:
+		return t.setUntyped(te)
+	default:
+		return fmt.Errorf("%w: unexpected entry type %T", ErrUnexpectedType, v)
+	}
+}
+
+// gobEncoder skips entries with nil pointer values, because encoding/gob can not encode them.
+//
+// Skipped entries are counted to exclude them from number of dumped entries.
+type gobEncoder struct {
+	*gob.Encoder
+	skipped int
+}
+
+func (g *gobEncoder) Encode(v interface{}) error {
+	if e, ok := v.(*TraitEntry); ok && isNilPointer(e.V) {
+		g.skipped++
+
+		return nil
+	}
+
+	return g.Encoder.Encode(v)
+}
+
+// isNilPointer checks if value is a typed nil pointer.
+func isNilPointer(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+
+	rv := reflect.ValueOf(v)
+
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// traitEntry converts Entry to TraitEntry.
+func traitEntry(e Entry) TraitEntry {
+	switch t := e.(type) {
+	case *TraitEntry:
+		return *t
+	case TraitEntry:
+		return t
+	}
+
+	te := TraitEntry{K: e.Key(), V: e.Value()}
+
+	if exp := e.ExpireAt(); !exp.IsZero() && exp.UnixNano() != 0 {
+		te.E = ts(exp)
+	}
+
+	if created := e.CreatedAt(); !created.IsZero() {
+		te.T = ts(created)
+	}
+
+	return te
+}
+
+// gzipMagic is a header of gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// dumpWriter writes dump header, payload and trailing checksum.
+type dumpWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+}
+
+func newDumpWriter(w io.Writer) (*dumpWriter, error) {
+	if _, err := w.Write(append([]byte(dumpMagic), dumpVersion)); err != nil {
+		return nil, err
+	}
+
+	return &dumpWriter{w: w, crc: crc32.NewIEEE()}, nil
+}
+
+func (d *dumpWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	_, _ = d.crc.Write(p[:n])
+
+	return n, err
+}
+
+// Close writes checksum of payload.
+func (d *dumpWriter) Close() error {
+	var sum [crcSize]byte
+
+	binary.BigEndian.PutUint32(sum[:], d.crc.Sum32())
+
+	_, err := d.w.Write(sum[:])
+
+	return err
+}
+
+// dumpReader reads payload of dump and holds back trailing checksum.
+type dumpReader struct {
+	r       io.Reader
+	crc     hash.Hash32
+	buf     []byte
+	scratch [4096]byte
+	err     error
+}
+
+func newDumpReader(r io.Reader) (*dumpReader, error) {
+	header := make([]byte, len(dumpMagic)+1)
+
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrBadDumpFormat
+	}
+
+	if !bytes.Equal(header[:len(dumpMagic)], []byte(dumpMagic)) || header[len(dumpMagic)] != dumpVersion {
+		return nil, ErrBadDumpFormat
+	}
+
+	return &dumpReader{r: r, crc: crc32.NewIEEE()}, nil
+}
+
+func (d *dumpReader) Read(p []byte) (int, error) {
+	for {
+		// Last bytes are not available to payload reader until the end of stream is reached.
+		if len(d.buf) > crcSize {
+			n := copy(p, d.buf[:len(d.buf)-crcSize])
+			_, _ = d.crc.Write(p[:n])
+			d.buf = append(d.buf[:0], d.buf[n:]...)
+
+			return n, nil
+		}
+
+		if d.err != nil {
+			return 0, d.err
+		}
+
+		n, err := d.r.Read(d.scratch[:])
+		d.buf = append(d.buf, d.scratch[:n]...)
+
+		if err != nil {
+			d.err = err
+		}
+	}
+}
+
+// verify checks trailing checksum after payload is read.
+func (d *dumpReader) verify() error {
+	if len(d.buf) != crcSize || binary.BigEndian.Uint32(d.buf) != d.crc.Sum32() {
+		return ErrDumpChecksum
+	}
+
+	return nil
+}
+
+// encodeDump writes dump with entries encoded by walk function.
+//
+// Entries are encoded with gob unless custom Serializer is provided.
+func encodeDump(w io.Writer, opts DumpOptions, s Serializer, walk func(encoder entryEncoder) (int, error)) (int, error) {
+	if opts.Compress {
+		zw := gzip.NewWriter(w)
+
+		n, err := encodeDump(zw, DumpOptions{}, s, walk)
+		if err != nil {
+			return n, err
+		}
+
+		return n, zw.Close()
+	}
+
+	dw, err := newDumpWriter(w)
+	if err != nil {
+		return 0, err
+	}
+
+	ge := &gobEncoder{Encoder: gob.NewEncoder(dw)}
+
+	var encoder entryEncoder = ge
+	if s != nil {
+		encoder = serializerCodec{s: s, w: dw}
+	}
+
+	n, err := walk(encoder)
+	n -= ge.skipped
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, dw.Close()
+}
+
+// decodeDump reads dump with decode function invoked for every entry until io.EOF and validates checksum.
+//
+// Gzip compressed dump is decompressed transparently.
+//
+// Corrupted payload is reported as ErrDumpChecksum.
+func decodeDump(r io.Reader, s Serializer, decode func(decoder entryDecoder) error) error {
+	br := bufio.NewReader(r)
+	r = br
+
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			_ = zr.Close()
+		}()
+
+		r = zr
+	}
+
+	d, err := newDumpReader(r)
+	if err != nil {
+		return err
+	}
+
+	var decoder entryDecoder = gob.NewDecoder(d)
+	if s != nil {
+		decoder = serializerCodec{s: s, r: bufio.NewReader(d)}
+	}
+
+	for {
+		err := decode(decoder)
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, io.EOF) {
+			return d.verify()
+		}
+
+		// Draining payload to check if decoding failed due to corruption.
+		if _, cerr := io.Copy(io.Discard, d); cerr == nil && d.verify() != nil {
+			return ErrDumpChecksum
+		}
+
+		return err
+	}
+}
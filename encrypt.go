@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+// Encrypt creates middleware that encrypts values with AES-GCM.
+//
+// Values are gob-encoded and encrypted on Write, so that backend and its dumps only contain ciphertext,
+// please register cached types in advance with GobRegister. Key must be 16, 24 or 32 bytes long to select
+// AES-128, AES-192 or AES-256. New values are encrypted with key, values are decrypted with key or any of
+// oldKeys to allow key rotation.
+//
+// Read fails with ErrDecryptionFailed if value can not be authenticated with any of the keys.
+func Encrypt(key []byte, oldKeys ...[]byte) (Middleware, error) {
+	aeads := make([]cipher.AEAD, 0, len(oldKeys)+1)
+
+	for _, k := range append([][]byte{key}, oldKeys...) {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		aeads = append(aeads, aead)
+	}
+
+	return func(next ReadWriter) ReadWriter {
+		return codec{
+			next: next,
+			encode: func(v interface{}) (interface{}, error) {
+				return encrypt(aeads[0], v)
+			},
+			decode: func(v interface{}) (interface{}, error) {
+				return decrypt(aeads, v)
+			},
+		}
+	}, nil
+}
+
+// encrypt returns nonce followed by sealed gob-encoded value.
+func encrypt(aead cipher.AEAD, v interface{}) (interface{}, error) {
+	plain, err := gobEncodeValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func decrypt(aeads []cipher.AEAD, v interface{}) (interface{}, error) {
+	sealed, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnexpectedType, v)
+	}
+
+	for _, aead := range aeads {
+		if len(sealed) < aead.NonceSize() {
+			break
+		}
+
+		plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+		if err != nil {
+			continue
+		}
+
+		return gobDecodeValue(plain)
+	}
+
+	return nil, ErrDecryptionFailed
+}
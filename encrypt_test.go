@@ -0,0 +1,85 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypt(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	_, err := cache.Encrypt([]byte("short"))
+	require.Error(t, err)
+
+	base := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+	})
+
+	encOld, err := cache.Encrypt(oldKey)
+	require.NoError(t, err)
+
+	c := cache.Chain(base, encOld)
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "secret value"))
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret value", v)
+
+	// Backend and dump only hold ciphertext.
+	raw, err := base.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw.([]byte)), "secret value")
+
+	dump := bytes.NewBuffer(nil)
+	_, err = base.Dump(dump)
+	require.NoError(t, err)
+	assert.NotContains(t, dump.String(), "secret value")
+
+	// Rotated key can still read values of old key.
+	encNew, err := cache.Encrypt(newKey, oldKey)
+	require.NoError(t, err)
+
+	c = cache.Chain(base, encNew)
+
+	v, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret value", v)
+
+	require.NoError(t, c.Write(ctx, []byte("bar"), 123))
+
+	// Values of unknown key fail authentication.
+	c = cache.Chain(base, encOld)
+
+	_, err = c.Read(ctx, []byte("bar"))
+	assert.ErrorIs(t, err, cache.ErrDecryptionFailed)
+
+	// Stale value is decrypted.
+	clock.Add(2 * time.Minute)
+
+	c = cache.Chain(base, encNew)
+
+	_, err = c.Read(ctx, []byte("bar"))
+	require.ErrorIs(t, err, cache.ErrExpired)
+
+	var errExp cache.ErrWithExpiredItem
+
+	require.True(t, errors.As(err, &errExp))
+	assert.Equal(t, 123, errExp.Value())
+	assert.True(t, clock.Now().Add(-time.Minute).Equal(errExp.ExpiredAt()))
+
+	_, err = c.Read(ctx, []byte("baz"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+}
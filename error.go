@@ -1,15 +1,23 @@
 package cache
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // SentinelError is an error.
 type SentinelError string
 
 const (
 	// ErrExpired indicates expired cache entry,
-	// may implement ErrWithExpiredItem to enable stale value serving.
+	// may implement ErrWithExpiredItem to enable stale value serving, see StaleValue.
 	ErrExpired = SentinelError("expired cache item")
 
+	// ErrSoftExpired indicates valid cache entry that has passed soft expiration of Config.SoftTTLFraction,
+	// it implements ErrWithExpiredItem to carry the value that can be served while refreshing, see StaleValue.
+	ErrSoftExpired = SentinelError("soft expired cache item")
+
 	// ErrNotFound indicates missing cache entry.
 	ErrNotFound = SentinelError("missing cache item")
 
@@ -21,8 +29,45 @@ const (
 
 	// ErrUnexpectedType is thrown on failed type assertion.
 	ErrUnexpectedType = SentinelError("unexpected type")
+
+	// ErrKeyTooLarge indicates rejected write of a key that exceeds Config.MaxKeySize.
+	ErrKeyTooLarge = SentinelError("cache key too large")
+
+	// ErrValueTooLarge indicates rejected write of a value that exceeds Config.MaxValueSize.
+	ErrValueTooLarge = SentinelError("cache value too large")
+
+	// ErrCacheFull indicates rejected write of a new entry when Config.CountHardLimit is reached.
+	ErrCacheFull = SentinelError("cache is full")
+
+	// ErrBadDumpFormat indicates dump without valid header, for example written by an incompatible version.
+	ErrBadDumpFormat = SentinelError("bad dump format")
+
+	// ErrDumpChecksum indicates truncated or corrupted dump.
+	ErrDumpChecksum = SentinelError("dump checksum mismatch")
+
+	// ErrNotCounter indicates Increment or Decrement of an entry that does not hold int64 value.
+	ErrNotCounter = SentinelError("cache value is not a counter")
+
+	// ErrTooManyBuilds indicates rejected build when limit of concurrent builds is reached.
+	ErrTooManyBuilds = SentinelError("too many concurrent builds")
+
+	// ErrCachedFailure indicates error of a recently failed build served from errors cache of Failover
+	// with FailoverConfig.FailedUpdateMaxTTL backoff, original build error is available with errors.Is and errors.As.
+	ErrCachedFailure = SentinelError("cached failure of build")
+
+	// ErrDecryptionFailed indicates cached value that can not be authenticated with any of decryption keys.
+	ErrDecryptionFailed = SentinelError("failed to decrypt cached value")
+
+	// ErrCallbackPanic indicates recovered panic of a user callback, see Config.RecoverCallbacks.
+	ErrCallbackPanic = SentinelError("cache callback panicked")
+
+	// ErrNoChange can be returned by update function to leave cache entry untouched.
+	ErrNoChange = SentinelError("no change")
 )
 
+// ErrCachedMiss indicates cached absence of entry written with WriteMiss, it matches ErrNotFound.
+var ErrCachedMiss = fmt.Errorf("%w (cached)", ErrNotFound)
+
 // Error implements error.
 func (e SentinelError) Error() string {
 	return string(e)
@@ -34,3 +79,29 @@ type ErrWithExpiredItem interface {
 	Value() interface{}
 	ExpiredAt() time.Time
 }
+
+// StaleValue returns expired value and its expiration time from an error of Read.
+//
+// It returns false if error does not carry expired value, for example ErrNotFound.
+func StaleValue(err error) (interface{}, time.Time, bool) {
+	var errExp ErrWithExpiredItem
+
+	if !errors.As(err, &errExp) {
+		return nil, time.Time{}, false
+	}
+
+	return errExp.Value(), errExp.ExpiredAt(), true
+}
+
+// softExpiredHit returns value of soft expired entry as a hit.
+//
+// Soft expiration only hints refresh ahead to Failover, other consumers should treat such entry as valid.
+func softExpiredHit(v interface{}, err error) (interface{}, error) {
+	if errors.Is(err, ErrSoftExpired) {
+		v, _, _ = StaleValue(err)
+
+		return v, nil
+	}
+
+	return v, err
+}
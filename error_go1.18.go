@@ -3,7 +3,10 @@
 
 package cache
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 // ErrWithExpiredItemOf defines an expiration error with entry details.
 type ErrWithExpiredItemOf[V any] interface {
@@ -11,3 +14,18 @@ type ErrWithExpiredItemOf[V any] interface {
 	Value() V
 	ExpiredAt() time.Time
 }
+
+// StaleValueOf returns expired value and its expiration time from an error of Read.
+//
+// It returns false if error does not carry expired value, for example ErrNotFound.
+func StaleValueOf[V any](err error) (V, time.Time, bool) {
+	var errExp ErrWithExpiredItemOf[V]
+
+	if !errors.As(err, &errExp) {
+		var v V
+
+		return v, time.Time{}, false
+	}
+
+	return errExp.Value(), errExp.ExpiredAt(), true
+}
@@ -0,0 +1,123 @@
+package cache
+
+type evictLeastEntry struct {
+	hash uint64
+	val  int64
+}
+
+// evictLeastEntries is a max-heap of candidates for eviction.
+type evictLeastEntries []evictLeastEntry
+
+func (e evictLeastEntries) Len() int           { return len(e) }
+func (e evictLeastEntries) Less(i, j int) bool { return e[i].val < e[j].val }
+func (e evictLeastEntries) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+// offer adds entry to a max-heap of k entries with least values.
+//
+// Memory is bounded by k entries and complexity is O(n log k) for n offered entries.
+//
+//nolint:dupl // Hard to deduplicate without allocations.
+func (e *evictLeastEntries) offer(en evictLeastEntry, k int) {
+	h := *e
+
+	if len(h) < k {
+		h = append(h, en)
+		*e = h
+
+		// Sifting new entry up.
+		for j := len(h) - 1; j > 0; {
+			i := (j - 1) / 2
+			if h[i].val >= h[j].val {
+				break
+			}
+
+			h[i], h[j] = h[j], h[i]
+			j = i
+		}
+
+		return
+	}
+
+	if k == 0 || en.val >= h[0].val {
+		return
+	}
+
+	// Replacing the largest candidate and sifting it down.
+	h[0] = en
+
+	for i := 0; ; {
+		j := 2*i + 1
+		if j >= len(h) {
+			break
+		}
+
+		if j2 := j + 1; j2 < len(h) && h[j].val < h[j2].val {
+			j = j2
+		}
+
+		if h[i].val >= h[j].val {
+			break
+		}
+
+		h[i], h[j] = h[j], h[i]
+		i = j
+	}
+}
+
+type evictLeastKey struct {
+	entry *TraitEntry
+	val   int64
+}
+
+// evictLeastKeys is a max-heap of candidates for eviction.
+type evictLeastKeys []evictLeastKey
+
+// offer adds entry to a max-heap of k entries with least values.
+//
+//nolint:dupl // Hard to deduplicate without allocations.
+func (e *evictLeastKeys) offer(en evictLeastKey, k int) {
+	h := *e
+
+	if len(h) < k {
+		h = append(h, en)
+		*e = h
+
+		// Sifting new entry up.
+		for j := len(h) - 1; j > 0; {
+			i := (j - 1) / 2
+			if h[i].val >= h[j].val {
+				break
+			}
+
+			h[i], h[j] = h[j], h[i]
+			j = i
+		}
+
+		return
+	}
+
+	if k == 0 || en.val >= h[0].val {
+		return
+	}
+
+	// Replacing the largest candidate and sifting it down.
+	h[0] = en
+
+	for i := 0; ; {
+		j := 2*i + 1
+		if j >= len(h) {
+			break
+		}
+
+		if j2 := j + 1; j2 < len(h) && h[j].val < h[j2].val {
+			j = j2
+		}
+
+		if h[i].val >= h[j].val {
+			break
+		}
+
+		h[i], h[j] = h[j], h[i]
+		i = j
+	}
+}
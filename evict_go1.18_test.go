@@ -281,14 +281,18 @@ func Test_generic_LRU_eviction(t *testing.T) {
 
 			assert.Equal(t, c.Len(), 50)
 
-			for i := 0; i < 50; i++ {
+			// Recently written "100!" is kept, so one more of least recently used is evicted.
+			_, err := c.Read(ctx, []byte("100!"))
+			require.NoError(t, err)
+
+			for i := 0; i < 51; i++ {
 				k := []byte(strconv.Itoa(i))
 				_, err := c.Read(ctx, k)
 
 				require.EqualError(t, err, "missing cache item", i) // Evicted.
 			}
 
-			for i := 50; i < 100; i++ {
+			for i := 51; i < 100; i++ {
 				k := []byte(strconv.Itoa(i))
 				_, err := c.Read(ctx, k)
 
@@ -297,3 +301,36 @@ func Test_generic_LRU_eviction(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_CanEvict_generic(t *testing.T) {
+	clock := &manualClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ctx := context.Background()
+
+	var c *ShardedMapOf[int]
+
+	c = NewShardedMapOf[int](Config{
+		Clock:            clock,
+		TimeToLive:       time.Minute,
+		ExpirationJitter: -1,
+		CanEvict: func(e Entry) bool {
+			// Cache is accessible from CanEvict.
+			_, _ = c.Read(ctx, e.Key())
+
+			return e.Value() != 1
+		},
+	}.Use)
+
+	require.NoError(t, c.Write(ctx, []byte("pinned"), 1))
+	require.NoError(t, c.Write(ctx, []byte("other"), 2))
+
+	clock.Add(25 * time.Hour)
+	c.t.invokeCleanup()
+
+	assert.Equal(t, 1, c.Len())
+
+	_, err := c.Read(ctx, []byte("pinned"))
+	assert.ErrorIs(t, err, ErrExpired)
+
+	assert.Equal(t, 0, c.evictMostExpired(1))
+	assert.Equal(t, 1, c.Len())
+}
@@ -3,11 +3,17 @@ package cache //nolint:testpackage
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/bool64/ctxd"
+	"github.com/bool64/stats"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -208,8 +214,9 @@ func Test_eviction(t *testing.T) {
 			cfg.CountSoftLimit = 100
 			cfg.Name = "LRU"
 
-			keptMin, keptMax = 50, 100
-			evictedMin, evictedMax = 0, 50
+			// Recently written "100!" is kept, so one more of least recently used is evicted.
+			keptMin, keptMax = 51, 100
+			evictedMin, evictedMax = 0, 51
 		},
 		func(cfg *Config) {
 			cfg.EvictionStrategy = EvictLeastFrequentlyUsed
@@ -291,3 +298,471 @@ func Test_eviction(t *testing.T) {
 		}
 	}
 }
+
+func Test_evictionLFUDecay(t *testing.T) {
+	for _, c := range backends(func(cfg *Config) {
+		cfg.EvictionStrategy = EvictLeastFrequentlyUsed
+		cfg.LFUDecayInterval = time.Millisecond
+	}) {
+		t.Run(fmt.Sprintf("%T", c), func(t *testing.T) {
+			ctx := context.Background()
+
+			require.NoError(t, c.Write(ctx, []byte("foo"), 1))
+
+			for i := 0; i < 1000; i++ {
+				_, err := c.Read(ctx, []byte("foo"))
+				require.NoError(t, err)
+			}
+
+			assert.Eventually(t, func() bool {
+				var cnt int64
+
+				_, err := c.(Walker).Walk(func(e Entry) error {
+					cnt = atomic.LoadInt64(&e.(*TraitEntry).C)
+
+					return nil
+				})
+
+				return err == nil && cnt < 10
+			}, time.Second, time.Millisecond)
+		})
+	}
+}
+
+func TestTrait_invokeCleanup_onEvict(t *testing.T) {
+	st := &stats.TrackerMock{}
+
+	var triggers []string
+
+	tr := NewTrait(Config{
+		Stats:              st,
+		HeapInUseSoftLimit: 1, // Setting heap threshold to 1B to force eviction.
+		OnEvict: func(trigger string, count int) {
+			triggers = append(triggers, trigger+":"+strconv.Itoa(count))
+		},
+	}, func(t *Trait) {
+		t.Evict = func(fraction float64) int { return 5 }
+	})
+	defer tr.Close()
+
+	tr.invokeCleanup()
+
+	tr.Config.HeapInUseSoftLimit = 0
+	tr.Config.EvictionNeeded = func() bool { return true }
+
+	tr.invokeCleanup()
+
+	assert.Equal(t, []string{"heap:5", "custom:5"}, triggers)
+	assert.Equal(t, 10, st.Int(MetricEvict))
+	assert.Equal(t, 5, st.Int(MetricEvict, "name", "", "trigger", EvictTriggerHeap))
+	assert.Equal(t, 5, st.Int(MetricEvict, "name", "", "trigger", EvictTriggerCustom))
+	assert.Equal(t, 5, st.Int(MetricEvictHeap))
+}
+
+func TestConfig_OnEvicted(t *testing.T) {
+	ctx := context.Background()
+
+	type cache interface {
+		ReadWriter
+		Deleter
+	}
+
+	for _, newCache := range []func(options ...func(*Config)) cache{
+		func(options ...func(*Config)) cache { return NewShardedMap(options...) },
+		func(options ...func(*Config)) cache { return NewSyncMap(options...) },
+	} {
+		var (
+			c       cache
+			mu      sync.Mutex
+			reasons = map[EvictReason]int{}
+		)
+
+		c = newCache(Config{
+			CountHardLimit: 3,
+			OnEvicted: func(key []byte, value interface{}, reason EvictReason) {
+				// Cache is accessible from the callback.
+				_, err := c.Read(ctx, key)
+				assert.Error(t, err)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				reasons[reason]++
+			},
+		}.Use)
+
+		for i := 0; i < 4; i++ {
+			require.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+		}
+
+		for i := 0; i < 4; i++ {
+			if _, err := c.Read(ctx, []byte(strconv.Itoa(i))); err == nil {
+				require.NoError(t, c.Delete(ctx, []byte(strconv.Itoa(i))))
+
+				break
+			}
+		}
+
+		c.(interface{ ExpireAll(ctx context.Context) }).ExpireAll(ctx)
+		c.(interface{ deleteExpired(before time.Time) }).deleteExpired(time.Now().Add(time.Second))
+
+		require.NoError(t, c.Write(ctx, []byte("foo"), 1))
+		require.NoError(t, c.Write(ctx, []byte("bar"), missMarker(true)))
+		c.(interface{ DeleteAll(ctx context.Context) }).DeleteAll(ctx)
+
+		assert.Equal(t, map[EvictReason]int{
+			EvictReasonCapacity: 1,
+			EvictReasonDeleted:  2,
+			EvictReasonExpired:  2,
+		}, reasons)
+	}
+}
+
+func Test_evictionMinItems(t *testing.T) {
+	for _, minItems := range []int{0, 2} {
+		for _, be := range backends(Config{
+			CountSoftLimit:   3,
+			EvictMinItems:    minItems,
+			ExpirationJitter: -1,
+		}.Use) {
+			m, ok := be.(evictInterface)
+
+			require.True(t, ok)
+
+			t.Run(fmt.Sprintf("%T/%d", be, minItems), func(t *testing.T) {
+				ctx := context.Background()
+
+				for i := 0; i < 5; i++ {
+					require.NoError(t, m.Write(ctx, []byte(strconv.Itoa(i)), i))
+				}
+
+				// Default fraction of 5 entries rounds down to zero, but eviction makes progress.
+				expected := minItems
+				if expected == 0 {
+					expected = 1
+				}
+
+				assert.Equal(t, expected, m.evictMostExpired(0.1))
+				assert.Equal(t, 5-expected, m.Len())
+			})
+		}
+	}
+}
+
+func Test_evictLeastEntries_offer(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+
+	for _, n := range []int{0, 1, 2, 10, 1000} {
+		for _, k := range []int{0, 1, n / 10, n / 2, n - 1, n} {
+			var (
+				entries evictLeastEntries
+				all     evictLeastEntries
+			)
+
+			for i := 0; i < n; i++ {
+				// Small range of values to have many duplicates.
+				en := evictLeastEntry{hash: uint64(i), val: rnd.Int63n(int64(n/4 + 1))}
+				all = append(all, en)
+				entries.offer(en, k)
+			}
+
+			sort.Sort(all)
+			sort.Sort(entries)
+
+			expected := k
+			if expected > n {
+				expected = n
+			}
+
+			if expected < 0 {
+				expected = 0
+			}
+
+			require.Len(t, entries, expected)
+
+			for i := range entries {
+				assert.Equal(t, all[i].val, entries[i].val, "n=%d k=%d", n, k)
+			}
+		}
+	}
+}
+
+func Benchmark_evictLeast(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	n := 100000
+	k := n / 10
+
+	src := make(evictLeastEntries, n)
+	for i := range src {
+		src[i] = evictLeastEntry{hash: uint64(i), val: rnd.Int63()}
+	}
+
+	b.Run("sort", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			entries := make(evictLeastEntries, 0, n)
+			entries = append(entries, src...)
+
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].val < entries[j].val
+			})
+		}
+	})
+
+	b.Run("heap", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			entries := make(evictLeastEntries, 0, k)
+
+			for _, en := range src {
+				entries.offer(en, k)
+			}
+		}
+	})
+}
+
+func Test_evictionMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	st := &stats.TrackerMock{}
+
+	for _, be := range backends(Config{
+		Stats:                    st,
+		MaxBytes:                 1000,
+		Sizer:                    func(value interface{}) int { return 100 },
+		DeleteExpiredJobInterval: time.Millisecond,
+	}.Use) {
+		c, ok := be.(interface {
+			ReadWriter
+			Deleter
+			Len() int
+			Stats() CacheStats
+		})
+		require.True(t, ok)
+
+		for i := 0; i < 20; i++ {
+			require.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+		}
+
+		// Overwrite does not change total size.
+		require.NoError(t, c.Write(ctx, []byte("0"), 0))
+		require.NoError(t, c.Delete(ctx, []byte("1")))
+
+		assert.Eventually(t, func() bool {
+			s := c.Stats()
+
+			return s.Bytes <= 1000 && s.Bytes == int64(s.Items)*100
+		}, time.Second, time.Millisecond)
+
+		assert.Greater(t, c.Len(), 0)
+	}
+
+	assert.Greater(t, st.Int(MetricEvict, "name", "", "trigger", EvictTriggerBytes), 0)
+}
+
+// manualClock is a clock that is advanced by test.
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *manualClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func Test_evictionMinAge(t *testing.T) {
+	clock := &manualClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	for _, be := range backends(Config{
+		Clock:            clock,
+		EvictMinAge:      time.Minute,
+		ExpirationJitter: -1,
+	}.Use) {
+		m, ok := be.(evictInterface)
+
+		require.True(t, ok)
+
+		t.Run(fmt.Sprintf("%T", be), func(t *testing.T) {
+			ctx := context.Background()
+
+			// Young entries with short TTL are protected from eviction.
+			for i := 0; i < 5; i++ {
+				require.NoError(t, m.Write(WithTTL(ctx, time.Hour, false), []byte("young"+strconv.Itoa(i)), i))
+			}
+
+			assert.Equal(t, 1, m.evictMostExpired(0.1))
+			assert.Equal(t, 4, m.Len())
+
+			clock.Add(2 * time.Minute)
+
+			for i := 0; i < 5; i++ {
+				require.NoError(t, m.Write(WithTTL(ctx, time.Minute, false), []byte("fresh"+strconv.Itoa(i)), i))
+			}
+
+			// Old entries are evicted first, even though young entries expire earlier.
+			assert.Equal(t, 4, m.evictMostExpired(0.5))
+			assert.Equal(t, 5, m.Len())
+
+			for i := 0; i < 5; i++ {
+				_, err := m.Read(ctx, []byte("fresh"+strconv.Itoa(i)))
+				assert.NoError(t, err)
+			}
+
+			clock.Add(2 * time.Minute)
+
+			for i := 0; i < 5; i++ {
+				require.NoError(t, m.Write(WithTTL(ctx, time.Duration(i+1)*time.Hour, false), []byte("late"+strconv.Itoa(i)), i))
+			}
+
+			// Young entries complete the quota when there are not enough old entries.
+			assert.Equal(t, 8, m.evictMostExpired(0.8))
+			assert.Equal(t, 2, m.Len())
+
+			for i := 3; i < 5; i++ {
+				_, err := m.Read(ctx, []byte("late"+strconv.Itoa(i)))
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_CanEvict(t *testing.T) {
+	clock := &manualClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	logger := &ctxd.LoggerMock{}
+
+	for _, be := range backends(Config{
+		Clock:               clock,
+		Logger:              logger,
+		TimeToLive:          time.Minute,
+		ExpirationJitter:    -1,
+		PinnedWarnThreshold: 1,
+		CanEvict: func(e Entry) bool {
+			return !strings.HasPrefix(string(e.Key()), "pinned")
+		},
+	}.Use) {
+		m, ok := be.(evictInterface)
+
+		require.True(t, ok)
+
+		var tr *Trait
+
+		switch c := be.(type) {
+		case *ShardedMap:
+			tr = c.t
+		case *SyncMap:
+			tr = c.t
+		}
+
+		t.Run(fmt.Sprintf("%T", be), func(t *testing.T) {
+			ctx := context.Background()
+
+			require.NoError(t, m.Write(ctx, []byte("pinned1"), 1))
+			require.NoError(t, m.Write(ctx, []byte("pinned2"), 2))
+			require.NoError(t, m.Write(ctx, []byte("other"), 3))
+
+			clock.Add(25 * time.Hour)
+			tr.invokeCleanup()
+
+			assert.Equal(t, 2, m.Len())
+			assert.Contains(t, logger.String(), `warn: too many expired cache entries are pinned {"count":2,"name":""}`)
+
+			// Pinned entries keep serving stale values.
+			_, err := m.Read(ctx, []byte("pinned1"))
+			assert.ErrorIs(t, err, ErrExpired)
+
+			v, _, ok := StaleValue(err)
+			assert.True(t, ok)
+			assert.Equal(t, 1, v)
+
+			_, err = m.Read(ctx, []byte("other"))
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			// Pinned entries are not evicted.
+			require.NoError(t, m.Write(ctx, []byte("fresh"), 4))
+
+			assert.Equal(t, 1, m.evictMostExpired(1))
+			assert.Equal(t, 0, m.evictMostExpired(1))
+			assert.Equal(t, 2, m.Len())
+		})
+	}
+}
+
+func TestConfig_CanEvict_readsCache(t *testing.T) {
+	for _, indexBucket := range []time.Duration{0, 10 * time.Second} {
+		clock := &manualClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+		var current ReadWriter
+
+		for _, be := range backends(Config{
+			Clock:                 clock,
+			TimeToLive:            time.Minute,
+			ExpirationJitter:      -1,
+			ExpirationIndexBucket: indexBucket,
+			// Entry is pinned by a marker entry in the same cache.
+			CanEvict: func(e Entry) bool {
+				ctx := context.Background()
+
+				// Reading the same key would block if CanEvict was called under lock.
+				_, _ = current.Read(ctx, e.Key())
+				_, err := current.Read(ctx, append([]byte("pin:"), e.Key()...))
+
+				return err != nil
+			},
+		}.Use) {
+			m, ok := be.(evictInterface)
+
+			require.True(t, ok)
+
+			var tr *Trait
+
+			switch c := be.(type) {
+			case *ShardedMap:
+				tr = c.t
+			case *SyncMap:
+				tr = c.t
+			}
+
+			current = be
+
+			t.Run(fmt.Sprintf("%T/%s", be, indexBucket), func(t *testing.T) {
+				ctx := context.Background()
+
+				require.NoError(t, m.Write(ctx, []byte("foo"), 1))
+				require.NoError(t, m.Write(ctx, []byte("bar"), 2))
+				require.NoError(t, m.Write(WithTTL(ctx, 100*time.Hour, false), []byte("pin:foo"), true))
+
+				clock.Add(25 * time.Hour)
+
+				done := make(chan struct{})
+
+				go func() {
+					defer close(done)
+
+					tr.invokeCleanup()
+					assert.Equal(t, 1, m.evictMostExpired(1))
+				}()
+
+				select {
+				case <-done:
+				case <-time.After(time.Second):
+					require.Fail(t, "cleanup is blocked by CanEvict")
+				}
+
+				_, err := m.Read(ctx, []byte("foo"))
+				assert.ErrorIs(t, err, ErrExpired)
+				assert.Equal(t, 1, m.Len())
+			})
+		}
+	}
+}
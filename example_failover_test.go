@@ -90,3 +90,48 @@ func ExampleFailover_Get_caching_wrapper() {
 	// {3 456 US} <nil>
 	// {4 789 FR} <nil>
 }
+
+func ExampleFailover_Get_stale_while_revalidate() {
+	ctx := context.Background()
+	backend := cache.NewSyncMap()
+
+	f := cache.NewFailover(cache.FailoverConfig{
+		Backend: backend,
+	}.Use)
+
+	key := []byte("my-key")
+
+	// Cache miss, value is built synchronously.
+	v, _ := f.Get(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return "first", nil
+	})
+	fmt.Println(v)
+
+	backend.ExpireAll(ctx)
+
+	// Stale value is served immediately while update runs in background.
+	v, _ = f.Get(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return "second", nil
+	})
+	fmt.Println(v)
+
+	// Waiting for background update to finish.
+	for {
+		if v, err := backend.Read(ctx, key); err == nil && v == "second" {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	// Fresh value is served from cache.
+	v, _ = f.Get(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return "third", nil
+	})
+	fmt.Println(v)
+
+	// Output:
+	// first
+	// first
+	// second
+}
@@ -81,3 +81,26 @@ func ExampleFailoverOf_Get() {
 	// Output:
 	// Snoopy
 }
+
+func ExampleStaleValueOf() {
+	ctx := context.Background()
+	c := cache.NewShardedMapOf[int]()
+
+	_ = c.Write(cache.WithTTL(ctx, time.Millisecond, false), []byte("foo"), 123)
+	time.Sleep(2 * time.Millisecond)
+
+	_, err := c.Read(ctx, []byte("foo"))
+
+	// Serving stale value if it is available.
+	if v, _, ok := cache.StaleValueOf[int](err); ok {
+		fmt.Println(v)
+	}
+
+	_, err = c.Read(ctx, []byte("bar"))
+	_, _, ok := cache.StaleValueOf[int](err)
+	fmt.Println(ok)
+
+	// Output:
+	// 123
+	// false
+}
@@ -108,3 +108,20 @@ func ExampleInvalidationIndex_InvalidateByLabels() {
 	// deleted items for 'my': 1
 	// my-foo err: missing cache item
 }
+
+func ExampleStaleValue() {
+	ctx := context.Background()
+	c := cache.NewShardedMap()
+
+	_ = c.Write(cache.WithTTL(ctx, time.Millisecond, false), []byte("foo"), "bar")
+	time.Sleep(2 * time.Millisecond)
+
+	_, err := c.Read(ctx, []byte("foo"))
+
+	// Serving stale value if it is available.
+	if v, _, ok := cache.StaleValue(err); ok {
+		fmt.Println(v)
+	}
+
+	// Output: bar
+}
@@ -0,0 +1,74 @@
+package cache
+
+import "sync"
+
+// expirationIndex maps coarse time buckets of deletion to keys, so that janitor does not scan whole cache.
+//
+// Index is not exact, keys may stay in buckets after entries were updated or removed,
+// so entries are checked again before deletion.
+type expirationIndex struct {
+	mu      sync.Mutex
+	width   int64
+	buckets map[int64]map[string]struct{}
+}
+
+func newExpirationIndex(width int64) *expirationIndex {
+	return &expirationIndex{
+		width:   width,
+		buckets: make(map[int64]map[string]struct{}),
+	}
+}
+
+// add puts key to a bucket of deletion timestamp, that is comparable with janitor boundary.
+func (x *expirationIndex) add(key string, due int64) {
+	b := due / x.width
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	keys := x.buckets[b]
+	if keys == nil {
+		keys = make(map[string]struct{})
+		x.buckets[b] = keys
+	}
+
+	keys[key] = struct{}{}
+}
+
+// remove deletes key from a bucket of deletion timestamp.
+func (x *expirationIndex) remove(key string, due int64) {
+	b := due / x.width
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if keys := x.buckets[b]; keys != nil {
+		delete(keys, key)
+
+		if len(keys) == 0 {
+			delete(x.buckets, b)
+		}
+	}
+}
+
+// take removes and returns keys of buckets that have fully passed before boundary.
+func (x *expirationIndex) take(beforeTS int64) []string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	var res []string
+
+	for b, keys := range x.buckets {
+		if (b+1)*x.width > beforeTS {
+			continue
+		}
+
+		for k := range keys {
+			res = append(res, k)
+		}
+
+		delete(x.buckets, b)
+	}
+
+	return res
+}
@@ -23,6 +23,11 @@ type FailoverConfig struct {
 	// FailedUpdateTTL is ttl of failed build cache, default 20s, -1 disables errors cache.
 	FailedUpdateTTL time.Duration
 
+	// FailedUpdateMaxTTL enables exponential backoff of failed build cache, ttl is doubled with each
+	// consecutive failure of a key starting from FailedUpdateTTL up to FailedUpdateMaxTTL.
+	// Successful build resets backoff, default 0 (constant FailedUpdateTTL).
+	FailedUpdateMaxTTL time.Duration
+
 	// UpdateTTL is a time interval to retry update, default 1 minute.
 	UpdateTTL time.Duration
 
@@ -50,6 +55,13 @@ type FailoverConfig struct {
 
 	// ObserveMutability enables deep equal check with metric collection on cache update.
 	ObserveMutability bool
+
+	// MaxConcurrentBuilds limits number of simultaneous builds of different keys, default 0 (unlimited).
+	// Builds over limit wait for a slot or for context cancellation.
+	MaxConcurrentBuilds int
+
+	// RejectOnBuildLimit makes builds over MaxConcurrentBuilds fail with ErrTooManyBuilds instead of waiting.
+	RejectOnBuildLimit bool
 }
 
 // Use is a functional option for NewFailover to apply configuration.
@@ -74,6 +86,7 @@ type Failover struct {
 	lock     sync.Mutex     // Securing keyLocks
 	keyLocks map[string]*kl // Preventing update concurrency per key
 	config   FailoverConfig
+	builds   buildLimiter
 
 	logTrait
 
@@ -118,14 +131,16 @@ func NewFailover(options ...func(cfg *FailoverConfig)) *Failover {
 			Logger:     cfg.Logger,
 			Stats:      cfg.Stats,
 			TimeToLive: cfg.FailedUpdateTTL,
+			Clock:      cfg.BackendConfig.Clock,
 
 			// Short cleanup intervals to avoid storing potentially heavy errors for long time.
-			DeleteExpiredAfter:       time.Minute,
+			DeleteExpiredAfter:       failuresRetention(cfg.FailedUpdateMaxTTL),
 			DeleteExpiredJobInterval: time.Minute,
 		}.Use)
 	}
 
 	f.keyLocks = make(map[string]*kl)
+	f.builds = newBuildLimiter(cfg.MaxConcurrentBuilds)
 
 	return f
 }
@@ -141,8 +156,14 @@ func (f *Failover) Get(
 		err   error
 	)
 
+	// Forced refresh ignores cached value and recent failures.
+	forceRefresh := ForceRefresh(ctx)
+	if forceRefresh {
+		err = ErrNotFound
+	}
+
 	// Performing initial check before critical section.
-	if !f.config.SyncRead {
+	if !f.config.SyncRead && !forceRefresh {
 		// Checking for valid value in cache store.
 		if value, err = f.backend.Read(ctx, key); err == nil {
 			return value, nil
@@ -150,15 +171,17 @@ func (f *Failover) Get(
 	}
 
 	// Locking key for update or finding active lock.
+	lk := lockKey(ctx, key)
+
 	f.lock.Lock()
 	var keyLock *kl
 
 	alreadyLocked := false
 
-	keyLock, alreadyLocked = f.keyLocks[string(key)]
+	keyLock, alreadyLocked = f.keyLocks[lk]
 	if !alreadyLocked {
 		keyLock = &kl{lock: make(chan struct{})}
-		f.keyLocks[string(key)] = keyLock
+		f.keyLocks[lk] = keyLock
 	}
 	f.lock.Unlock()
 
@@ -166,14 +189,14 @@ func (f *Failover) Get(
 	defer func() {
 		if !alreadyLocked {
 			f.lock.Lock()
-			delete(f.keyLocks, string(key))
+			delete(f.keyLocks, lk)
 			close(keyLock.lock)
 			f.lock.Unlock()
 		}
 	}()
 
 	// Performing initial check in critical section.
-	if f.config.SyncRead {
+	if f.config.SyncRead && !forceRefresh {
 		// Checking for valid value in cache store.
 		if value, err = f.backend.Read(ctx, key); err == nil {
 			if !alreadyLocked {
@@ -196,9 +219,14 @@ func (f *Failover) Get(
 		return f.waitForValue(withoutSkipRead(ctx), key, keyLock)
 	}
 
+	softExpired := errors.Is(err, ErrSoftExpired)
+
 	// Pushing expired value with short ttl to serve during update.
 	if val, freshEnough, unexpectedBackendError := f.valueFromError(err); freshEnough {
-		if err = f.refreshStale(ctx, key, val); err != nil {
+		// Soft expired value is valid till hard expiration, so it is served without refreshing.
+		if softExpired {
+			err = nil
+		} else if err = f.refreshStale(ctx, key, val); err != nil {
 			return nil, err
 		}
 
@@ -208,7 +236,13 @@ func (f *Failover) Get(
 	}
 
 	// Check if update failed recently.
-	if err := f.recentlyFailed(ctx, key); err != nil {
+	if err := f.recentlyFailed(ctx, key); err != nil && !forceRefresh {
+		if softExpired {
+			keyLock.val = value
+
+			return value, nil
+		}
+
 		keyLock.err = err
 
 		return nil, err
@@ -243,7 +277,7 @@ func (f *Failover) Get(
 	go func() {
 		defer func() {
 			f.lock.Lock()
-			delete(f.keyLocks, string(key))
+			delete(f.keyLocks, lk)
 			close(keyLock.lock)
 			f.lock.Unlock()
 		}()
@@ -260,6 +294,16 @@ func (f *Failover) Get(
 	return value, nil
 }
 
+// lockKey returns key with prefix from context (see WithKeyPrefix) for internal maps of wrappers,
+// so that callers with different prefixes do not share updates, loads or pending writes.
+func lockKey(ctx context.Context, key []byte) string {
+	if p := KeyPrefix(ctx); len(p) > 0 {
+		return string(p) + string(key)
+	}
+
+	return string(key)
+}
+
 func (f *Failover) valueFromError(err error) (interface{}, bool, error) {
 	var errExpired ErrWithExpiredItem
 
@@ -268,7 +312,8 @@ func (f *Failover) valueFromError(err error) (interface{}, bool, error) {
 	}
 
 	if errors.As(err, &errExpired) {
-		if f.config.MaxStaleness == 0 || time.Since(errExpired.ExpiredAt()) < f.config.MaxStaleness {
+		// Soft expired value is valid regardless of MaxStaleness.
+		if errors.Is(err, ErrSoftExpired) || f.config.MaxStaleness == 0 || time.Since(errExpired.ExpiredAt()) < f.config.MaxStaleness {
 			return errExpired.Value(), true, nil
 		}
 
@@ -287,6 +332,10 @@ func (f *Failover) waitForValue(ctx context.Context, key []byte, keyLock *kl) (i
 		f.logDebug(ctx, "waiting for cache value", "name", f.config.Name, "key", key)
 	}
 
+	if f.stat != nil {
+		f.stat.Add(ctx, MetricSingleFlightDedup, 1, "name", f.config.Name)
+	}
+
 	// Waiting for value built by keyLock owner.
 	<-keyLock.lock
 
@@ -319,6 +368,13 @@ func (f *Failover) doBuild(
 	value interface{},
 	buildFunc func(ctx context.Context) (interface{}, error),
 ) (interface{}, error) { //nolint:dupl // Non-generic API is different.
+	// Build slot is not available, such failure is not cached.
+	if err := f.builds.acquire(ctx, f.config.RejectOnBuildLimit); err != nil {
+		return nil, err
+	}
+
+	defer f.builds.release()
+
 	if f.stat != nil {
 		defer func() {
 			f.stat.Add(ctx, MetricBuild, 1, "name", f.config.Name)
@@ -336,7 +392,7 @@ func (f *Failover) doBuild(
 		}
 
 		if f.config.FailedUpdateTTL > -1 {
-			writeErr := f.Errors.Write(ctx, key, err)
+			writeErr := f.cacheFailure(ctx, key, err)
 			if writeErr != nil && f.logError != nil {
 				f.logError(ctx, "failed to cache update failure",
 					"error", writeErr,
@@ -354,6 +410,8 @@ func (f *Failover) doBuild(
 		return nil, writeErr
 	}
 
+	f.resetFailures(ctx, key)
+
 	if f.config.ObserveMutability && value != nil {
 		f.observeMutability(ctx, uVal, value)
 	}
@@ -382,9 +440,92 @@ func (f *Failover) recentlyFailed(ctx context.Context, key []byte) error {
 	return nil
 }
 
+// cacheFailure stores build error with ttl of failures backoff.
+func (f *Failover) cacheFailure(ctx context.Context, key []byte, err error) error {
+	// Error is stored as is unless failures backoff is enabled.
+	if f.config.FailedUpdateMaxTTL <= 0 {
+		return f.Errors.Write(ctx, key, err)
+	}
+
+	failures := 1
+
+	prev, readErr := f.Errors.Read(ctx, key)
+	if readErr != nil {
+		prev, _, _ = StaleValue(readErr)
+	}
+
+	if prevErr, ok := prev.(error); ok {
+		failures += prevFailures(prevErr)
+	}
+
+	ctx = WithTTL(ctx, failedUpdateTTL(f.config.FailedUpdateTTL, f.config.FailedUpdateMaxTTL, failures), false)
+
+	return f.Errors.Write(ctx, key, failedUpdate{err: err, failures: failures})
+}
+
+// resetFailures removes cached error after successful build to reset backoff.
+func (f *Failover) resetFailures(ctx context.Context, key []byte) {
+	if f.config.FailedUpdateTTL > -1 && f.config.FailedUpdateMaxTTL > 0 {
+		_ = f.Errors.Delete(ctx, key)
+	}
+}
+
 func (f *Failover) observeMutability(ctx context.Context, uVal, value interface{}) {
 	equal := reflect.DeepEqual(value, uVal)
 	if !equal {
 		f.stat.Add(ctx, MetricChanged, 1, "name", f.config.Name)
 	}
 }
+
+// failedUpdate is a cached error of failed build.
+type failedUpdate struct {
+	err      error
+	failures int
+}
+
+func (e failedUpdate) Error() string {
+	return e.err.Error()
+}
+
+func (e failedUpdate) Unwrap() error {
+	return e.err
+}
+
+func (e failedUpdate) Is(err error) bool {
+	return errors.Is(err, ErrCachedFailure)
+}
+
+// prevFailures returns number of consecutive failures of a cached error.
+func prevFailures(err error) int {
+	var fu failedUpdate
+
+	if errors.As(err, &fu) {
+		return fu.failures
+	}
+
+	return 0
+}
+
+// failedUpdateTTL doubles base ttl for each consecutive failure up to maxTTL.
+func failedUpdateTTL(base, maxTTL time.Duration, failures int) time.Duration {
+	ttl := base
+
+	for i := 1; i < failures && ttl < maxTTL; i++ {
+		ttl *= 2
+	}
+
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+
+	return ttl
+}
+
+// failuresRetention returns how long expired errors are kept to count consecutive failures.
+func failuresRetention(maxTTL time.Duration) time.Duration {
+	if maxTTL > time.Minute {
+		return maxTTL
+	}
+
+	return time.Minute
+}
@@ -26,6 +26,11 @@ type FailoverConfigOf[V any] struct {
 	// FailedUpdateTTL is ttl of failed build cache, default 20s, -1 disables errors cache.
 	FailedUpdateTTL time.Duration
 
+	// FailedUpdateMaxTTL enables exponential backoff of failed build cache, ttl is doubled with each
+	// consecutive failure of a key starting from FailedUpdateTTL up to FailedUpdateMaxTTL.
+	// Successful build resets backoff, default 0 (constant FailedUpdateTTL).
+	FailedUpdateMaxTTL time.Duration
+
 	// UpdateTTL is a time interval to retry update, default 1 minute.
 	UpdateTTL time.Duration
 
@@ -53,6 +58,13 @@ type FailoverConfigOf[V any] struct {
 
 	// ObserveMutability enables deep equal check with metric collection on cache update.
 	ObserveMutability bool
+
+	// MaxConcurrentBuilds limits number of simultaneous builds of different keys, default 0 (unlimited).
+	// Builds over limit wait for a slot or for context cancellation.
+	MaxConcurrentBuilds int
+
+	// RejectOnBuildLimit makes builds over MaxConcurrentBuilds fail with ErrTooManyBuilds instead of waiting.
+	RejectOnBuildLimit bool
 }
 
 // Use is a functional option for NewFailover to apply configuration.
@@ -71,6 +83,7 @@ type FailoverOf[V any] struct {
 	lock     sync.Mutex          // Securing keyLocks
 	keyLocks map[string]*klOf[V] // Preventing update concurrency per key
 	config   FailoverConfigOf[V]
+	builds   buildLimiter
 	logTrait
 	stat StatsTracker
 }
@@ -112,14 +125,16 @@ func NewFailoverOf[V any](options ...func(cfg *FailoverConfigOf[V])) *FailoverOf
 			Logger:     cfg.Logger,
 			Stats:      cfg.Stats,
 			TimeToLive: cfg.FailedUpdateTTL,
+			Clock:      cfg.BackendConfig.Clock,
 
 			// Short cleanup intervals to avoid storing potentially heavy errors for long time.
-			DeleteExpiredAfter:       time.Minute,
+			DeleteExpiredAfter:       failuresRetention(cfg.FailedUpdateMaxTTL),
 			DeleteExpiredJobInterval: time.Minute,
 		}.Use)
 	}
 
 	f.keyLocks = make(map[string]*klOf[V])
+	f.builds = newBuildLimiter(cfg.MaxConcurrentBuilds)
 
 	return f
 }
@@ -135,8 +150,14 @@ func (f *FailoverOf[V]) Get(
 		err error
 	)
 
+	// Forced refresh ignores cached value and recent failures.
+	forceRefresh := ForceRefresh(ctx)
+	if forceRefresh {
+		err = ErrNotFound
+	}
+
 	// Performing initial check before critical section.
-	if !f.config.SyncRead {
+	if !f.config.SyncRead && !forceRefresh {
 		// Checking for valid value in cache store.
 		if val, err = f.backend.Read(ctx, key); err == nil {
 			return val, nil
@@ -144,15 +165,17 @@ func (f *FailoverOf[V]) Get(
 	}
 
 	// Locking key for update or finding active lock.
+	lk := lockKey(ctx, key)
+
 	f.lock.Lock()
 	var keyLock *klOf[V]
 
 	alreadyLocked := false
 
-	keyLock, alreadyLocked = f.keyLocks[string(key)]
+	keyLock, alreadyLocked = f.keyLocks[lk]
 	if !alreadyLocked {
 		keyLock = &klOf[V]{lock: make(chan struct{})}
-		f.keyLocks[string(key)] = keyLock
+		f.keyLocks[lk] = keyLock
 	}
 	f.lock.Unlock()
 
@@ -160,14 +183,14 @@ func (f *FailoverOf[V]) Get(
 	defer func() {
 		if !alreadyLocked {
 			f.lock.Lock()
-			delete(f.keyLocks, string(key))
+			delete(f.keyLocks, lk)
 			close(keyLock.lock)
 			f.lock.Unlock()
 		}
 	}()
 
 	// Performing initial check in critical section.
-	if f.config.SyncRead {
+	if f.config.SyncRead && !forceRefresh {
 		// Checking for valid value in cache store.
 		if val, err = f.backend.Read(ctx, key); err == nil {
 			if !alreadyLocked {
@@ -188,9 +211,14 @@ func (f *FailoverOf[V]) Get(
 		return f.waitForValue(withoutSkipRead(ctx), key, keyLock)
 	}
 
+	softExpired := errors.Is(err, ErrSoftExpired)
+
 	// Pushing expired value with short ttl to serve during update.
 	if v, freshEnough := f.freshEnough(err); freshEnough {
-		if err = f.refreshStale(ctx, key, v); err != nil {
+		// Soft expired value is valid till hard expiration, so it is served without refreshing.
+		if softExpired {
+			err = nil
+		} else if err = f.refreshStale(ctx, key, v); err != nil {
 			return val, err
 		}
 
@@ -198,7 +226,13 @@ func (f *FailoverOf[V]) Get(
 	}
 
 	// Check if update failed recently.
-	if err := f.recentlyFailed(ctx, key); err != nil {
+	if err := f.recentlyFailed(ctx, key); err != nil && !forceRefresh {
+		if softExpired {
+			keyLock.val = val
+
+			return val, nil
+		}
+
 		keyLock.err = err
 
 		return val, err
@@ -233,7 +267,7 @@ func (f *FailoverOf[V]) Get(
 	go func() {
 		defer func() {
 			f.lock.Lock()
-			delete(f.keyLocks, string(key))
+			delete(f.keyLocks, lk)
 			close(keyLock.lock)
 			f.lock.Unlock()
 		}()
@@ -260,7 +294,8 @@ func (f *FailoverOf[V]) freshEnough(err error) (val V, _ bool) {
 	var errExpired ErrWithExpiredItemOf[V]
 
 	if errors.As(err, &errExpired) {
-		if f.config.MaxStaleness == 0 || time.Since(errExpired.ExpiredAt()) < f.config.MaxStaleness {
+		// Soft expired value is valid regardless of MaxStaleness.
+		if errors.Is(err, ErrSoftExpired) || f.config.MaxStaleness == 0 || time.Since(errExpired.ExpiredAt()) < f.config.MaxStaleness {
 			return errExpired.Value(), true
 		}
 	}
@@ -273,6 +308,10 @@ func (f *FailoverOf[V]) waitForValue(ctx context.Context, key []byte, keyLock *k
 		f.logDebug(ctx, "waiting for cache value", "name", f.config.Name, "key", key)
 	}
 
+	if f.stat != nil {
+		f.stat.Add(ctx, MetricSingleFlightDedup, 1, "name", f.config.Name)
+	}
+
 	// Waiting for value built by keyLock owner.
 	<-keyLock.lock
 
@@ -305,6 +344,13 @@ func (f *FailoverOf[V]) doBuild(
 	val V,
 	buildFunc func(ctx context.Context) (V, error),
 ) (v V, _ error) { //nolint:dupl // Generic API is different.
+	// Build slot is not available, such failure is not cached.
+	if err := f.builds.acquire(ctx, f.config.RejectOnBuildLimit); err != nil {
+		return v, err
+	}
+
+	defer f.builds.release()
+
 	if f.stat != nil {
 		defer func() {
 			f.stat.Add(ctx, MetricBuild, 1, "name", f.config.Name)
@@ -322,7 +368,7 @@ func (f *FailoverOf[V]) doBuild(
 		}
 
 		if f.config.FailedUpdateTTL > -1 {
-			writeErr := f.Errors.Write(ctx, key, err)
+			writeErr := f.cacheFailure(ctx, key, err)
 			if writeErr != nil && f.logError != nil {
 				f.logError(ctx, "failed to cache update failure",
 					"error", writeErr,
@@ -340,6 +386,8 @@ func (f *FailoverOf[V]) doBuild(
 		return v, writeErr
 	}
 
+	f.resetFailures(ctx, key)
+
 	if f.config.ObserveMutability && err == nil {
 		f.observeMutability(ctx, uVal, val)
 	}
@@ -368,6 +416,36 @@ func (f *FailoverOf[V]) recentlyFailed(ctx context.Context, key []byte) error {
 	return nil
 }
 
+// cacheFailure stores build error with ttl of failures backoff.
+func (f *FailoverOf[V]) cacheFailure(ctx context.Context, key []byte, err error) error {
+	// Error is stored as is unless failures backoff is enabled.
+	if f.config.FailedUpdateMaxTTL <= 0 {
+		return f.Errors.Write(ctx, key, err)
+	}
+
+	failures := 1
+
+	prev, readErr := f.Errors.Read(ctx, key)
+	if readErr != nil {
+		prev, _, _ = StaleValueOf[error](readErr)
+	}
+
+	if prev != nil {
+		failures += prevFailures(prev)
+	}
+
+	ctx = WithTTL(ctx, failedUpdateTTL(f.config.FailedUpdateTTL, f.config.FailedUpdateMaxTTL, failures), false)
+
+	return f.Errors.Write(ctx, key, failedUpdate{err: err, failures: failures})
+}
+
+// resetFailures removes cached error after successful build to reset backoff.
+func (f *FailoverOf[V]) resetFailures(ctx context.Context, key []byte) {
+	if f.config.FailedUpdateTTL > -1 && f.config.FailedUpdateMaxTTL > 0 {
+		_ = f.Errors.Delete(ctx, key)
+	}
+}
+
 func (f *FailoverOf[V]) observeMutability(ctx context.Context, uVal, val V) {
 	equal := reflect.DeepEqual(val, uVal)
 	if !equal {
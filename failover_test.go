@@ -893,3 +893,343 @@ func TestFailover_Get_alwaysFail(t *testing.T) {
 		})
 	}
 }
+
+func TestFailover_Get_forceRefresh(t *testing.T) {
+	for _, be := range backends() {
+		be := be
+
+		t.Run(fmt.Sprintf("%T", be), func(t *testing.T) {
+			ctx := context.Background()
+			key := []byte("some-key")
+
+			c := cache.NewFailover(cache.FailoverConfig{
+				Backend: be,
+			}.Use)
+
+			v, err := c.Get(ctx, key, func(ctx context.Context) (interface{}, error) {
+				return 123, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 123, v)
+
+			fctx := cache.WithForceRefresh(ctx)
+
+			_, err = c.Get(fctx, key, func(ctx context.Context) (interface{}, error) {
+				return nil, errors.New("failed")
+			})
+			assert.EqualError(t, err, "failed")
+
+			// Recent failure is ignored with forced refresh.
+			v, err = c.Get(fctx, key, func(ctx context.Context) (interface{}, error) {
+				return 456, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 456, v)
+
+			v, err = be.Read(ctx, key)
+			assert.NoError(t, err)
+			assert.Equal(t, 456, v)
+		})
+	}
+}
+
+func TestFailover_Get_maxConcurrentBuilds(t *testing.T) {
+	ctx := context.Background()
+	f := cache.NewFailover(cache.FailoverConfig{MaxConcurrentBuilds: 2}.Use)
+
+	var active, maxActive int64
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			v, err := f.Get(ctx, []byte(strconv.Itoa(i)), func(ctx context.Context) (interface{}, error) {
+				n := atomic.AddInt64(&active, 1)
+				defer atomic.AddInt64(&active, -1)
+
+				for {
+					m := atomic.LoadInt64(&maxActive)
+					if n <= m || atomic.CompareAndSwapInt64(&maxActive, m, n) {
+						break
+					}
+				}
+
+				time.Sleep(time.Millisecond)
+
+				return i, nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, i, v)
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int64(2), atomic.LoadInt64(&maxActive))
+}
+
+func TestFailover_Get_rejectOnBuildLimit(t *testing.T) {
+	ctx := context.Background()
+
+	for _, reject := range []bool{true, false} {
+		f := cache.NewFailover(cache.FailoverConfig{MaxConcurrentBuilds: 1, RejectOnBuildLimit: reject}.Use)
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		go func() {
+			_, _ = f.Get(ctx, []byte("slow"), func(ctx context.Context) (interface{}, error) {
+				close(started)
+				<-release
+
+				return 1, nil
+			})
+		}()
+
+		<-started
+
+		cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+
+		_, err := f.Get(cctx, []byte("other"), func(ctx context.Context) (interface{}, error) {
+			return 2, nil
+		})
+
+		cancel()
+
+		if reject {
+			assert.ErrorIs(t, err, cache.ErrTooManyBuilds)
+		} else {
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+
+		close(release)
+
+		// Rejected build is not cached as failure.
+		assert.Eventually(t, func() bool {
+			v, err := f.Get(ctx, []byte("other"), func(ctx context.Context) (interface{}, error) {
+				return 2, nil
+			})
+
+			return err == nil && v == 2
+		}, time.Second, time.Millisecond)
+	}
+}
+
+func TestFailover_Get_singleFlightDedup(t *testing.T) {
+	ctx := context.Background()
+	st := &stats.TrackerMock{}
+	f := cache.NewFailover(cache.FailoverConfig{Name: "test", Stats: st}.Use)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	wg := sync.WaitGroup{}
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+
+		v, err := f.Get(ctx, []byte("foo"), func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-release
+
+			return 1, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}()
+
+	<-started
+
+	go func() {
+		defer wg.Done()
+
+		v, err := f.Get(ctx, []byte("foo"), func(ctx context.Context) (interface{}, error) {
+			return 2, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return st.Int(cache.MetricSingleFlightDedup, "name", "test") == 1
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+}
+
+func TestFailover_Get_keyPrefix(t *testing.T) {
+	ctx := context.Background()
+	f := cache.NewFailover()
+
+	started := sync.WaitGroup{}
+	started.Add(2)
+
+	wg := sync.WaitGroup{}
+
+	for _, tenant := range []string{"a", "b"} {
+		wg.Add(1)
+
+		go func(tenant string) {
+			defer wg.Done()
+
+			v, err := f.Get(cache.WithKeyPrefix(ctx, []byte(tenant+":")), []byte("k"),
+				func(ctx context.Context) (interface{}, error) {
+					// Both builds are in flight at the same time.
+					started.Done()
+					started.Wait()
+
+					return tenant, nil
+				})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tenant, v)
+		}(tenant)
+	}
+
+	wg.Wait()
+
+	for _, tenant := range []string{"a", "b"} {
+		v, err := f.Get(ctx, []byte(tenant+":k"), func(ctx context.Context) (interface{}, error) {
+			return nil, errors.New("unexpected build")
+		})
+		require.NoError(t, err)
+		assert.Equal(t, tenant, v)
+	}
+}
+
+func TestFailover_Get_softExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	f := cache.NewFailover(cache.FailoverConfig{
+		BackendConfig: cache.Config{
+			Clock:           clock,
+			TimeToLive:      10 * time.Minute,
+			SoftTTLFraction: 0.5,
+		},
+	}.Use)
+
+	builds := int64(0)
+	build := func(ctx context.Context) (interface{}, error) {
+		return atomic.AddInt64(&builds, 1), nil
+	}
+
+	v, err := f.Get(ctx, []byte("foo"), build)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+
+	clock.Add(6 * time.Minute)
+
+	// Soft expired value is served while it is refreshed in background.
+	v, err = f.Get(ctx, []byte("foo"), build)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+
+	assert.Eventually(t, func() bool {
+		v, err := f.Get(ctx, []byte("foo"), build)
+
+		return err == nil && v == int64(2)
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, int64(2), atomic.LoadInt64(&builds))
+}
+
+func TestFailover_Get_cachedFailureDefault(t *testing.T) {
+	ctx := context.Background()
+	f := cache.NewFailover()
+	buildErr := errors.New("failed")
+	builds := 0
+
+	build := func(ctx context.Context) (interface{}, error) {
+		builds++
+
+		return nil, buildErr
+	}
+
+	_, err := f.Get(ctx, []byte("foo"), build)
+	assert.Equal(t, buildErr, err)
+
+	_, err = f.Get(ctx, []byte("foo"), build)
+	assert.True(t, err == buildErr) //nolint:errorlint // Identity of error value is checked.
+	assert.Equal(t, 1, builds)
+}
+
+func TestFailover_Get_failedUpdateBackoff(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	f := cache.NewFailover(cache.FailoverConfig{
+		FailedUpdateTTL:    time.Second,
+		FailedUpdateMaxTTL: 3 * time.Second,
+		BackendConfig: cache.Config{
+			Clock: clock,
+		},
+	}.Use)
+
+	builds := 0
+	fail := true
+	build := func(ctx context.Context) (interface{}, error) {
+		builds++
+
+		if fail {
+			return nil, errors.New("failed")
+		}
+
+		return builds, nil
+	}
+
+	_, err := f.Get(ctx, []byte("foo"), build)
+	require.EqualError(t, err, "failed")
+	assert.False(t, errors.Is(err, cache.ErrCachedFailure))
+	assert.Equal(t, 1, builds)
+
+	_, err = f.Get(ctx, []byte("foo"), build)
+	require.EqualError(t, err, "failed")
+	assert.True(t, errors.Is(err, cache.ErrCachedFailure))
+	assert.Equal(t, 1, builds)
+
+	// Ttl of cached failure grows with consecutive failures: 1s, 2s, 3s (capped), with ±5% of jitter.
+	for i, ttl := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		clock.Add(ttl * 9 / 10)
+
+		_, err = f.Get(ctx, []byte("foo"), build)
+		assert.True(t, errors.Is(err, cache.ErrCachedFailure))
+		assert.Equal(t, i+1, builds)
+
+		clock.Add(ttl * 2 / 10)
+
+		_, err = f.Get(ctx, []byte("foo"), build)
+		assert.False(t, errors.Is(err, cache.ErrCachedFailure))
+		assert.Equal(t, i+2, builds)
+	}
+
+	// Successful build resets backoff.
+	clock.Add(3300 * time.Millisecond)
+
+	fail = false
+
+	v, err := f.Get(ctx, []byte("foo"), build)
+	require.NoError(t, err)
+	assert.Equal(t, 5, v)
+
+	fail = true
+
+	_, err = f.Get(cache.WithForceRefresh(ctx), []byte("foo"), build)
+	require.EqualError(t, err, "failed")
+
+	cached, err := f.Errors.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.True(t, errors.Is(cached.(error), cache.ErrCachedFailure))
+
+	clock.Add(1100 * time.Millisecond)
+
+	_, err = f.Errors.Read(ctx, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+}
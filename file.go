@@ -0,0 +1,358 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// fileSuffix is an extension of files with cache entries.
+const fileSuffix = ".cache"
+
+// fileHeaderSize is a size of expiration timestamp and grace period in front of encoded entry.
+const fileHeaderSize = 16
+
+var (
+	_ ReadWriter = &File{}
+	_ Deleter    = &File{}
+)
+
+// File is a cache backend that stores gob-encoded entries as files in a directory.
+//
+// Please use NewFile to create it.
+type File struct {
+	dir   string
+	locks [keyLocks]sync.Mutex
+
+	t *Trait
+}
+
+// NewFile creates an instance of cache backed by files in a directory.
+//
+// Each entry is stored in a separate file named by a hash of the key, file starts with expiration timestamp
+// and grace period followed by gob-encoded entry, please register cached types in advance with GobRegister.
+// Files are replaced with atomic rename, so that readers never observe partially written entry.
+// Expired files are removed by background job according to Config.DeleteExpiredAfter,
+// please call Close to stop background jobs when cache is not needed anymore.
+func NewFile(dir string, options ...func(cfg *Config)) (*File, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	c := &File{
+		dir: dir,
+	}
+
+	cfg := Config{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	c.t = NewTrait(cfg, func(t *Trait) {
+		t.DeleteExpired = c.deleteExpired
+		t.Len = c.Len
+	})
+
+	return c, nil
+}
+
+// Close stops background jobs of cache, it is safe to call it multiple times.
+//
+// Files are kept in directory and are available to a new instance of cache.
+func (c *File) Close() error {
+	c.t.Close()
+
+	return nil
+}
+
+func (c *File) path(h uint64) string {
+	return filepath.Join(c.dir, strconv.FormatUint(h, 16)+fileSuffix)
+}
+
+func (c *File) lock(h uint64) *sync.Mutex {
+	return &c.locks[h%keyLocks]
+}
+
+// Read gets value.
+func (c *File) Read(ctx context.Context, key []byte) (interface{}, error) {
+	if SkipRead(ctx) {
+		return nil, ErrNotFound
+	}
+
+	key = c.t.key(ctx, key)
+
+	e, err := c.load(c.path(xxhash.Sum64(key)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c.t.prepareRead(ctx, key, nil, false)
+		}
+
+		return nil, err
+	}
+
+	// Different key with same hash is a miss.
+	if !bytes.Equal(e.K, key) {
+		return c.t.prepareRead(ctx, key, nil, false)
+	}
+
+	return c.t.prepareRead(ctx, key, e, true)
+}
+
+func (c *File) load(path string) (*TraitEntry, error) {
+	b, err := os.ReadFile(path) //nolint:gosec // Path is built from hash of the key.
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) < fileHeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	e := TraitEntry{}
+	if err := gob.NewDecoder(bytes.NewReader(b[fileHeaderSize:])).Decode(&e); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// Write sets value by the key.
+func (c *File) Write(ctx context.Context, k []byte, v interface{}) error {
+	if c.t.skipWrite(ctx, k) {
+		return nil
+	}
+
+	k, err := c.t.writeKey(ctx, k)
+	if err != nil {
+		return err
+	}
+
+	if err = c.t.prepareWrite(ctx, k, v); err != nil {
+		return err
+	}
+
+	ttl, expireAt := c.t.expireAt(ctx)
+	grace := int64(GracePeriod(ctx))
+
+	buf := bytes.NewBuffer(make([]byte, fileHeaderSize))
+	binary.BigEndian.PutUint64(buf.Bytes(), uint64(expireAt))
+	binary.BigEndian.PutUint64(buf.Bytes()[8:], uint64(grace))
+
+	e := TraitEntry{K: k, V: v, E: expireAt, G: grace, N: c.t.Config.Generation, T: ts(c.t.now())}
+	if err := gob.NewEncoder(buf).Encode(e); err != nil {
+		return err
+	}
+
+	h := xxhash.Sum64(k)
+
+	if err := c.store(h, buf.Bytes()); err != nil {
+		return err
+	}
+
+	c.t.NotifyWritten(ctx, k, v, ttl)
+
+	return nil
+}
+
+// store writes data to a temporary file and renames it to replace entry file.
+func (c *File) store(h uint64, data []byte) error {
+	f, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+
+		return err
+	}
+
+	l := c.lock(h)
+	l.Lock()
+	defer l.Unlock()
+
+	if err := os.Rename(f.Name(), c.path(h)); err != nil {
+		_ = os.Remove(f.Name())
+
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes value by the key.
+//
+// It fails with ErrNotFound if key does not exist, unless Config.IgnoreMissingDelete is enabled.
+func (c *File) Delete(ctx context.Context, key []byte) error {
+	key = c.t.key(ctx, key)
+	h := xxhash.Sum64(key)
+	path := c.path(h)
+
+	l := c.lock(h)
+	l.Lock()
+
+	e, err := c.load(path)
+	if err == nil {
+		if bytes.Equal(e.K, key) {
+			err = os.Remove(path)
+		} else {
+			// Different key with same hash is kept.
+			err = fs.ErrNotExist
+		}
+	}
+
+	l.Unlock()
+
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c.t.missingDelete()
+		}
+
+		return err
+	}
+
+	c.t.NotifyDeleted(ctx, key)
+	c.t.notifyRemoved(e.K, e.V, EvictReasonDeleted)
+
+	return nil
+}
+
+// Len returns number of elements including expired.
+func (c *File) Len() int {
+	cnt := 0
+
+	_ = c.walk(func(_ uint64, _ string) error {
+		cnt++
+
+		return nil
+	})
+
+	return cnt
+}
+
+// Stats returns a snapshot of cache activity counters.
+func (c *File) Stats() CacheStats {
+	return c.t.Stats()
+}
+
+// walk calls function for every entry file in directory.
+func (c *File) walk(fn func(h uint64, path string) error) error {
+	items, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		name := item.Name()
+		if item.IsDir() || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+
+		h, err := strconv.ParseUint(strings.TrimSuffix(name, fileSuffix), 16, 64)
+		if err != nil {
+			continue
+		}
+
+		if err := fn(h, filepath.Join(c.dir, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *File) deleteExpired(before time.Time) {
+	beforeTS := ts(before)
+	cnt := 0
+
+	err := c.walk(func(h uint64, path string) error {
+		expireAt, grace, err := readHeader(path)
+		if err != nil || expireAt == 0 || !c.t.deleteExpiredBefore(expireAt, grace, beforeTS) {
+			return nil //nolint:nilerr // Entry is skipped if it can not be checked, for example concurrently removed.
+		}
+
+		var e *TraitEntry
+
+		if c.t.Config.OnEvicted != nil || c.t.Config.CanEvict != nil {
+			e, _ = c.load(path) //nolint:errcheck // Entry value is not required for removal.
+		}
+
+		// Config.CanEvict is called without file lock, so that it can read cache.
+		if e != nil && c.t.pinnedExpired(e) {
+			return nil
+		}
+
+		l := c.lock(h)
+		l.Lock()
+
+		// Entry that was replaced meanwhile is kept.
+		if exp, g, err := readHeader(path); err != nil || exp != expireAt || g != grace {
+			l.Unlock()
+
+			return nil //nolint:nilerr // Entry is skipped if it can not be checked, for example concurrently removed.
+		}
+
+		err = os.Remove(path)
+		l.Unlock()
+
+		if err == nil {
+			cnt++
+
+			if e != nil {
+				c.t.notifyRemoved(e.K, e.V, EvictReasonExpired)
+			}
+		}
+
+		return nil
+	})
+
+	if cnt > 0 {
+		c.t.notifyDeletedExpired(cnt)
+	}
+
+	if err != nil && c.t.Log.logError != nil {
+		c.t.Log.logError(context.Background(), "failed to delete expired cache files",
+			"name", c.t.Config.Name,
+			"error", err,
+		)
+	}
+}
+
+// readHeader reads expiration timestamp and grace period from the header of entry file.
+func readHeader(path string) (expireAt, grace int64, err error) {
+	f, err := os.Open(path) //nolint:gosec // Path is built from hash of the key.
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var h [fileHeaderSize]byte
+
+	if _, err := io.ReadFull(f, h[:]); err != nil {
+		return 0, 0, err
+	}
+
+	return int64(binary.BigEndian.Uint64(h[:8])), int64(binary.BigEndian.Uint64(h[8:])), nil
+}
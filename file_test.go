@@ -0,0 +1,174 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/stats"
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	clock := newFakeClock()
+	st := &stats.TrackerMock{}
+
+	c, err := cache.NewFile(dir, func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.Stats = st
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+		cfg.DeleteExpiredAfter = time.Hour
+		cfg.DeleteExpiredJobInterval = time.Minute
+	})
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, c.Close())
+	}()
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	require.NoError(t, c.Write(cache.WithTTL(ctx, 24*time.Hour, false), []byte("baz"), 123))
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	_, err = c.Read(ctx, []byte("qux"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+	assert.Equal(t, 1, st.Int(cache.MetricHit, "name", ""))
+	assert.Equal(t, 1, st.Int(cache.MetricMiss, "name", ""))
+	assert.Equal(t, 2, c.Len())
+
+	// Another instance reads same files.
+	c2, err := cache.NewFile(dir, func(cfg *cache.Config) {
+		cfg.Clock = clock
+	})
+	require.NoError(t, err)
+	require.NoError(t, c2.Close())
+
+	v, err = c2.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	clock.Add(2 * time.Minute)
+
+	_, err = c.Read(ctx, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+
+	var errExpired cache.ErrWithExpiredItem
+
+	require.True(t, errors.As(err, &errExpired))
+	assert.Equal(t, "bar", errExpired.Value())
+
+	// Janitor removes expired file after DeleteExpiredAfter, fresh entry is kept.
+	assert.Eventually(t, func() bool {
+		clock.Add(time.Minute)
+
+		return c.Len() == 1
+	}, time.Second, time.Millisecond)
+
+	v, err = c.Read(ctx, []byte("baz"))
+	require.NoError(t, err)
+	assert.Equal(t, 123, v)
+
+	require.NoError(t, c.Delete(ctx, []byte("baz")))
+	assert.True(t, errors.Is(c.Delete(ctx, []byte("baz")), cache.ErrNotFound))
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestFile_deleteExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	var (
+		mu      sync.Mutex
+		evicted []string
+	)
+
+	c, err := cache.NewFile(t.TempDir(), func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+		cfg.DeleteExpiredAfter = time.Hour
+		cfg.DeleteExpiredJobInterval = time.Minute
+		cfg.CanEvict = func(e cache.Entry) bool {
+			return string(e.Key()) != "pinned"
+		}
+		cfg.OnEvicted = func(key []byte, value interface{}, reason cache.EvictReason) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			evicted = append(evicted, string(key))
+		}
+	})
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, c.Close())
+	}()
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	require.NoError(t, c.Write(ctx, []byte("pinned"), 2))
+	require.NoError(t, c.Write(cache.WithGracePeriod(ctx, 3*time.Hour), []byte("grace"), 3))
+
+	// Entry with grace period outlives DeleteExpiredAfter, pinned entry is kept.
+	assert.Eventually(t, func() bool {
+		clock.Add(time.Minute)
+
+		return c.Len() == 2
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, []string{"foo"}, evicted)
+	mu.Unlock()
+
+	_, err = c.Read(ctx, []byte("grace"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+
+	clock.Add(2 * time.Hour)
+
+	assert.Eventually(t, func() bool {
+		clock.Add(time.Minute)
+
+		return c.Len() == 1
+	}, time.Second, time.Millisecond)
+
+	_, err = c.Read(ctx, []byte("pinned"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+}
+
+func TestFile_Delete_hashCollision(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	c, err := cache.NewFile(dir)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, c.Close())
+	}()
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), 1))
+
+	// Simulating collision by placing entry of foo to the file of bar.
+	path := func(key string) string {
+		return filepath.Join(dir, strconv.FormatUint(xxhash.Sum64String(key), 16)+".cache")
+	}
+
+	require.NoError(t, os.Rename(path("foo"), path("bar")))
+
+	assert.True(t, errors.Is(c.Delete(ctx, []byte("bar")), cache.ErrNotFound))
+	assert.Equal(t, 1, c.Len())
+}
@@ -2,10 +2,12 @@ package cache
 
 import (
 	"encoding/gob"
+	"fmt"
 	"hash"
 	"hash/fnv"
 	"reflect"
 	"strings"
+	"sync"
 )
 
 var (
@@ -46,6 +48,34 @@ func GobRegister(values ...interface{}) {
 	}
 }
 
+var autoGobTypes sync.Map
+
+// autoGobRegister registers concrete type of value with gob once.
+//
+// It does not affect GobTypesHash, because order of first writes is not deterministic.
+func autoGobRegister(value interface{}) (err error) {
+	if value == nil {
+		return nil
+	}
+
+	t := reflect.TypeOf(value)
+	if _, ok := autoGobTypes.Load(t); ok {
+		return nil
+	}
+
+	defer func() {
+		// gob.Register panics on name conflicts.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to register gob type %s: %v", t.String(), r)
+		}
+	}()
+
+	gob.Register(value)
+	autoGobTypes.Store(t, true)
+
+	return nil
+}
+
 // RecursiveTypeHash hashes type of value recursively to ensure structural match.
 func recursiveTypeHash(t reflect.Type, h hash.Hash64, met map[reflect.Type]bool) {
 	for {
@@ -94,4 +124,5 @@ func init() {
 	// Registering commonly used types.
 	gob.Register(map[string]interface{}{})
 	gob.Register([]interface{}{})
+	gob.Register(missMarker(true))
 }
@@ -3,10 +3,16 @@ package cache_test
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"runtime"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/bool64/cache"
 	"github.com/stretchr/testify/assert"
@@ -151,3 +157,398 @@ func BenchmarkShardedMap_Dump(b *testing.B) {
 
 	runtime.GC()
 }
+
+type autoRegisteredEntity struct {
+	Name string
+}
+
+func TestConfig_AutoGobRegister(t *testing.T) {
+	ctx := context.Background()
+
+	c1 := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.AutoGobRegister = true
+	})
+	c2 := cache.NewSyncMap()
+
+	require.NoError(t, c1.Write(ctx, []byte("key1"), autoRegisteredEntity{Name: "foo"}))
+	require.NoError(t, c1.Write(ctx, []byte("key2"), autoRegisteredEntity{Name: "bar"}))
+
+	w := bytes.NewBuffer(nil)
+
+	n, err := c1.Dump(w)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = c2.Restore(w)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	v, err := c2.Read(ctx, []byte("key1"))
+	require.NoError(t, err)
+	assert.Equal(t, autoRegisteredEntity{Name: "foo"}, v)
+}
+
+func TestRestoreMerge(t *testing.T) {
+	ctx := context.Background()
+
+	type mergeRestorer interface {
+		cache.ReadWriter
+		cache.Dumper
+		RestoreMerge(r io.Reader, strategy cache.MergeStrategy) (int, error)
+	}
+
+	for _, tc := range []struct {
+		strategy cache.MergeStrategy
+		stored   int
+		a, b     interface{}
+	}{
+		{strategy: cache.MergeOverwrite, stored: 3, a: "dumped", b: "dumped"},
+		{strategy: cache.MergeKeepNewer, stored: 2, a: "dumped", b: "existing"},
+		{strategy: cache.MergeKeepExisting, stored: 1, a: "existing", b: "existing"},
+	} {
+		for _, newCache := range []func(options ...func(cfg *cache.Config)) mergeRestorer{
+			func(options ...func(cfg *cache.Config)) mergeRestorer { return cache.NewShardedMap(options...) },
+			func(options ...func(cfg *cache.Config)) mergeRestorer { return cache.NewSyncMap(options...) },
+		} {
+			src := newCache()
+			dst := newCache()
+
+			require.NoError(t, src.Write(cache.WithTTL(ctx, time.Hour, false), []byte("a"), "dumped"))
+			require.NoError(t, src.Write(cache.WithTTL(ctx, time.Minute, false), []byte("b"), "dumped"))
+			require.NoError(t, src.Write(ctx, []byte("c"), "dumped"))
+
+			require.NoError(t, dst.Write(cache.WithTTL(ctx, time.Minute, false), []byte("a"), "existing"))
+			require.NoError(t, dst.Write(cache.WithTTL(ctx, time.Hour, false), []byte("b"), "existing"))
+
+			w := bytes.NewBuffer(nil)
+			_, err := src.Dump(w)
+			require.NoError(t, err)
+
+			// Broken dump does not affect cache.
+			_, err = dst.RestoreMerge(bytes.NewReader(w.Bytes()[:w.Len()-5]), tc.strategy)
+			require.Error(t, err)
+
+			_, err = dst.Read(ctx, []byte("c"))
+			assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+			n, err := dst.RestoreMerge(w, tc.strategy)
+			require.NoError(t, err)
+			assert.Equal(t, tc.stored, n)
+
+			v, err := dst.Read(ctx, []byte("a"))
+			require.NoError(t, err)
+			assert.Equal(t, tc.a, v)
+
+			v, err = dst.Read(ctx, []byte("b"))
+			require.NoError(t, err)
+			assert.Equal(t, tc.b, v)
+
+			v, err = dst.Read(ctx, []byte("c"))
+			require.NoError(t, err)
+			assert.Equal(t, "dumped", v)
+		}
+	}
+}
+
+func TestDumpLive(t *testing.T) {
+	ctx := context.Background()
+
+	type liveDumper interface {
+		cache.ReadWriter
+		cache.Restorer
+		DumpLive(w io.Writer) (int, error)
+		ExpireAll(ctx context.Context)
+	}
+
+	for _, c := range []liveDumper{cache.NewShardedMap(), cache.NewSyncMap()} {
+		require.NoError(t, c.Write(ctx, []byte("expired"), 1))
+		c.ExpireAll(ctx)
+		require.NoError(t, c.Write(ctx, []byte("live"), 2))
+		require.NoError(t, c.Write(cache.WithTTL(ctx, time.Hour, false), []byte("hour"), 3))
+
+		w := bytes.NewBuffer(nil)
+
+		n, err := c.DumpLive(w)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		c2 := cache.NewSyncMap()
+
+		n, err = c2.Restore(w)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		_, err = c2.Read(ctx, []byte("expired"))
+		assert.True(t, errors.Is(err, cache.ErrNotFound))
+	}
+}
+
+func TestRestore_dumpFormat(t *testing.T) {
+	ctx := context.Background()
+	src := cache.NewShardedMap()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, src.Write(ctx, []byte(strconv.Itoa(i)), i))
+	}
+
+	w := bytes.NewBuffer(nil)
+	_, err := src.Dump(w)
+	require.NoError(t, err)
+
+	dump := w.Bytes()
+
+	for _, c := range []cache.Restorer{cache.NewShardedMap(), cache.NewSyncMap(), cache.NoOp{}} {
+		_, err = c.Restore(bytes.NewReader([]byte("not a dump")))
+		assert.True(t, errors.Is(err, cache.ErrBadDumpFormat), err)
+
+		corrupted := append([]byte(nil), dump...)
+		corrupted[len(corrupted)/2] ^= 0xFF
+
+		_, err = c.Restore(bytes.NewReader(corrupted))
+		assert.Error(t, err)
+
+		_, err = c.Restore(bytes.NewReader(dump[:len(dump)-1]))
+		assert.True(t, errors.Is(err, cache.ErrDumpChecksum), err)
+
+		if r, ok := c.(cache.ReadWriter); ok {
+			_, err = r.Read(ctx, []byte("1"))
+			assert.True(t, errors.Is(err, cache.ErrNotFound), err)
+		}
+
+		n, err := c.Restore(bytes.NewReader(dump))
+		require.NoError(t, err)
+		assert.Equal(t, 100, n)
+	}
+}
+
+func TestDump_nilValues(t *testing.T) {
+	ctx := context.Background()
+
+	type dumpRestorer interface {
+		cache.ReadWriter
+		cache.Dumper
+		cache.Restorer
+	}
+
+	for _, c := range []dumpRestorer{
+		cache.NewShardedMap(cache.Config{MaxBytes: 1000}.Use),
+		cache.NewSyncMap(cache.Config{MaxBytes: 1000}.Use),
+	} {
+		require.NoError(t, c.Write(ctx, []byte("nil"), nil))
+		require.NoError(t, c.Write(ctx, []byte("nilPtr"), (*SomeEntity)(nil)))
+		require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+
+		v, err := c.Read(ctx, []byte("nil"))
+		require.NoError(t, err)
+		assert.Nil(t, v)
+
+		_, err = c.Read(ctx, []byte("missing"))
+		assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+		w := bytes.NewBuffer(nil)
+		dumped, err := c.Dump(w)
+		require.NoError(t, err)
+		assert.Equal(t, 2, dumped)
+
+		for _, dst := range []dumpRestorer{cache.NewShardedMap(), cache.NewSyncMap()} {
+			n, err := dst.Restore(bytes.NewReader(w.Bytes()))
+			require.NoError(t, err)
+			assert.Equal(t, 2, n)
+
+			v, err = dst.Read(ctx, []byte("nil"))
+			require.NoError(t, err)
+			assert.Nil(t, v)
+
+			_, err = dst.Read(ctx, []byte("nilPtr"))
+			assert.True(t, errors.Is(err, cache.ErrNotFound))
+		}
+	}
+}
+
+func TestSyncMap_RestoreLegacy(t *testing.T) {
+	w := bytes.NewBuffer(nil)
+	enc := gob.NewEncoder(w)
+
+	require.NoError(t, enc.Encode(cache.TraitEntry{K: []byte("foo"), V: 123}))
+	require.NoError(t, enc.Encode(cache.TraitEntry{K: []byte("bar"), V: 456}))
+
+	c := cache.NewSyncMap()
+
+	n, err := c.RestoreLegacy(w)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	v, err := c.Read(context.Background(), []byte("bar"))
+	require.NoError(t, err)
+	assert.Equal(t, 456, v)
+}
+
+func TestShardedMap_DumpWithOptions(t *testing.T) {
+	ctx := context.Background()
+	src := cache.NewShardedMap()
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, src.Write(ctx, []byte(strconv.Itoa(i)), "value"))
+	}
+
+	plain := bytes.NewBuffer(nil)
+	_, err := src.Dump(plain)
+	require.NoError(t, err)
+
+	compressed := bytes.NewBuffer(nil)
+	n, err := src.DumpWithOptions(compressed, cache.DumpOptions{Compress: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1000, n)
+	assert.Less(t, compressed.Len(), plain.Len())
+
+	for _, c := range []cache.Restorer{cache.NewShardedMap(), cache.NewSyncMap()} {
+		n, err = c.Restore(bytes.NewReader(compressed.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, 1000, n)
+
+		n, err = c.Restore(bytes.NewReader(plain.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, 1000, n)
+	}
+}
+
+// jsonSerializer encodes entries with string values as length-prefixed JSON.
+type jsonSerializer struct{}
+
+type jsonEntry struct {
+	K string `json:"k"`
+	V string `json:"v"`
+	E int64  `json:"e"`
+}
+
+func (jsonSerializer) Encode(w io.Writer, e cache.Entry) error {
+	v, ok := e.Value().(string)
+	if !ok {
+		return fmt.Errorf("%w: %T", cache.ErrUnexpectedType, e.Value())
+	}
+
+	b, err := json.Marshal(jsonEntry{K: string(e.Key()), V: v, E: e.ExpireAt().UnixNano()})
+	if err != nil {
+		return err
+	}
+
+	if err := binary.Write(w, binary.BigEndian, uint32(len(b))); err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
+
+func (jsonSerializer) Decode(r io.Reader) (cache.Entry, error) {
+	var l uint32
+
+	if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	var e jsonEntry
+
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+
+	return cache.TraitEntry{K: []byte(e.K), V: e.V, E: e.E}, nil
+}
+
+func TestConfig_Serializer(t *testing.T) {
+	ctx := context.Background()
+	cfg := cache.Config{Serializer: jsonSerializer{}}
+
+	type dumpRestorer interface {
+		cache.ReadWriter
+		cache.Dumper
+		cache.Restorer
+	}
+
+	for _, src := range []dumpRestorer{cache.NewShardedMap(cfg.Use), cache.NewSyncMap(cfg.Use)} {
+		for i := 0; i < 100; i++ {
+			require.NoError(t, src.Write(ctx, []byte(strconv.Itoa(i)), "v"+strconv.Itoa(i)))
+		}
+
+		w := bytes.NewBuffer(nil)
+		n, err := src.Dump(w)
+		require.NoError(t, err)
+		assert.Equal(t, 100, n)
+		assert.Contains(t, w.String(), `{"k":"42","v":"v42","e":`)
+
+		for _, dst := range []dumpRestorer{cache.NewShardedMap(cfg.Use), cache.NewSyncMap(cfg.Use)} {
+			n, err = dst.Restore(bytes.NewReader(w.Bytes()))
+			require.NoError(t, err)
+			assert.Equal(t, 100, n)
+
+			v, err := dst.Read(ctx, []byte("42"))
+			require.NoError(t, err)
+			assert.Equal(t, "v42", v)
+		}
+
+		// Dump of default gob serializer is not compatible.
+		_, err = cache.NewSyncMap().Restore(bytes.NewReader(w.Bytes()))
+		assert.Error(t, err)
+
+		// Serializer errors are propagated.
+		require.NoError(t, src.Write(ctx, []byte("int"), 123))
+
+		_, err = src.Dump(io.Discard)
+		assert.True(t, errors.Is(err, cache.ErrUnexpectedType), err)
+	}
+}
+
+func TestConfig_Generation(t *testing.T) {
+	ctx := context.Background()
+	src := cache.NewSyncMap(cache.Config{Generation: 1}.Use)
+
+	require.NoError(t, src.Write(ctx, []byte("foo"), "bar"))
+
+	dump := bytes.NewBuffer(nil)
+	_, err := src.Dump(dump)
+	require.NoError(t, err)
+
+	type restoreReader interface {
+		cache.Restorer
+		cache.Reader
+	}
+
+	for _, gen := range []uint64{0, 1, 2} {
+		for _, c := range []restoreReader{
+			cache.NewShardedMap(cache.Config{Generation: gen}.Use),
+			cache.NewSyncMap(cache.Config{Generation: gen}.Use),
+		} {
+			n, err := c.Restore(bytes.NewReader(dump.Bytes()))
+			require.NoError(t, err)
+
+			_, readErr := c.Read(ctx, []byte("foo"))
+
+			if gen == 1 {
+				assert.Equal(t, 1, n)
+				assert.NoError(t, readErr)
+			} else {
+				assert.Equal(t, 0, n)
+				assert.True(t, errors.Is(readErr, cache.ErrNotFound))
+			}
+		}
+	}
+
+	// Entries of shared storage are checked on read.
+	store := newMapStore()
+
+	require.NoError(t, cache.NewRemote(store, func(cfg *cache.RemoteConfig) {
+		cfg.Generation = 1
+	}).Write(ctx, []byte("foo"), "bar"))
+
+	_, err = cache.NewRemote(store, func(cfg *cache.RemoteConfig) {
+		cfg.Generation = 2
+	}).Read(ctx, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+}
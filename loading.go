@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LoadingConfig is optional configuration for NewLoading.
+type LoadingConfig struct {
+	// Config controls default backend, Config.MissTTL is used as time to live of cacheable loader errors.
+	Config
+
+	// Backend stores loaded values, default is ShardedMap with Config.
+	Backend ReadWriter
+
+	// MaxConcurrentBuilds limits number of simultaneous loads of different keys, default 0 (unlimited).
+	// Loads over limit wait for a slot or for context cancellation.
+	MaxConcurrentBuilds int
+
+	// RejectOnBuildLimit makes loads over MaxConcurrentBuilds fail with ErrTooManyBuilds instead of waiting.
+	RejectOnBuildLimit bool
+}
+
+// Use is a functional option for NewLoading to apply configuration.
+func (lc LoadingConfig) Use(cfg *LoadingConfig) {
+	*cfg = lc
+}
+
+// CacheableError is an error of loader that can be stored in cache.
+//
+// Loader errors are not cached by default, such error is cached if Cacheable returns true.
+type CacheableError interface {
+	error
+	Cacheable() bool
+}
+
+// loadError is a cached error of loader.
+type loadError struct {
+	err error
+}
+
+var (
+	_ ReadWriter = &Loading{}
+	_ Deleter    = &Loading{}
+)
+
+// Loading is a read-through cache that loads missing values with a loader function.
+//
+// Please use NewLoading to create instance.
+type Loading struct {
+	backend ReadWriter
+	loader  func(ctx context.Context, key []byte) (interface{}, error)
+	missTTL time.Duration
+
+	flights    flightGroup
+	loads      buildLimiter
+	rejectLoad bool
+
+	stat          StatsTracker
+	name          string
+	recoverLoader bool
+}
+
+// NewLoading creates a read-through cache with a loader of missing values.
+//
+// Read invokes loader on cache miss or expiration and stores the result in backend,
+// concurrent loads of the same key are deduplicated.
+// Loader errors are not stored unless they implement CacheableError.
+func NewLoading(
+	loader func(ctx context.Context, key []byte) (interface{}, error),
+	options ...func(cfg *LoadingConfig),
+) *Loading {
+	cfg := LoadingConfig{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	if cfg.Backend == nil {
+		cfg.Backend = NewShardedMap(cfg.Config.Use)
+	}
+
+	return &Loading{
+		backend: cfg.Backend,
+		loader:  loader,
+		missTTL: cfg.MissTTL,
+
+		loads:      newBuildLimiter(cfg.MaxConcurrentBuilds),
+		rejectLoad: cfg.RejectOnBuildLimit,
+
+		stat:          cfg.Stats,
+		name:          cfg.Name,
+		recoverLoader: cfg.RecoverCallbacks,
+	}
+}
+
+// Read gets value from backend or loads it on cache miss.
+func (l *Loading) Read(ctx context.Context, key []byte) (interface{}, error) {
+	v, err := l.read(ctx, key)
+	if err == nil || (!errors.Is(err, ErrNotFound) && !errors.Is(err, ErrExpired)) {
+		return v, err
+	}
+
+	// Loads are deduplicated by prefixed key, so that callers with different key prefixes do not share values.
+	v, shared, err := l.flights.do(lockKey(ctx, key), func() (interface{}, error) {
+		// Checking again in case another load has just finished.
+		if cached, readErr := l.read(ctx, key); readErr == nil ||
+			(!errors.Is(readErr, ErrNotFound) && !errors.Is(readErr, ErrExpired)) {
+			return cached, readErr
+		}
+
+		if err := l.loads.acquire(ctx, l.rejectLoad); err != nil {
+			return nil, err
+		}
+
+		val, loadErr := l.load(ctx, key)
+		l.loads.release()
+
+		if loadErr != nil {
+			var ce CacheableError
+			if errors.As(loadErr, &ce) && ce.Cacheable() {
+				l.writeError(ctx, key, loadErr)
+			}
+
+			return nil, loadErr
+		}
+
+		if err := l.backend.Write(ctx, key, val); err != nil {
+			return nil, err
+		}
+
+		return val, nil
+	})
+
+	if shared && l.stat != nil {
+		l.stat.Add(ctx, MetricSingleFlightDedup, 1, "name", l.name)
+	}
+
+	return v, err
+}
+
+// load invokes loader, panic is returned as ErrCallbackPanic error if Config.RecoverCallbacks is enabled.
+func (l *Loading) load(ctx context.Context, key []byte) (val interface{}, err error) {
+	if l.recoverLoader {
+		defer func() {
+			if r := recover(); r != nil {
+				val, err = nil, fmt.Errorf("%w: loader: %v", ErrCallbackPanic, r)
+			}
+		}()
+	}
+
+	return l.loader(ctx, key)
+}
+
+func (l *Loading) read(ctx context.Context, key []byte) (interface{}, error) {
+	// Soft expired value is still valid, it is not refreshed ahead.
+	v, err := softExpiredHit(l.backend.Read(ctx, key))
+	if err != nil {
+		return nil, err
+	}
+
+	if le, ok := v.(loadError); ok {
+		return nil, le.err
+	}
+
+	return v, nil
+}
+
+// writeError stores cacheable error, failure to store is ignored as loader error is returned anyway.
+func (l *Loading) writeError(ctx context.Context, key []byte, err error) {
+	if l.missTTL != 0 {
+		ctx = WithTTL(ctx, l.missTTL, false)
+	}
+
+	_ = l.backend.Write(ctx, key, loadError{err: err})
+}
+
+// Write sets value by the key.
+func (l *Loading) Write(ctx context.Context, key []byte, v interface{}) error {
+	return l.backend.Write(ctx, key, v)
+}
+
+// Delete removes value by the key, backend must implement Deleter.
+//
+// It fails with ErrNotFound if key does not exist.
+func (l *Loading) Delete(ctx context.Context, key []byte) error {
+	if d, ok := l.backend.(Deleter); ok {
+		return d.Delete(ctx, key)
+	}
+
+	return ErrNotFound
+}
@@ -0,0 +1,199 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cacheableErr string
+
+func (e cacheableErr) Error() string   { return string(e) }
+func (e cacheableErr) Cacheable() bool { return true }
+
+func TestNewLoading(t *testing.T) {
+	ctx := context.Background()
+
+	var calls int64
+
+	c := cache.NewLoading(func(ctx context.Context, key []byte) (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+
+		switch string(key) {
+		case "transient":
+			return nil, errors.New("failed")
+		case "absent":
+			return nil, cacheableErr("absent")
+		}
+
+		return "value of " + string(key), nil
+	})
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			v, err := c.Read(ctx, []byte("foo"))
+			assert.NoError(t, err)
+			assert.Equal(t, "value of foo", v)
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+
+	// Transient errors are not cached.
+	for i := 0; i < 2; i++ {
+		_, err := c.Read(ctx, []byte("transient"))
+		assert.EqualError(t, err, "failed")
+	}
+
+	assert.Equal(t, int64(3), atomic.LoadInt64(&calls))
+
+	// Cacheable errors are cached.
+	for i := 0; i < 2; i++ {
+		_, err := c.Read(ctx, []byte("absent"))
+		assert.Equal(t, cacheableErr("absent"), err)
+	}
+
+	assert.Equal(t, int64(4), atomic.LoadInt64(&calls))
+
+	require.NoError(t, c.Write(ctx, []byte("bar"), "manual"))
+
+	v, err := c.Read(ctx, []byte("bar"))
+	require.NoError(t, err)
+	assert.Equal(t, "manual", v)
+	assert.Equal(t, int64(4), atomic.LoadInt64(&calls))
+
+	require.NoError(t, c.Delete(ctx, []byte("bar")))
+
+	v, err = c.Read(ctx, []byte("bar"))
+	require.NoError(t, err)
+	assert.Equal(t, "value of bar", v)
+	assert.Equal(t, int64(5), atomic.LoadInt64(&calls))
+}
+
+func TestLoading_Read_keyPrefix(t *testing.T) {
+	ctx := context.Background()
+
+	started := sync.WaitGroup{}
+	started.Add(2)
+
+	c := cache.NewLoading(func(ctx context.Context, key []byte) (interface{}, error) {
+		// Both loads are in flight at the same time.
+		started.Done()
+		started.Wait()
+
+		return string(cache.KeyPrefix(ctx)) + string(key), nil
+	})
+
+	wg := sync.WaitGroup{}
+
+	for _, tenant := range []string{"a:", "b:"} {
+		wg.Add(1)
+
+		go func(tenant string) {
+			defer wg.Done()
+
+			v, err := c.Read(cache.WithKeyPrefix(ctx, []byte(tenant)), []byte("k"))
+			assert.NoError(t, err)
+			assert.Equal(t, tenant+"k", v)
+		}(tenant)
+	}
+
+	wg.Wait()
+
+	for _, tenant := range []string{"a:", "b:"} {
+		v, err := c.Read(ctx, []byte(tenant+"k"))
+		require.NoError(t, err)
+		assert.Equal(t, tenant+"k", v)
+	}
+}
+
+func TestLoading_Read_softExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	var calls int64
+
+	c := cache.NewLoading(func(ctx context.Context, key []byte) (interface{}, error) {
+		return atomic.AddInt64(&calls, 1), nil
+	}, func(cfg *cache.LoadingConfig) {
+		cfg.Config.Clock = clock
+		cfg.Config.TimeToLive = time.Minute
+		cfg.Config.ExpirationJitter = -1
+		cfg.Config.SoftTTLFraction = 0.5
+	})
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+
+	clock.Add(45 * time.Second)
+
+	v, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), v)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+}
+
+func TestNewLoading_maxConcurrentBuilds(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	c := cache.NewLoading(func(ctx context.Context, key []byte) (interface{}, error) {
+		if string(key) == "slow" {
+			close(started)
+			<-release
+		}
+
+		return string(key), nil
+	}, func(cfg *cache.LoadingConfig) {
+		cfg.MaxConcurrentBuilds = 1
+		cfg.RejectOnBuildLimit = true
+	})
+
+	go func() {
+		_, _ = c.Read(ctx, []byte("slow"))
+	}()
+
+	<-started
+
+	_, err := c.Read(ctx, []byte("fast"))
+	assert.ErrorIs(t, err, cache.ErrTooManyBuilds)
+
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		v, err := c.Read(ctx, []byte("fast"))
+
+		return err == nil && v == "fast"
+	}, time.Second, time.Millisecond)
+}
+
+func TestNewLoading_recoverCallbacks(t *testing.T) {
+	ctx := context.Background()
+
+	c := cache.NewLoading(func(ctx context.Context, key []byte) (interface{}, error) {
+		panic("loader failed")
+	}, func(cfg *cache.LoadingConfig) {
+		cfg.RecoverCallbacks = true
+	})
+
+	_, err := c.Read(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrCallbackPanic)
+	assert.Contains(t, err.Error(), "loader failed")
+}
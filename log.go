@@ -62,6 +62,11 @@ func (lt *logTrait) setup(l Logger) {
 		return
 	}
 
+	defer func() {
+		lt.logDebug = withLogFields(lt.logDebug)
+		lt.logImportant = withLogFields(lt.logImportant)
+	}()
+
 	if t, ok := l.(logTrait); ok {
 		*lt = t
 
@@ -82,3 +87,18 @@ func (lt *logTrait) setup(l Logger) {
 		lt.logImportant = i.Important
 	}
 }
+
+// withLogFields decorates logging function with fields of WithLogFields context.
+func withLogFields(f logFunc) logFunc {
+	if f == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, msg string, keysAndValues ...interface{}) {
+		if fields := LogFields(ctx); len(fields) > 0 {
+			keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)], fields...)
+		}
+
+		f(ctx, msg, keysAndValues...)
+	}
+}
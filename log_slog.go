@@ -0,0 +1,53 @@
+//go:build go1.21
+// +build go1.21
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// SlogLogger creates Logger that writes to log/slog logger.
+//
+// Important messages are logged with Info level, key-value pairs are translated to attributes.
+func SlogLogger(l *slog.Logger) Logger {
+	logAt := func(level slog.Level) logFunc {
+		return func(ctx context.Context, msg string, keysAndValues ...interface{}) {
+			if !l.Enabled(ctx, level) {
+				return
+			}
+
+			l.LogAttrs(ctx, level, msg, slogAttrs(keysAndValues)...)
+		}
+	}
+
+	return NewLogger(
+		logAt(slog.LevelError),
+		logAt(slog.LevelWarn),
+		logAt(slog.LevelInfo),
+		logAt(slog.LevelDebug),
+	)
+}
+
+func slogAttrs(keysAndValues []interface{}) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(keysAndValues)/2)
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+
+		if i+1 == len(keysAndValues) {
+			attrs = append(attrs, slog.Any("!BADKEY", key))
+
+			break
+		}
+
+		attrs = append(attrs, slog.Any(key, keysAndValues[i+1]))
+	}
+
+	return attrs
+}
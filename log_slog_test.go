@@ -0,0 +1,45 @@
+//go:build go1.21
+// +build go1.21
+
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/bool64/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	ctx := context.Background()
+	buf := bytes.NewBuffer(nil)
+
+	l := cache.SlogLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	})))
+
+	c := cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.Logger = l
+		cfg.Name = "test"
+	})
+
+	_, err := c.Read(ctx, []byte("foo"))
+	assert.Error(t, err)
+
+	c.ExpireAll(ctx)
+	l.Error(ctx, "failed", "error", "boom", "odd")
+
+	assert.Contains(t, buf.String(), `level=DEBUG msg="cache miss" name=test`)
+	assert.Contains(t, buf.String(), `level=INFO msg="expired all entries in cache" name=test elapsed=`)
+	assert.Contains(t, buf.String(), `level=ERROR msg=failed error=boom !BADKEY=odd`)
+}
@@ -2,6 +2,7 @@ package cache //nolint:testpackage // Testing internals.
 
 import (
 	"context"
+	"math/rand"
 	"testing"
 
 	"github.com/bool64/ctxd"
@@ -31,3 +32,49 @@ important: important {"foo":"bar"}
 debug: debug {"foo":"bar"}
 `, m.String())
 }
+
+func TestConfig_LogSampleRate(t *testing.T) {
+	ctx := context.Background()
+	debugs := 0
+	important := 0
+
+	c := NewShardedMap(Config{
+		Logger: NewLogger(nil, nil, func(ctx context.Context, msg string, keysAndValues ...interface{}) {
+			important++
+		}, func(ctx context.Context, msg string, keysAndValues ...interface{}) {
+			debugs++
+		}),
+		LogSampleRate: 0.1,
+		RandSource:    rand.NewSource(1),
+	}.Use)
+
+	for i := 0; i < 1000; i++ {
+		_, err := c.Read(ctx, []byte("foo"))
+		assert.Error(t, err)
+	}
+
+	c.ExpireAll(ctx)
+
+	assert.Greater(t, debugs, 50)
+	assert.Less(t, debugs, 150)
+	assert.Equal(t, 1, important)
+}
+
+func TestWithLogFields(t *testing.T) {
+	m := ctxd.LoggerMock{}
+	c := NewShardedMap(Config{Logger: &m, Name: "test"}.Use)
+
+	ctx := WithLogFields(context.Background(), "requestID", "abc")
+	ctx = WithLogFields(ctx, "traceID", "def")
+
+	assert.Equal(t, []interface{}{"requestID", "abc", "traceID", "def"}, LogFields(ctx))
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	c.ExpireAll(ctx)
+	assert.NoError(t, c.Write(context.Background(), []byte("baz"), "qux"))
+
+	assert.Contains(t, m.String(), `debug: wrote to cache {"key":"foo","name":"test","requestID":"abc","traceID":"def"`)
+	assert.Equal(t, "expired all entries in cache", m.LoggedEntries[1].Message)
+	assert.Equal(t, "def", m.LoggedEntries[1].Data["traceID"])
+	assert.Contains(t, m.String(), `debug: wrote to cache {"key":"baz","name":"test","ttl"`)
+}
@@ -0,0 +1,314 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"time"
+)
+
+// Middleware decorates cache backend with additional behavior.
+type Middleware func(next ReadWriter) ReadWriter
+
+// Chain applies middlewares to base backend, first middleware is the outermost.
+//
+// Deleter and Walker of wrapped backend are preserved, if a middleware result does not implement them,
+// calls are passed through to the wrapped backend. Middleware that transforms keys or values
+// should implement Deleter and Walker itself.
+func Chain(base ReadWriter, mw ...Middleware) ReadWriter {
+	rw := base
+
+	for i := len(mw) - 1; i >= 0; i-- {
+		rw = passthrough(mw[i](rw), rw)
+	}
+
+	return rw
+}
+
+type (
+	rwDeleter struct {
+		ReadWriter
+		Deleter
+	}
+
+	rwWalker struct {
+		ReadWriter
+		Walker
+	}
+
+	rwDeleterWalker struct {
+		ReadWriter
+		Deleter
+		Walker
+	}
+)
+
+// passthrough adds Deleter and Walker of next to rw if rw does not implement them.
+func passthrough(rw, next ReadWriter) ReadWriter {
+	d, isDeleter := rw.(Deleter)
+	if !isDeleter {
+		d, isDeleter = next.(Deleter)
+	}
+
+	w, isWalker := rw.(Walker)
+	if !isWalker {
+		w, isWalker = next.(Walker)
+	}
+
+	switch {
+	case isDeleter && isWalker:
+		return rwDeleterWalker{ReadWriter: rw, Deleter: d, Walker: w}
+	case isDeleter:
+		return rwDeleter{ReadWriter: rw, Deleter: d}
+	case isWalker:
+		return rwWalker{ReadWriter: rw, Walker: w}
+	}
+
+	return rw
+}
+
+// Trace creates middleware that reports operations to a tracer.
+//
+// Function start is called before Read, Write and Delete with operation name and key,
+// it returns context for the operation and a function to finish the span with resulting error.
+// Cache miss is reported as ErrNotFound or ErrExpired error.
+func Trace(start func(ctx context.Context, op string, key []byte) (context.Context, func(err error))) Middleware {
+	return func(next ReadWriter) ReadWriter {
+		return traced{next: next, start: start}
+	}
+}
+
+type traced struct {
+	next  ReadWriter
+	start func(ctx context.Context, op string, key []byte) (context.Context, func(err error))
+}
+
+func (t traced) Read(ctx context.Context, key []byte) (interface{}, error) {
+	ctx, finish := t.start(ctx, "Read", key)
+
+	v, err := t.next.Read(ctx, key)
+	finish(err)
+
+	return v, err
+}
+
+func (t traced) Write(ctx context.Context, key []byte, value interface{}) error {
+	ctx, finish := t.start(ctx, "Write", key)
+
+	err := t.next.Write(ctx, key, value)
+	finish(err)
+
+	return err
+}
+
+// Delete removes value by the key, wrapped backend must implement Deleter.
+//
+// It fails with ErrNotFound if key does not exist.
+func (t traced) Delete(ctx context.Context, key []byte) error {
+	ctx, finish := t.start(ctx, "Delete", key)
+
+	var err error = ErrNotFound
+	if d, ok := t.next.(Deleter); ok {
+		err = d.Delete(ctx, key)
+	}
+
+	finish(err)
+
+	return err
+}
+
+// DecorateContext creates middleware that prepares context of every operation.
+//
+// It can be used to apply context options, for example WithTTLMultiplier in a degraded mode
+// or WithKeyPrefix for a tenant, to all operations of a backend.
+func DecorateContext(decorate func(ctx context.Context) context.Context) Middleware {
+	return func(next ReadWriter) ReadWriter {
+		return decorated{next: next, decorate: decorate}
+	}
+}
+
+type decorated struct {
+	next     ReadWriter
+	decorate func(ctx context.Context) context.Context
+}
+
+func (d decorated) Read(ctx context.Context, key []byte) (interface{}, error) {
+	return d.next.Read(d.decorate(ctx), key)
+}
+
+func (d decorated) Write(ctx context.Context, key []byte, value interface{}) error {
+	return d.next.Write(d.decorate(ctx), key, value)
+}
+
+// Delete removes value by the key, wrapped backend must implement Deleter.
+//
+// It fails with ErrNotFound if key does not exist.
+func (d decorated) Delete(ctx context.Context, key []byte) error {
+	if del, ok := d.next.(Deleter); ok {
+		return del.Delete(d.decorate(ctx), key)
+	}
+
+	return ErrNotFound
+}
+
+// Timeout creates middleware that bounds duration of Read, Write and Delete, zero or negative timeout disables it.
+//
+// Operation is started in a goroutine with context that is canceled after timeout (or earlier with parent deadline),
+// so that backend that does not respect context does not block caller. Goroutine of timed out operation
+// exits once backend returns. Timed out Read is a miss, its error matches both ErrNotFound
+// and context.DeadlineExceeded, timed out Write and Delete fail with context error.
+func Timeout(d time.Duration) Middleware {
+	return func(next ReadWriter) ReadWriter {
+		if d <= 0 {
+			return next
+		}
+
+		return timeout{next: next, d: d}
+	}
+}
+
+type timeout struct {
+	next ReadWriter
+	d    time.Duration
+}
+
+type readResult struct {
+	v   interface{}
+	err error
+}
+
+func (t timeout) Read(ctx context.Context, key []byte) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.d)
+	defer cancel()
+
+	// Buffered channel allows goroutine to exit after timeout.
+	res := make(chan readResult, 1)
+
+	go func() {
+		v, err := t.next.Read(ctx, key)
+		res <- readResult{v: v, err: err}
+	}()
+
+	select {
+	case r := <-res:
+		return r.v, r.err
+	case <-ctx.Done():
+		return nil, timedOutRead{err: ctx.Err()}
+	}
+}
+
+func (t timeout) Write(ctx context.Context, key []byte, value interface{}) error {
+	return t.do(ctx, func(ctx context.Context) error {
+		return t.next.Write(ctx, key, value)
+	})
+}
+
+// Delete removes value by the key, wrapped backend must implement Deleter.
+//
+// It fails with ErrNotFound if key does not exist.
+func (t timeout) Delete(ctx context.Context, key []byte) error {
+	d, ok := t.next.(Deleter)
+	if !ok {
+		return ErrNotFound
+	}
+
+	return t.do(ctx, func(ctx context.Context) error {
+		return d.Delete(ctx, key)
+	})
+}
+
+func (t timeout) do(ctx context.Context, op func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, t.d)
+	defer cancel()
+
+	// Buffered channel allows goroutine to exit after timeout.
+	res := make(chan error, 1)
+
+	go func() {
+		res <- op(ctx)
+	}()
+
+	select {
+	case err := <-res:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// timedOutRead is a cache miss caused by timeout.
+type timedOutRead struct {
+	err error
+}
+
+func (e timedOutRead) Error() string {
+	return "cache read timed out: " + e.err.Error()
+}
+
+func (e timedOutRead) Unwrap() error {
+	return e.err
+}
+
+func (e timedOutRead) Is(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// codec is a middleware that transforms values on Write and restores them on Read.
+type codec struct {
+	next   ReadWriter
+	encode func(v interface{}) (interface{}, error)
+	decode func(v interface{}) (interface{}, error)
+}
+
+func (c codec) Read(ctx context.Context, key []byte) (interface{}, error) {
+	v, err := c.next.Read(ctx, key)
+	if err != nil {
+		var errExp ErrWithExpiredItem
+
+		// Stale value is decoded to be usable by caller.
+		if errors.As(err, &errExp) {
+			if v, decErr := c.decode(errExp.Value()); decErr == nil {
+				if errors.Is(err, ErrSoftExpired) {
+					return nil, errSoftExpired{entry: &TraitEntry{V: v}, at: ts(errExp.ExpiredAt())}
+				}
+
+				return nil, errExpired{entry: &TraitEntry{V: v, E: ts(errExp.ExpiredAt())}}
+			}
+		}
+
+		return nil, err
+	}
+
+	return c.decode(v)
+}
+
+func (c codec) Write(ctx context.Context, key []byte, value interface{}) error {
+	v, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+
+	return c.next.Write(ctx, key, v)
+}
+
+// gobEncodeValue encodes value with its type, so that it can be decoded into interface.
+func gobEncodeValue(v interface{}) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+
+	if err := gob.NewEncoder(buf).Encode(&v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func gobDecodeValue(b []byte) (interface{}, error) {
+	var v interface{}
+
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
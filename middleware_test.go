@@ -0,0 +1,151 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type upperValues struct {
+	cache.ReadWriter
+}
+
+func (u upperValues) Write(ctx context.Context, key []byte, value interface{}) error {
+	return u.ReadWriter.Write(ctx, key, value.(string)+"!")
+}
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	base := cache.NewShardedMap()
+
+	var ops []string
+
+	c := cache.Chain(base,
+		cache.Trace(func(ctx context.Context, op string, key []byte) (context.Context, func(err error)) {
+			return ctx, func(err error) {
+				ops = append(ops, op+" "+string(key)+" "+errString(err))
+			}
+		}),
+		cache.DecorateContext(func(ctx context.Context) context.Context {
+			return cache.WithKeyPrefix(ctx, []byte("t1:"))
+		}),
+		func(next cache.ReadWriter) cache.ReadWriter {
+			return upperValues{ReadWriter: next}
+		},
+	)
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+
+	v, err := base.Read(ctx, []byte("t1:foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar!", v)
+
+	v, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar!", v)
+
+	_, err = c.Read(ctx, []byte("baz"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+
+	d, ok := c.(cache.Deleter)
+	require.True(t, ok)
+	require.NoError(t, d.Delete(ctx, []byte("foo")))
+	assert.Equal(t, 0, base.Len())
+
+	assert.Equal(t, []string{
+		"Write foo <nil>",
+		"Read foo <nil>",
+		"Read baz missing cache item",
+		"Delete foo <nil>",
+	}, ops)
+}
+
+func TestChain_passthrough(t *testing.T) {
+	ctx := context.Background()
+	base := cache.NewSyncMap()
+
+	c := cache.Chain(base, func(next cache.ReadWriter) cache.ReadWriter {
+		return upperValues{ReadWriter: next}
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+
+	w, ok := c.(cache.Walker)
+	require.True(t, ok)
+
+	n, err := w.Walk(func(e cache.Entry) error {
+		assert.Equal(t, "bar!", e.Value())
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	d, ok := c.(cache.Deleter)
+	require.True(t, ok)
+	require.NoError(t, d.Delete(ctx, []byte("foo")))
+	assert.Equal(t, 0, base.Len())
+
+	c = cache.Chain(cache.NewLoading(nil), func(next cache.ReadWriter) cache.ReadWriter {
+		return upperValues{ReadWriter: next}
+	})
+
+	_, ok = c.(cache.Walker)
+	assert.False(t, ok)
+
+	_, ok = c.(cache.Deleter)
+	assert.True(t, ok)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+
+	return err.Error()
+}
+
+type hungBackend struct {
+	cache.ReadWriter
+	release chan struct{}
+}
+
+func (h hungBackend) Read(ctx context.Context, key []byte) (interface{}, error) {
+	<-h.release
+
+	return h.ReadWriter.Read(ctx, key)
+}
+
+func (h hungBackend) Write(ctx context.Context, key []byte, value interface{}) error {
+	<-h.release
+
+	return h.ReadWriter.Write(ctx, key, value)
+}
+
+func TestTimeout(t *testing.T) {
+	ctx := context.Background()
+	base := cache.NewShardedMap()
+	release := make(chan struct{})
+
+	c := cache.Chain(hungBackend{ReadWriter: base, release: release}, cache.Timeout(10*time.Millisecond))
+
+	_, err := c.Read(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.ErrorIs(t, c.Write(ctx, []byte("foo"), "bar"), context.DeadlineExceeded)
+
+	close(release)
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	assert.Equal(t, base, cache.Timeout(0)(base))
+}
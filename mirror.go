@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+)
+
+// MirrorConfig is optional configuration for NewMirror.
+type MirrorConfig struct {
+	// Name is added to logs.
+	Name string
+
+	// ReadFromSecondary makes reads served by secondary instead of primary.
+	// It can be changed later with Mirror.SetReadFromSecondary.
+	ReadFromSecondary bool
+
+	// CompareReads enables reading both caches and logging divergence of values.
+	CompareReads bool
+
+	// FailOnSecondaryError makes Write and Delete fail with errors of secondary,
+	// by default such errors are logged and ignored.
+	FailOnSecondaryError bool
+
+	// Logger collects messages with context.
+	Logger Logger
+}
+
+// Use is a functional option for NewMirror to apply configuration.
+func (mc MirrorConfig) Use(cfg *MirrorConfig) {
+	*cfg = mc
+}
+
+var (
+	_ ReadWriter = &Mirror{}
+	_ Deleter    = &Mirror{}
+)
+
+// Mirror is a cache that duplicates writes to a secondary cache, for example during backend migration.
+//
+// Please use NewMirror to create instance.
+type Mirror struct {
+	primary, secondary ReadWriter
+	config             MirrorConfig
+
+	readFromSecondary int32
+
+	logTrait
+}
+
+// NewMirror creates a mirroring cache instance.
+//
+// Read is served by primary (or by secondary if ReadFromSecondary is enabled).
+// Write and Delete are applied to both caches, primary first.
+func NewMirror(primary, secondary ReadWriter, options ...func(cfg *MirrorConfig)) *Mirror {
+	cfg := MirrorConfig{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	m := &Mirror{
+		primary:   primary,
+		secondary: secondary,
+		config:    cfg,
+	}
+
+	m.SetReadFromSecondary(cfg.ReadFromSecondary)
+	m.logTrait.setup(cfg.Logger)
+
+	return m
+}
+
+// SetReadFromSecondary switches source of reads, it is safe for concurrent use.
+func (m *Mirror) SetReadFromSecondary(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+
+	atomic.StoreInt32(&m.readFromSecondary, v)
+}
+
+// Read gets value from the read source.
+func (m *Mirror) Read(ctx context.Context, key []byte) (interface{}, error) {
+	source, other := m.primary, m.secondary
+	if atomic.LoadInt32(&m.readFromSecondary) == 1 {
+		source, other = m.secondary, m.primary
+	}
+
+	v, err := softExpiredHit(source.Read(ctx, key))
+
+	if m.config.CompareReads {
+		ov, oErr := softExpiredHit(other.Read(ctx, key))
+		m.compare(ctx, key, v, err, ov, oErr)
+	}
+
+	return v, err
+}
+
+// compare logs divergence of read results.
+func (m *Mirror) compare(ctx context.Context, key []byte, v interface{}, err error, ov interface{}, oErr error) {
+	if m.logWarn == nil {
+		return
+	}
+
+	found, otherFound := err == nil, oErr == nil
+
+	switch {
+	case found && otherFound:
+		if reflect.DeepEqual(v, ov) {
+			return
+		}
+	case !found && !otherFound:
+		if errors.Is(err, ErrNotFound) == errors.Is(oErr, ErrNotFound) {
+			return
+		}
+	}
+
+	m.logWarn(ctx, "mirrored cache read diverged",
+		"key", key,
+		"name", m.config.Name,
+		"error", err,
+		"otherError", oErr,
+	)
+}
+
+// Write sets value to primary and secondary.
+func (m *Mirror) Write(ctx context.Context, key []byte, v interface{}) error {
+	if err := m.primary.Write(ctx, key, v); err != nil {
+		return err
+	}
+
+	return m.secondaryResult(ctx, "failed to write mirrored cache", key, m.secondary.Write(ctx, key, v))
+}
+
+// Delete removes value from primary and secondary.
+//
+// It fails with ErrNotFound if key does not exist in primary.
+func (m *Mirror) Delete(ctx context.Context, key []byte) error {
+	var err error
+
+	if d, ok := m.primary.(Deleter); ok {
+		err = d.Delete(ctx, key)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+
+	if d, ok := m.secondary.(Deleter); ok {
+		if sErr := d.Delete(ctx, key); !errors.Is(sErr, ErrNotFound) {
+			if sErr = m.secondaryResult(ctx, "failed to delete from mirrored cache", key, sErr); sErr != nil {
+				return sErr
+			}
+		}
+	}
+
+	return err
+}
+
+// secondaryResult logs error of secondary and returns it if FailOnSecondaryError is enabled.
+func (m *Mirror) secondaryResult(ctx context.Context, msg string, key []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if m.config.FailOnSecondaryError {
+		return err
+	}
+
+	if m.logError != nil {
+		m.logError(ctx, msg,
+			"error", err,
+			"key", key,
+			"name", m.config.Name)
+	}
+
+	return nil
+}
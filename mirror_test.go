@@ -0,0 +1,105 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/ctxd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirror(t *testing.T) {
+	ctx := context.Background()
+	logger := ctxd.LoggerMock{}
+	primary := cache.NewShardedMap()
+	secondary := cache.NewShardedMap()
+
+	c := cache.NewMirror(primary, secondary, func(cfg *cache.MirrorConfig) {
+		cfg.Name = "mirror"
+		cfg.Logger = &logger
+		cfg.CompareReads = true
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	assert.Equal(t, 1, primary.Len())
+	assert.Equal(t, 1, secondary.Len())
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+	assert.Empty(t, logger.String())
+
+	require.NoError(t, secondary.Write(ctx, []byte("foo"), "baz"))
+
+	v, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+	assert.Contains(t, logger.String(), "mirrored cache read diverged")
+
+	c.SetReadFromSecondary(true)
+
+	v, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "baz", v)
+
+	require.NoError(t, c.Delete(ctx, []byte("foo")))
+	assert.Equal(t, 0, primary.Len())
+	assert.Equal(t, 0, secondary.Len())
+	assert.True(t, errors.Is(c.Delete(ctx, []byte("foo")), cache.ErrNotFound))
+}
+
+func TestMirror_Read_softExpired(t *testing.T) {
+	ctx := context.Background()
+	logger := ctxd.LoggerMock{}
+	clock := newFakeClock()
+
+	primary := cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+		cfg.SoftTTLFraction = 0.5
+	})
+	secondary := cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+	})
+
+	c := cache.NewMirror(primary, secondary, func(cfg *cache.MirrorConfig) {
+		cfg.Logger = &logger
+		cfg.CompareReads = true
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	clock.Add(45 * time.Second)
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+	assert.Empty(t, logger.String())
+}
+
+func TestMirror_secondaryError(t *testing.T) {
+	ctx := context.Background()
+	logger := ctxd.LoggerMock{}
+	primary := cache.NewShardedMap()
+	secondary := cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.MaxValueSize = 1
+		cfg.Sizer = func(v interface{}) int { return len(v.(string)) } //nolint // Panic on type assertion failure is fine here.
+	})
+
+	c := cache.NewMirror(primary, secondary, cache.MirrorConfig{Logger: &logger}.Use)
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	assert.Equal(t, 1, primary.Len())
+	assert.Equal(t, 0, secondary.Len())
+	assert.Contains(t, logger.String(), "failed to write mirrored cache")
+
+	c = cache.NewMirror(primary, secondary, cache.MirrorConfig{FailOnSecondaryError: true}.Use)
+
+	assert.ErrorIs(t, c.Write(ctx, []byte("foo"), "bar"), cache.ErrValueTooLarge)
+}
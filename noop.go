@@ -2,12 +2,19 @@ package cache
 
 import (
 	"context"
+	"io"
 )
 
 // NoOp is a ReadWriter stub.
+//
+// It can be used to disable caching without changing call sites.
 type NoOp struct{}
 
-var _ ReadWriter = NoOp{}
+var (
+	_ ReadWriter       = NoOp{}
+	_ Deleter          = NoOp{}
+	_ WalkDumpRestorer = NoOp{}
+)
 
 // Write does nothing.
 func (NoOp) Write(_ context.Context, _ []byte, _ interface{}) error {
@@ -23,3 +30,32 @@ func (NoOp) Read(_ context.Context, _ []byte) (interface{}, error) {
 func (NoOp) Delete(_ context.Context, _ []byte) error {
 	return ErrNotFound
 }
+
+// Walk does nothing.
+func (NoOp) Walk(_ func(entry Entry) error) (int, error) {
+	return 0, nil
+}
+
+// Dump does nothing.
+func (NoOp) Dump(_ io.Writer) (int, error) {
+	return 0, nil
+}
+
+// Restore discards cache entries and returns number of processed entries.
+func (NoOp) Restore(r io.Reader) (int, error) {
+	n := 0
+
+	err := decodeDump(r, nil, func(decoder entryDecoder) error {
+		var e TraitEntry
+
+		if err := decoder.Decode(&e); err != nil {
+			return err
+		}
+
+		n++
+
+		return nil
+	})
+
+	return n, err
+}
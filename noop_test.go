@@ -1,6 +1,7 @@
 package cache_test
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
@@ -27,3 +28,28 @@ func TestNoOp_Delete(t *testing.T) {
 	err := cache.NoOp{}.Delete(context.Background(), []byte("foo"))
 	assert.EqualError(t, err, cache.ErrNotFound.Error())
 }
+
+func TestNoOp_Restore(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewShardedMap()
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	assert.NoError(t, c.Write(ctx, []byte("bar"), 2))
+
+	w := bytes.NewBuffer(nil)
+
+	_, err := c.Dump(w)
+	assert.NoError(t, err)
+
+	n, err := cache.NoOp{}.Restore(w)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = cache.NoOp{}.Dump(w)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = cache.NoOp{}.Walk(func(entry cache.Entry) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
@@ -0,0 +1,124 @@
+//go:build go1.18
+// +build go1.18
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var _ ReadWriterOf[any] = Of[any]{}
+
+// Of is a typed adapter of non-generic ReadWriter.
+//
+// Please use NewOf to create it.
+type Of[V any] struct {
+	backend ReadWriter
+}
+
+// NewOf creates a typed adapter of ReadWriter.
+func NewOf[V any](rw ReadWriter) Of[V] {
+	return Of[V]{backend: rw}
+}
+
+// Read gets value and asserts its type.
+//
+// Expired value is available with ErrWithExpiredItemOf[V] error.
+func (c Of[V]) Read(ctx context.Context, key []byte) (V, error) {
+	v, err := c.backend.Read(ctx, key)
+	if err != nil {
+		var (
+			val V
+			ee  ErrWithExpiredItem
+		)
+
+		if errors.As(err, &ee) {
+			if tv, ok := ee.Value().(V); ok {
+				return val, errExpiredValueOf[V]{ErrWithExpiredItem: ee, value: tv}
+			}
+		}
+
+		return val, err
+	}
+
+	return c.assert(v)
+}
+
+// Write sets value by the key.
+func (c Of[V]) Write(ctx context.Context, key []byte, value V) error {
+	return c.backend.Write(ctx, key, value)
+}
+
+// ReadOrWrite reads value by the key or builds and writes it on cache miss.
+//
+// If backend implements ReadOrWrite (for example SyncMap), concurrent builds of the same key
+// are deduplicated, otherwise build is invoked for every miss.
+func (c Of[V]) ReadOrWrite(ctx context.Context, key []byte, build func(ctx context.Context) (V, error)) (V, error) {
+	if rw, ok := c.backend.(readOrWriter); ok {
+		v, err := rw.ReadOrWrite(ctx, key, func(ctx context.Context) (interface{}, error) {
+			return build(ctx)
+		})
+		if err != nil {
+			var val V
+
+			return val, err
+		}
+
+		return c.assert(v)
+	}
+
+	val, err := c.Read(ctx, key)
+
+	// Soft expired value is still valid, it is not rebuilt ahead.
+	if errors.Is(err, ErrSoftExpired) {
+		val, _, _ = StaleValueOf[V](err)
+
+		return val, nil
+	}
+
+	if err == nil || (!errors.Is(err, ErrNotFound) && !errors.Is(err, ErrExpired)) {
+		return val, err
+	}
+
+	if val, err = build(ctx); err != nil {
+		return val, err
+	}
+
+	return val, c.backend.Write(ctx, key, val)
+}
+
+func (c Of[V]) assert(v interface{}) (V, error) {
+	var val V
+
+	if v == nil {
+		return val, nil
+	}
+
+	val, ok := v.(V)
+	if !ok {
+		return val, fmt.Errorf("%w: %T received, %T expected", ErrUnexpectedType, v, val)
+	}
+
+	return val, nil
+}
+
+type readOrWriter interface {
+	ReadOrWrite(ctx context.Context, key []byte, build func(ctx context.Context) (interface{}, error)) (interface{}, error)
+}
+
+var _ ErrWithExpiredItemOf[any] = errExpiredValueOf[any]{}
+
+type errExpiredValueOf[V any] struct {
+	ErrWithExpiredItem
+	value V
+}
+
+func (e errExpiredValueOf[V]) Value() V {
+	return e.value
+}
+
+func (e errExpiredValueOf[V]) Unwrap() error {
+	return e.ErrWithExpiredItem
+}
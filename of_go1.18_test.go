@@ -0,0 +1,77 @@
+//go:build go1.18
+// +build go1.18
+
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOf(t *testing.T) {
+	ctx := context.Background()
+	m := cache.NewSyncMap()
+	c := cache.NewOf[string](m)
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+
+	v, err := c.Read(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	_, err = c.Read(ctx, []byte("baz"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+	assert.NoError(t, m.Write(ctx, []byte("baz"), 123))
+	_, err = c.Read(ctx, []byte("baz"))
+	assert.True(t, errors.Is(err, cache.ErrUnexpectedType))
+
+	m.ExpireAll(ctx)
+
+	_, err = c.Read(ctx, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+
+	var ee cache.ErrWithExpiredItemOf[string]
+
+	assert.True(t, errors.As(err, &ee))
+	assert.Equal(t, "bar", ee.Value())
+
+	v, err = c.ReadOrWrite(ctx, []byte("foo"), func(ctx context.Context) (string, error) {
+		return "qux", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "qux", v)
+
+	v, err = cache.NewOf[string](cache.NewShardedMap()).ReadOrWrite(ctx, []byte("foo"),
+		func(ctx context.Context) (string, error) {
+			return "quux", nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, "quux", v)
+}
+
+func TestOf_ReadOrWrite_softExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	c := cache.NewOf[string](cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+		cfg.SoftTTLFraction = 0.5
+	}))
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	clock.Add(45 * time.Second)
+
+	v, err := c.ReadOrWrite(ctx, []byte("foo"), func(ctx context.Context) (string, error) {
+		return "baz", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+}
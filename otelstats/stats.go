@@ -0,0 +1,117 @@
+// Package otelstats provides OpenTelemetry implementation of cache.StatsTracker.
+package otelstats
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bool64/cache"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var _ cache.StatsTracker = &Stats{}
+
+// Stats tracks cache metrics with OpenTelemetry instruments.
+//
+// Metrics collected with Add (e.g. cache.MetricHit, cache.MetricEvict) are mapped to Float64Counter.
+// Metrics collected with Set (e.g. cache.MetricItems) are mapped to Float64ObservableGauge that reports
+// last value for every set of attributes.
+//
+// Label pairs are translated to string attributes. Instruments are created on first use.
+type Stats struct {
+	meter metric.Meter
+
+	mu       sync.Mutex
+	counters map[string]metric.Float64Counter
+	gauges   map[string]*gauge
+}
+
+type gauge struct {
+	mu     sync.Mutex
+	values map[attribute.Distinct]gaugeValue
+}
+
+type gaugeValue struct {
+	attrs attribute.Set
+	value float64
+}
+
+// NewOTelStats creates cache stats tracker with a meter.
+func NewOTelStats(meter metric.Meter) *Stats {
+	return &Stats{
+		meter:    meter,
+		counters: make(map[string]metric.Float64Counter),
+		gauges:   make(map[string]*gauge),
+	}
+}
+
+// Add increments counter.
+func (s *Stats) Add(ctx context.Context, name string, increment float64, labelsAndValues ...string) {
+	s.mu.Lock()
+
+	c, ok := s.counters[name]
+	if !ok {
+		var err error
+
+		// Meter returns usable no-op instrument in case of an error.
+		c, err = s.meter.Float64Counter(name)
+		if err != nil {
+			otel.Handle(err)
+		}
+
+		s.counters[name] = c
+	}
+
+	s.mu.Unlock()
+
+	c.Add(ctx, increment, metric.WithAttributeSet(attributes(labelsAndValues)))
+}
+
+// Set sets gauge value, it is reported on collection.
+func (s *Stats) Set(_ context.Context, name string, absolute float64, labelsAndValues ...string) {
+	s.mu.Lock()
+
+	g, ok := s.gauges[name]
+	if !ok {
+		g = &gauge{values: make(map[attribute.Distinct]gaugeValue)}
+
+		_, err := s.meter.Float64ObservableGauge(name, metric.WithFloat64Callback(g.observe))
+		if err != nil {
+			otel.Handle(err)
+		}
+
+		s.gauges[name] = g
+	}
+
+	s.mu.Unlock()
+
+	attrs := attributes(labelsAndValues)
+
+	g.mu.Lock()
+	g.values[attrs.Equivalent()] = gaugeValue{attrs: attrs, value: absolute}
+	g.mu.Unlock()
+}
+
+func (g *gauge) observe(_ context.Context, o metric.Float64Observer) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, v := range g.values {
+		o.Observe(v.value, metric.WithAttributeSet(v.attrs))
+	}
+
+	return nil
+}
+
+// attributes translates label pairs to attributes.
+func attributes(labelsAndValues []string) attribute.Set {
+	kv := make([]attribute.KeyValue, 0, len(labelsAndValues)/2)
+
+	for i := 0; i+1 < len(labelsAndValues); i += 2 {
+		kv = append(kv, attribute.String(labelsAndValues[i], labelsAndValues[i+1]))
+	}
+
+	return attribute.NewSet(kv...)
+}
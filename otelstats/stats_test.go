@@ -0,0 +1,58 @@
+package otelstats_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/cache/otelstats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestNewOTelStats(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	st := otelstats.NewOTelStats(provider.Meter("cache"))
+
+	c := cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.Name = "test"
+		cfg.Stats = st
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	require.NoError(t, c.Write(ctx, []byte("bar"), 2))
+	st.Set(ctx, cache.MetricItems, 5, "name", "test")
+	st.Set(ctx, cache.MetricItems, 2, "name", "test")
+
+	rm := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	values := map[string]float64{}
+
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		switch d := m.Data.(type) {
+		case metricdata.Sum[float64]:
+			require.Len(t, d.DataPoints, 1)
+			assert.Equal(t, attribute.NewSet(attribute.String("name", "test")), d.DataPoints[0].Attributes)
+			assert.True(t, d.IsMonotonic)
+
+			values[m.Name] = d.DataPoints[0].Value
+		case metricdata.Gauge[float64]:
+			require.Len(t, d.DataPoints, 1)
+
+			values[m.Name] = d.DataPoints[0].Value
+		}
+	}
+
+	assert.Equal(t, map[string]float64{
+		cache.MetricWrite: 2,
+		cache.MetricItems: 2,
+	}, values)
+}
@@ -0,0 +1,137 @@
+// Package promstats provides Prometheus implementation of cache.StatsTracker.
+package promstats
+
+import (
+	"context"
+
+	"github.com/bool64/cache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ cache.StatsTracker = &Stats{}
+
+type metric struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// counters are cache metrics that are collected with Add, "_total" suffix is added to metric name.
+var counters = []metric{
+	{name: cache.MetricHit, help: "Number of valid cache reads.", labels: []string{"name", "tier", "group"}},
+	{name: cache.MetricMiss, help: "Number of cache misses.", labels: []string{"name", "tier", "group"}},
+	{name: cache.MetricExpired, help: "Number of expired cache reads.", labels: []string{"name"}},
+	{name: cache.MetricWrite, help: "Number of cache writes.", labels: []string{"name"}},
+	{name: cache.MetricDelete, help: "Number of deleted cache entries.", labels: []string{"name"}},
+	{name: cache.MetricRejected, help: "Number of rejected cache writes.", labels: []string{"name"}},
+	{name: cache.MetricRefreshed, help: "Number of stale value refreshes.", labels: []string{"name"}},
+	{name: cache.MetricBuild, help: "Number of value builds.", labels: []string{"name"}},
+	{name: cache.MetricFailed, help: "Number of failed value builds.", labels: []string{"name"}},
+	{name: cache.MetricSingleFlightDedup, help: "Number of reads that waited for an in-flight build.", labels: []string{"name"}},
+	{name: cache.MetricWriteBehindFlushed, help: "Number of persisted write-behind writes.", labels: []string{"name"}},
+	{name: cache.MetricWriteBehindFailed, help: "Number of lost write-behind writes.", labels: []string{"name"}},
+	{name: cache.MetricChanged, help: "Number of value builds that changed cached value.", labels: []string{"name"}},
+	{name: cache.MetricEvict, help: "Number of evicted cache entries.", labels: []string{"name", "trigger"}},
+	{name: cache.MetricEvictHeap, help: "Number of cache entries evicted due to heap usage.", labels: []string{"name"}},
+	{name: cache.MetricEvictionElapsedSeconds, help: "Time spent on eviction.", labels: []string{"name"}},
+}
+
+// gauges are cache metrics that are collected with Set.
+var gauges = []metric{
+	{name: cache.MetricItems, help: "Number of entries in cache.", labels: []string{"name"}},
+	{name: cache.MetricHitRatio, help: "Ratio of valid cache reads in a rolling window.", labels: []string{"name"}},
+	{name: cache.MetricAvgTTL, help: "Average time to live of sampled entries.", labels: []string{"name"}},
+	{name: cache.MetricTTLRemaining, help: "Quantiles of remaining time to expiration of sampled entries.", labels: []string{"name", "quantile"}},
+}
+
+// Stats tracks cache metrics with Prometheus counters and gauges.
+//
+// Labels that are not defined for a metric are ignored, missing labels have empty values.
+// Unknown metrics are ignored.
+type Stats struct {
+	counters map[string]*vec
+	gauges   map[string]*vec
+}
+
+type vec struct {
+	labels  []string
+	counter *prometheus.CounterVec
+	gauge   *prometheus.GaugeVec
+}
+
+// values picks values of metric labels from label pairs.
+func (v *vec) values(labelsAndValues []string) []string {
+	values := make([]string, len(v.labels))
+
+	for i := 0; i+1 < len(labelsAndValues); i += 2 {
+		for j, l := range v.labels {
+			if l == labelsAndValues[i] {
+				values[j] = labelsAndValues[i+1]
+
+				break
+			}
+		}
+	}
+
+	return values
+}
+
+// NewPrometheusStats creates and registers cache metrics in a registerer.
+//
+// Namespace is used as a prefix of metric names, it can be empty.
+func NewPrometheusStats(registerer prometheus.Registerer, namespace string) (*Stats, error) {
+	s := &Stats{
+		counters: make(map[string]*vec, len(counters)),
+		gauges:   make(map[string]*vec, len(gauges)),
+	}
+
+	for _, m := range counters {
+		v := &vec{
+			labels: m.labels,
+			counter: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      m.name + "_total",
+				Help:      m.help,
+			}, m.labels),
+		}
+
+		if err := registerer.Register(v.counter); err != nil {
+			return nil, err
+		}
+
+		s.counters[m.name] = v
+	}
+
+	for _, m := range gauges {
+		v := &vec{
+			labels: m.labels,
+			gauge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      m.name,
+				Help:      m.help,
+			}, m.labels),
+		}
+
+		if err := registerer.Register(v.gauge); err != nil {
+			return nil, err
+		}
+
+		s.gauges[m.name] = v
+	}
+
+	return s, nil
+}
+
+// Add increments counter.
+func (s *Stats) Add(_ context.Context, name string, increment float64, labelsAndValues ...string) {
+	if v, ok := s.counters[name]; ok {
+		v.counter.WithLabelValues(v.values(labelsAndValues)...).Add(increment)
+	}
+}
+
+// Set sets gauge value.
+func (s *Stats) Set(_ context.Context, name string, absolute float64, labelsAndValues ...string) {
+	if v, ok := s.gauges[name]; ok {
+		v.gauge.WithLabelValues(v.values(labelsAndValues)...).Set(absolute)
+	}
+}
@@ -0,0 +1,58 @@
+package promstats_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/cache/promstats"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPrometheusStats(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+
+	st, err := promstats.NewPrometheusStats(reg, "app")
+	require.NoError(t, err)
+
+	_, err = promstats.NewPrometheusStats(reg, "app")
+	assert.Error(t, err, "duplicate registration")
+
+	c := cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.Name = "test"
+		cfg.Stats = st
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	_, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	_, err = c.Read(ctx, []byte("bar"))
+	require.Error(t, err)
+
+	st.Add(ctx, cache.MetricEvict, 3, "name", "test", "trigger", cache.EvictTriggerCount, "unknown", "label")
+	st.Add(ctx, "unknown_metric", 1)
+	st.Set(ctx, cache.MetricItems, 5, "name", "test")
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP app_cache_evict_total Number of evicted cache entries.
+# TYPE app_cache_evict_total counter
+app_cache_evict_total{name="test",trigger="count"} 3
+# HELP app_cache_hit_total Number of valid cache reads.
+# TYPE app_cache_hit_total counter
+app_cache_hit_total{group="",name="test",tier=""} 1
+# HELP app_cache_items Number of entries in cache.
+# TYPE app_cache_items gauge
+app_cache_items{name="test"} 5
+# HELP app_cache_miss_total Number of cache misses.
+# TYPE app_cache_miss_total counter
+app_cache_miss_total{group="",name="test",tier=""} 1
+# HELP app_cache_write_total Number of cache writes.
+# TYPE app_cache_write_total counter
+app_cache_write_total{name="test"} 1
+`), "app_cache_evict_total", "app_cache_hit_total", "app_cache_items", "app_cache_miss_total", "app_cache_write_total"))
+}
@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"time"
+)
+
+// BytesStore is a storage of binary values with expiration, for example Redis.
+//
+// It can be implemented with a thin adapter of a client library.
+type BytesStore interface {
+	// Get returns stored value or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores value with time to live, zero ttl disables expiration.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Del removes value and returns ErrNotFound for non-existent keys.
+	Del(ctx context.Context, key string) error
+
+	// Scan calls function for every stored key with a prefix.
+	Scan(ctx context.Context, prefix string, fn func(key string) error) error
+}
+
+// RemoteConfig controls remote cache instance.
+type RemoteConfig struct {
+	// Config controls expiration, logging and stats.
+	Config
+
+	// KeyPrefix is prepended to every key in BytesStore, e.g. "cache:users:".
+	KeyPrefix string
+}
+
+var (
+	_ ReadWriter = &Remote{}
+	_ Deleter    = &Remote{}
+)
+
+// Remote is a cache backend that stores gob-encoded entries in BytesStore.
+//
+// Please use NewRemote to create it.
+type Remote struct {
+	store     BytesStore
+	keyPrefix string
+
+	t *Trait
+}
+
+// NewRemote creates an instance of cache backed by BytesStore.
+//
+// Values are serialized with encoding/gob, therefore it is necessary to
+// register cached types in advance with GobRegister.
+//
+// Entries are stored with time to live extended by DeleteExpiredAfter (or GracePeriod of write context), so that
+// expired values are available as stale.
+func NewRemote(store BytesStore, options ...func(cfg *RemoteConfig)) *Remote {
+	cfg := RemoteConfig{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	return &Remote{
+		store:     store,
+		keyPrefix: cfg.KeyPrefix,
+		t:         NewTrait(cfg.Config),
+	}
+}
+
+// Read gets value.
+func (c *Remote) Read(ctx context.Context, key []byte) (interface{}, error) {
+	if SkipRead(ctx) {
+		return nil, ErrNotFound
+	}
+
+	key = c.t.key(ctx, key)
+
+	b, err := c.store.Get(ctx, c.keyPrefix+string(key))
+	if err != nil {
+		if err == ErrNotFound { //nolint:errorlint // Sentinel error is expected as is.
+			return c.t.prepareRead(ctx, key, nil, false)
+		}
+
+		return nil, err
+	}
+
+	var e TraitEntry
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&e); err != nil {
+		return nil, err
+	}
+
+	return c.t.PrepareRead(ctx, &e, true)
+}
+
+// Write sets value by the key.
+func (c *Remote) Write(ctx context.Context, key []byte, v interface{}) error {
+	if c.t.skipWrite(ctx, key) {
+		return nil
+	}
+
+	key, err := c.t.writeKey(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if err := c.t.prepareWrite(ctx, key, v); err != nil {
+		return err
+	}
+
+	ttl, expireAt := c.t.expireAt(ctx)
+
+	if err := c.set(ctx, TraitEntry{
+		K: key, V: v, E: expireAt,
+		G: int64(GracePeriod(ctx)), N: c.t.Config.Generation, T: ts(c.t.now()),
+	}); err != nil {
+		return err
+	}
+
+	c.t.NotifyWritten(ctx, key, v, ttl)
+
+	return nil
+}
+
+func (c *Remote) set(ctx context.Context, e TraitEntry) error {
+	buf := bytes.NewBuffer(nil)
+
+	if err := gob.NewEncoder(buf).Encode(e); err != nil {
+		return err
+	}
+
+	storeTTL := time.Duration(0)
+	if e.E != 0 {
+		grace := c.t.Config.DeleteExpiredAfter
+		if e.G != 0 {
+			grace = time.Duration(e.G)
+		}
+
+		storeTTL = tsTime(e.E).Sub(c.t.now()) + grace
+	}
+
+	return c.store.Set(ctx, c.keyPrefix+string(e.K), buf.Bytes(), storeTTL)
+}
+
+// Delete removes value by the key.
+//
+// It fails with ErrNotFound if key does not exist.
+func (c *Remote) Delete(ctx context.Context, key []byte) error {
+	key = c.t.key(ctx, key)
+
+	if err := c.store.Del(ctx, c.keyPrefix+string(key)); err != nil {
+		return err
+	}
+
+	c.t.NotifyDeleted(ctx, key)
+
+	return nil
+}
+
+// ExpireAll marks all entries with key prefix as expired, they can still serve stale values.
+func (c *Remote) ExpireAll(ctx context.Context) error {
+	start := time.Now()
+	startTS := ts(c.t.now())
+	cnt := 0
+
+	err := c.store.Scan(ctx, c.keyPrefix, func(key string) error {
+		b, err := c.store.Get(ctx, key)
+		if err != nil {
+			if err == ErrNotFound { //nolint:errorlint // Sentinel error is expected as is.
+				return nil
+			}
+
+			return err
+		}
+
+		var e TraitEntry
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&e); err != nil {
+			return err
+		}
+
+		e.E = startTS
+		cnt++
+
+		return c.set(ctx, e)
+	})
+
+	c.t.NotifyExpiredAll(ctx, start, cnt)
+
+	return err
+}
+
+// DeleteAll erases all entries with key prefix.
+func (c *Remote) DeleteAll(ctx context.Context) error {
+	start := time.Now()
+	cnt := 0
+
+	err := c.store.Scan(ctx, c.keyPrefix, func(key string) error {
+		if err := c.store.Del(ctx, key); err != nil {
+			if err == ErrNotFound { //nolint:errorlint // Sentinel error is expected as is.
+				return nil
+			}
+
+			return err
+		}
+
+		cnt++
+
+		return nil
+	})
+
+	c.t.NotifyDeletedAll(ctx, start, cnt)
+
+	return err
+}
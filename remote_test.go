@@ -0,0 +1,176 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapStore is an in-memory cache.BytesStore.
+type mapStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttl  map[string]time.Duration
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{
+		data: map[string][]byte{},
+		ttl:  map[string]time.Duration{},
+	}
+}
+
+func (m *mapStore) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok := m.data[key]
+	if !ok {
+		return nil, cache.ErrNotFound
+	}
+
+	return v, nil
+}
+
+func (m *mapStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data[key] = value
+	m.ttl[key] = ttl
+
+	return nil
+}
+
+func (m *mapStore) Del(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data[key]; !ok {
+		return cache.ErrNotFound
+	}
+
+	delete(m.data, key)
+	delete(m.ttl, key)
+
+	return nil
+}
+
+func (m *mapStore) Scan(_ context.Context, prefix string, fn func(key string) error) error {
+	m.mu.Lock()
+
+	var keys []string
+
+	for k := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, k := range keys {
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestRemote(t *testing.T) {
+	ctx := context.Background()
+	st := stats.TrackerMock{}
+	s := newMapStore()
+
+	require.NoError(t, s.Set(ctx, "other", []byte("x"), 0))
+
+	c := cache.NewRemote(s, func(cfg *cache.RemoteConfig) {
+		cfg.KeyPrefix = "users:"
+		cfg.Stats = &st
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+		cfg.DeleteExpiredAfter = time.Hour
+	})
+
+	_, err := c.Read(ctx, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	assert.InDelta(t, float64(time.Hour+time.Minute), float64(s.ttl["users:foo"]), float64(time.Second))
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	require.NoError(t, c.Write(ctx, []byte("baz"), 123))
+
+	require.NoError(t, c.ExpireAll(ctx))
+
+	v, err = c.Read(ctx, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+	assert.Nil(t, v)
+
+	var ee cache.ErrWithExpiredItem
+
+	require.True(t, errors.As(err, &ee))
+	assert.Equal(t, "bar", ee.Value())
+
+	require.NoError(t, c.Delete(ctx, []byte("foo")))
+	assert.True(t, errors.Is(c.Delete(ctx, []byte("foo")), cache.ErrNotFound))
+
+	require.NoError(t, c.DeleteAll(ctx))
+	assert.Len(t, s.data, 1)
+
+	assert.Equal(t, 1, st.Int(cache.MetricHit))
+	assert.Equal(t, 1, st.Int(cache.MetricMiss))
+	assert.Equal(t, 3, st.Int(cache.MetricExpired))
+	assert.Equal(t, 2, st.Int(cache.MetricWrite))
+	assert.Equal(t, 2, st.Int(cache.MetricDelete))
+}
+
+func TestRemote_keyPrefix(t *testing.T) {
+	ctx := context.Background()
+	s := newMapStore()
+
+	c := cache.NewRemote(s, func(cfg *cache.RemoteConfig) {
+		cfg.KeyPrefix = "users:"
+		cfg.KeyFunc = func(key []byte) []byte {
+			return bytes.ToUpper(key)
+		}
+	})
+
+	actx := cache.WithKeyPrefix(ctx, []byte("a:"))
+	bctx := cache.WithKeyPrefix(ctx, []byte("b:"))
+
+	require.NoError(t, c.Write(actx, []byte("foo"), 1))
+	require.NoError(t, c.Write(bctx, []byte("foo"), 2))
+	assert.Contains(t, s.data, "users:A:FOO")
+	assert.Contains(t, s.data, "users:B:FOO")
+
+	v, err := c.Read(actx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	v, err = c.Read(bctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+
+	require.NoError(t, c.Delete(actx, []byte("foo")))
+	assert.NotContains(t, s.data, "users:A:FOO")
+
+	_, err = c.Read(actx, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+	// Writes are skipped with context flag.
+	require.NoError(t, c.Write(cache.WithSkipWrite(ctx), []byte("bar"), 3))
+	assert.NotContains(t, s.data, "users:BAR")
+}
@@ -0,0 +1,52 @@
+package cache
+
+import "io"
+
+// MergeStrategy defines how restored entries are merged with existing entries.
+type MergeStrategy uint8
+
+const (
+	// MergeOverwrite replaces existing entries with restored ones.
+	MergeOverwrite MergeStrategy = iota
+
+	// MergeKeepNewer keeps entry with later expiration time, entries without expiration are the newest.
+	MergeKeepNewer
+
+	// MergeKeepExisting only adds restored entries that are missing in cache.
+	MergeKeepExisting
+)
+
+// replace reports whether restored entry should replace existing one.
+func (s MergeStrategy) replace(existing, restored *TraitEntry) bool {
+	switch s {
+	case MergeKeepExisting:
+		return false
+	case MergeKeepNewer:
+		if existing.E == 0 {
+			return false
+		}
+
+		return restored.E == 0 || restored.E > existing.E
+	default:
+		return true
+	}
+}
+
+// decodeEntries reads all entries of a dump.
+func decodeEntries(r io.Reader, s Serializer) ([]TraitEntry, error) {
+	var entries []TraitEntry
+
+	err := decodeDump(r, s, func(decoder entryDecoder) error {
+		var e TraitEntry
+
+		if err := decoder.Decode(&e); err != nil {
+			return err
+		}
+
+		entries = append(entries, e)
+
+		return nil
+	})
+
+	return entries, err
+}
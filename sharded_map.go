@@ -7,7 +7,6 @@ import (
 	"errors"
 	"io"
 	"runtime"
-	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,8 +15,11 @@ import (
 )
 
 var (
-	_ ReadWriter = &shardedMap{}
-	_ Deleter    = &shardedMap{}
+	_ ReadWriter       = &shardedMap{}
+	_ Deleter          = &shardedMap{}
+	_ Walker           = &shardedMap{}
+	_ WalkDumpRestorer = &ShardedMap{}
+	_ io.Closer        = &ShardedMap{}
 )
 
 const shards = 128
@@ -64,19 +66,32 @@ func NewShardedMap(options ...func(cfg *Config)) *ShardedMap {
 
 	c.t = NewTrait(cfg, func(t *Trait) {
 		t.DeleteExpired = c.deleteExpired
+		t.deleteExpiredKeys = c.deleteExpiredKeys
+		t.sampleExpirations = c.sampleExpirations
 		t.Len = c.Len
 		t.Evict = evict
+		t.DecayCounters = c.decayCounters
 	})
 
 	c.InvalidationIndex = NewInvalidationIndex(c)
 
 	runtime.SetFinalizer(C, func(m *ShardedMap) {
-		close(m.t.Closed)
+		m.t.Close()
 	})
 
 	return C
 }
 
+// Close stops background jobs of cache, it is safe to call it multiple times.
+//
+// Cache remains operational after Close, but expired entries are not deleted and eviction does not happen.
+func (c *ShardedMap) Close() error {
+	runtime.SetFinalizer(c, nil)
+	c.t.Close()
+
+	return nil
+}
+
 // Load returns the value stored in the map for a key, or nil if no
 // value is present.
 // The ok result indicates whether value was found in the map.
@@ -106,6 +121,8 @@ func (c *shardedMap) Read(ctx context.Context, key []byte) (interface{}, error)
 		return nil, ErrNotFound
 	}
 
+	key = c.t.key(ctx, key)
+
 	h := xxhash.Sum64(key)
 	b := &c.hashedBuckets[h%shards]
 	b.RLock()
@@ -117,15 +134,38 @@ func (c *shardedMap) Read(ctx context.Context, key []byte) (interface{}, error)
 		found = false
 	}
 
-	return c.t.PrepareRead(ctx, cacheEntry, found)
+	return c.t.prepareRead(ctx, key, cacheEntry, found)
+}
+
+// WriteRead sets value by the key and returns stored value.
+//
+// It saves a subsequent Read that could observe a value of a concurrent writer.
+func (c *shardedMap) WriteRead(ctx context.Context, k []byte, v interface{}) (interface{}, error) {
+	if err := c.Write(ctx, k, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
 }
 
 // Write sets value by the key.
 func (c *shardedMap) Write(ctx context.Context, k []byte, v interface{}) error {
+	if c.t.skipWrite(ctx, k) {
+		return nil
+	}
+
+	k, err := c.t.writeKey(ctx, k)
+	if err != nil {
+		return err
+	}
+
+	if err = c.t.prepareWrite(ctx, k, v); err != nil {
+		return err
+	}
+
 	h := xxhash.Sum64(k)
 	b := &c.hashedBuckets[h%shards]
 	b.Lock()
-	defer b.Unlock()
 
 	// Copy key to allow mutations of original argument.
 	key := make([]byte, len(k))
@@ -133,30 +173,146 @@ func (c *shardedMap) Write(ctx context.Context, k []byte, v interface{}) error {
 
 	ttl, expireAt := c.t.expireAt(ctx)
 
-	b.data[h] = &TraitEntry{V: v, K: key, E: expireAt}
+	if c.t.Config.SkipEqualWrites {
+		if prev, found := b.data[h]; found && bytes.Equal(prev.K, key) && c.t.equalWrite(ctx, prev.V, v, prev.N, prev.G) {
+			atomic.StoreInt64(&prev.E, expireAt)
+			c.t.indexExpiration(string(key), expireAt, prev.G)
+			b.Unlock()
+
+			return nil
+		}
+	}
+
+	e := &TraitEntry{
+		V: v, K: key, E: expireAt, C: c.t.counter(),
+		G: int64(GracePeriod(ctx)), S: c.t.entrySize(v), N: c.t.Config.Generation, T: ts(c.t.now()),
+	}
+	_, found := b.data[h]
+	c.put(b, h, e)
+
+	b.Unlock()
+
+	if !found {
+		if err := c.t.fitHardLimit(ctx, func() {
+			b.Lock()
+			if b.data[h] == e {
+				delete(b.data, h)
+				c.t.addBytes(-e.S)
+			}
+			b.Unlock()
+		}); err != nil {
+			return err
+		}
+	}
 
 	c.t.NotifyWritten(ctx, key, v, ttl)
 
 	return nil
 }
 
+// Preload stores entries with a shared time to live and returns number of stored entries.
+//
+// It is a faster alternative to Write for seeding cache, for example on startup from a database:
+// expiration is calculated once, write metric is reported once for the batch and entries are not logged.
+// Expiration of entries is ignored, ttl is applied instead, DefaultTTL uses Config.TimeToLive and UnlimitedTTL
+// stores entries that never expire. Values rejected by Config.MaxValueSize are skipped.
+//
+// If cache exceeds Config.CountHardLimit and can not evict, preloaded entries are removed and ErrCacheFull is returned.
+// Preload stops with context error if context is canceled, entries stored so far are kept.
+func (c *shardedMap) Preload(ctx context.Context, entries []Entry, ttl time.Duration) (int, error) {
+	if SkipWrite(ctx) {
+		return 0, nil
+	}
+
+	ttl, expireAt := c.t.expireAtTTL(ctx, ttl)
+	now := ts(c.t.now())
+	grace := int64(GracePeriod(ctx))
+	stored := make([]*TraitEntry, 0, len(entries))
+
+	var err error
+
+	for i, pe := range entries {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+		}
+
+		k, keyErr := c.t.writeKey(ctx, pe.Key())
+		if keyErr != nil {
+			continue
+		}
+
+		v := pe.Value()
+
+		if c.t.prepareWrite(ctx, k, v) != nil {
+			continue
+		}
+
+		// Copy key to allow mutations of original entry.
+		key := make([]byte, len(k))
+		copy(key, k)
+
+		e := &TraitEntry{
+			V: v, K: key, E: expireAt, C: c.t.counter(),
+			G: grace, S: c.t.entrySize(v), N: c.t.Config.Generation, T: now,
+		}
+
+		h := xxhash.Sum64(key)
+		b := &c.hashedBuckets[h%shards]
+
+		b.Lock()
+		c.put(b, h, e)
+		b.Unlock()
+
+		stored = append(stored, e)
+	}
+
+	if fitErr := c.t.fitHardLimit(ctx, func() {
+		for _, e := range stored {
+			h := xxhash.Sum64(e.K)
+			b := &c.hashedBuckets[h%shards]
+
+			b.Lock()
+			if b.data[h] == e {
+				delete(b.data, h)
+				c.t.addBytes(-e.S)
+			}
+			b.Unlock()
+		}
+	}); fitErr != nil {
+		return 0, fitErr
+	}
+
+	c.t.notifyPreloaded(ctx, len(stored), ttl)
+
+	return len(stored), err
+}
+
 // Delete removes value by the key.
 //
-// It fails with ErrNotFound if key does not exist.
+// It fails with ErrNotFound if key does not exist, unless Config.IgnoreMissingDelete is enabled.
 func (c *shardedMap) Delete(ctx context.Context, key []byte) error {
+	key = c.t.key(ctx, key)
+
 	h := xxhash.Sum64(key)
 	b := &c.hashedBuckets[h%shards]
 
 	b.Lock()
-	defer b.Unlock()
 
 	cachedEntry, found := b.data[h]
 	if !found || !bytes.Equal(cachedEntry.K, key) {
-		return ErrNotFound
+		b.Unlock()
+
+		return c.t.missingDelete()
 	}
 
 	delete(b.data, h)
+	c.t.addBytes(-cachedEntry.S)
+	b.Unlock()
 
+	c.t.unindexExpiration(string(cachedEntry.K), cachedEntry.E, cachedEntry.G)
+	c.t.notifyRemoved(cachedEntry.K, cachedEntry.V, EvictReasonDeleted)
 	c.t.NotifyDeleted(ctx, key)
 
 	return nil
@@ -164,26 +320,83 @@ func (c *shardedMap) Delete(ctx context.Context, key []byte) error {
 
 // ExpireAll marks all entries as expired, they can still serve stale cache.
 func (c *shardedMap) ExpireAll(ctx context.Context) {
+	c.ExpireAllCount(ctx)
+}
+
+// ExpireAllCount marks all entries as expired and returns number of expired entries.
+func (c *shardedMap) ExpireAllCount(ctx context.Context) int {
+	start := time.Now()
+	cnt := c.expireIf(nil)
+
+	c.t.NotifyExpiredAll(ctx, start, cnt)
+
+	return cnt
+}
+
+// ExpireMatching marks entries that satisfy predicate as expired and returns number of expired entries.
+//
+// Expired entries can still serve stale values, other entries are not affected.
+// Predicate is called while shard is locked, so it must not access cache.
+func (c *shardedMap) ExpireMatching(ctx context.Context, predicate func(e Entry) bool) int {
 	start := time.Now()
-	startTS := ts(start)
+	cnt := c.expireIf(predicate)
+
+	c.t.notifyExpiredMatching(ctx, start, cnt)
+
+	return cnt
+}
+
+// expireIf marks entries as expired if optional predicate is satisfied.
+func (c *shardedMap) expireIf(predicate func(e Entry) bool) int {
+	startTS := ts(c.t.now())
 	cnt := 0
 
 	for i := range c.hashedBuckets {
 		b := &c.hashedBuckets[i]
 		b.Lock()
 		for h, v := range b.data {
+			if predicate != nil && !predicate(v) {
+				continue
+			}
+
 			v.E = startTS
 			b.data[h] = v
+			c.t.indexExpiration(string(v.K), v.E, v.G)
 			cnt++
 		}
 		b.Unlock()
 	}
 
-	c.t.NotifyExpiredAll(ctx, start, cnt)
+	return cnt
 }
 
 // DeleteAll erases all entries.
 func (c *shardedMap) DeleteAll(ctx context.Context) {
+	c.Purge(ctx)
+}
+
+// Purge erases all entries and returns number of deleted entries.
+func (c *shardedMap) Purge(ctx context.Context) int {
+	return c.purge(ctx, nil)
+}
+
+// Drain removes all entries and returns them, for example to hand off cache to another process.
+//
+// Shards are drained one by one under lock, so entry that is written concurrently is either returned or kept in cache.
+func (c *shardedMap) Drain(ctx context.Context) []Entry {
+	entries := make([]Entry, 0, c.Len())
+
+	c.purge(ctx, func(e *TraitEntry) {
+		entries = append(entries, e)
+	})
+
+	return entries
+}
+
+// purge erases all entries and calls optional collect for every removed entry.
+func (c *shardedMap) purge(ctx context.Context, collect func(e *TraitEntry)) int {
+	var removed []*TraitEntry
+
 	now := time.Now()
 	cnt := 0
 
@@ -191,30 +404,245 @@ func (c *shardedMap) DeleteAll(ctx context.Context) {
 		b := &c.hashedBuckets[i]
 
 		b.Lock()
-		for h := range c.hashedBuckets[i].data {
+		for h, v := range c.hashedBuckets[i].data {
 			delete(b.data, h)
+			c.t.addBytes(-v.S)
 			cnt++
+
+			if collect != nil {
+				collect(v)
+			}
+
+			removed = c.removed(removed, v)
 		}
 		b.Unlock()
+
+		removed = c.notifyRemoved(removed, EvictReasonDeleted)
 	}
 
 	c.t.NotifyDeletedAll(ctx, now, cnt)
+
+	return cnt
+}
+
+// DeletePrefix removes entries with keys that have prefix and returns number of deleted entries.
+//
+// Iteration stops with context error if context is canceled.
+func (c *shardedMap) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	var removed []*TraitEntry
+
+	start := time.Now()
+	cnt := 0
+
+	for i := range c.hashedBuckets {
+		if err := ctx.Err(); err != nil {
+			c.t.NotifyDeletedPrefix(ctx, start, prefix, cnt)
+
+			return cnt, err
+		}
+
+		b := &c.hashedBuckets[i]
+
+		b.Lock()
+		for h, v := range b.data {
+			if bytes.HasPrefix(v.K, prefix) {
+				delete(b.data, h)
+				c.t.addBytes(-v.S)
+				cnt++
+
+				removed = c.removed(removed, v)
+			}
+		}
+		b.Unlock()
+
+		removed = c.notifyRemoved(removed, EvictReasonDeleted)
+	}
+
+	c.t.NotifyDeletedPrefix(ctx, start, prefix, cnt)
+
+	return cnt, nil
+}
+
+// put stores entry in a locked bucket and maintains total size of values.
+func (c *shardedMap) put(b *hashedBucket, h uint64, e *TraitEntry) {
+	if prev, found := b.data[h]; found {
+		c.t.addBytes(-prev.S)
+	}
+
+	b.data[h] = e
+	c.t.addBytes(e.S)
+	c.t.indexExpiration(string(e.K), e.E, e.G)
 }
 
 func (c *shardedMap) deleteExpired(before time.Time) {
 	beforeTS := ts(before)
 
+	var removed, candidates []*TraitEntry
+
+	cnt := 0
+
 	for i := range c.hashedBuckets {
 		b := &c.hashedBuckets[i]
 
 		b.Lock()
 		for h, v := range b.data {
-			if v.E < beforeTS {
+			if c.t.deleteExpiredBefore(v.E, v.G, beforeTS) {
+				if c.t.Config.CanEvict != nil {
+					candidates = append(candidates, v)
+
+					continue
+				}
+
+				delete(b.data, h)
+				c.t.addBytes(-v.S)
+
+				cnt++
+				removed = c.removed(removed, v)
+			}
+		}
+		b.Unlock()
+
+		if len(candidates) > 0 {
+			var n int
+
+			n, removed = c.deleteUnpinned(candidates, beforeTS, removed)
+			cnt += n
+			candidates = candidates[:0]
+		}
+
+		removed = c.notifyRemoved(removed, EvictReasonExpired)
+	}
+
+	if cnt > 0 {
+		c.t.notifyDeletedExpired(cnt)
+	}
+}
+
+// deleteExpiredKeys deletes expired entries of keys from expiration index and returns number of deleted entries.
+func (c *shardedMap) deleteExpiredKeys(keys []string, beforeTS int64) int {
+	var removed, candidates []*TraitEntry
+
+	cnt := 0
+
+	for _, k := range keys {
+		h := xxhash.Sum64String(k)
+		b := &c.hashedBuckets[h%shards]
+
+		b.Lock()
+		if v, found := b.data[h]; found && string(v.K) == k {
+			switch {
+			case !c.t.deleteExpiredBefore(v.E, v.G, beforeTS):
+				// Entry was updated after indexing.
+				c.t.indexExpiration(k, v.E, v.G)
+			case c.t.Config.CanEvict != nil:
+				candidates = append(candidates, v)
+			default:
 				delete(b.data, h)
+				c.t.addBytes(-v.S)
+
+				cnt++
+				removed = c.removed(removed, v)
 			}
 		}
 		b.Unlock()
 	}
+
+	n, removed := c.deleteUnpinned(candidates, beforeTS, removed)
+
+	c.notifyRemoved(removed, EvictReasonExpired)
+
+	return cnt + n
+}
+
+// deleteUnpinned deletes expired entries that are not pinned by Config.CanEvict and returns number of deleted entries.
+//
+// Config.CanEvict is called outside of bucket locks, so that it can access cache. Entry that was replaced
+// or touched meanwhile is kept, pinned entry is indexed again to be checked by next cleanup.
+func (c *shardedMap) deleteUnpinned(candidates []*TraitEntry, beforeTS int64, removed []*TraitEntry) (int, []*TraitEntry) {
+	cnt := 0
+
+	for _, v := range candidates {
+		if c.t.pinnedExpired(v) {
+			c.t.indexExpiration(string(v.K), atomic.LoadInt64(&v.E), v.G)
+
+			continue
+		}
+
+		h := xxhash.Sum64(v.K)
+		b := &c.hashedBuckets[h%shards]
+
+		b.Lock()
+		if cur, found := b.data[h]; found && cur == v && c.t.deleteExpiredBefore(atomic.LoadInt64(&v.E), v.G, beforeTS) {
+			delete(b.data, h)
+			c.t.addBytes(-v.S)
+
+			cnt++
+			removed = c.removed(removed, v)
+		}
+		b.Unlock()
+	}
+
+	return cnt, removed
+}
+
+// removed collects removed entry to notify after lock is released, if Config.OnEvicted is set.
+func (c *shardedMap) removed(removed []*TraitEntry, e *TraitEntry) []*TraitEntry {
+	if c.t.Config.OnEvicted == nil {
+		return removed
+	}
+
+	return append(removed, e)
+}
+
+// notifyRemoved invokes Config.OnEvicted for removed entries and resets the slice.
+func (c *shardedMap) notifyRemoved(removed []*TraitEntry, reason EvictReason) []*TraitEntry {
+	for _, e := range removed {
+		c.t.notifyRemoved(e.K, e.V, reason)
+	}
+
+	return removed[:0]
+}
+
+// sampleExpirations calls fn for expiration and creation timestamps of up to n entries,
+// entries are sampled evenly from all shards.
+func (c *shardedMap) sampleExpirations(n int, fn func(expireAt, createdAt int64)) {
+	perShard := n/shards + 1
+	i := 0
+
+	for j := range c.hashedBuckets {
+		b := &c.hashedBuckets[j]
+		k := 0
+
+		b.RLock()
+		for _, v := range b.data {
+			if k >= perShard || i >= n {
+				break
+			}
+
+			fn(v.E, v.T)
+
+			k++
+			i++
+		}
+		b.RUnlock()
+	}
+}
+
+func (c *shardedMap) decayCounters() {
+	for i := range c.hashedBuckets {
+		b := &c.hashedBuckets[i]
+
+		b.RLock()
+		for _, v := range b.data {
+			halveCounter(&v.C)
+		}
+		b.RUnlock()
+	}
+}
+
+// Stats returns a snapshot of cache activity counters.
+func (c *shardedMap) Stats() CacheStats {
+	return c.t.Stats()
 }
 
 // Len returns number of elements in cache.
@@ -257,15 +685,60 @@ func (c *shardedMap) Walk(walkFn func(e Entry) error) (int, error) {
 	return n, nil
 }
 
+// WalkSorted walks cached entries in order defined by less, entries are ordered by key if less is nil.
+//
+// Entries are collected and sorted before walking to have a deterministic order, for example for snapshot tests,
+// so it needs memory for references to all entries.
+func (c *shardedMap) WalkSorted(less func(a, b Entry) bool, walkFn func(e Entry) error) (int, error) {
+	return walkSorted(c.Walk, less, walkFn)
+}
+
 // Dump saves cached entries and returns a number of processed entries.
 //
 // Dump uses encoding/gob to serialize cache entries, therefore it is necessary to
 // register cached types in advance with cache.GobRegister.
+// Custom serialization can be configured with Config.Serializer.
+//
+// Nil values are dumped, but entries with typed nil pointer values (for example (*T)(nil)) are skipped
+// and not counted, because encoding/gob can not encode them.
 func (c *ShardedMap) Dump(w io.Writer) (int, error) {
-	encoder := gob.NewEncoder(w)
+	return c.DumpWithOptions(w, DumpOptions{})
+}
+
+// DumpWithOptions saves cached entries with encoding options and returns a number of processed entries.
+func (c *ShardedMap) DumpWithOptions(w io.Writer, opts DumpOptions) (int, error) {
+	return encodeDump(w, opts, c.t.Config.Serializer, func(encoder entryEncoder) (int, error) {
+		walk := c.Walk
+		if opts.Sorted {
+			walk = func(walkFn func(e Entry) error) (int, error) {
+				return c.WalkSorted(nil, walkFn)
+			}
+		}
+
+		return walk(func(e Entry) error {
+			return encoder.Encode(e)
+		})
+	})
+}
+
+// DumpLive saves cached entries that are not expired and returns a number of saved entries.
+func (c *ShardedMap) DumpLive(w io.Writer) (int, error) {
+	now := ts(c.t.now())
+
+	return encodeDump(w, DumpOptions{}, c.t.Config.Serializer, func(encoder entryEncoder) (int, error) {
+		n := 0
+
+		_, err := c.Walk(func(e Entry) error {
+			if exp := e.(*TraitEntry).E; exp != 0 && exp < now { //nolint // Panic on type assertion failure is fine here.
+				return nil
+			}
+
+			n++
 
-	return c.Walk(func(e Entry) error {
-		return encoder.Encode(e)
+			return encoder.Encode(e)
+		})
+
+		return n, err
 	})
 }
 
@@ -273,7 +746,42 @@ func (c *ShardedMap) Dump(w io.Writer) (int, error) {
 //
 // Restore uses encoding/gob to unserialize cache entries, therefore it is necessary to
 // register cached types in advance with cache.GobRegister.
+// Custom serialization can be configured with Config.Serializer.
+//
+// Dump is fully decoded and validated before entries are stored, so that cache is not modified
+// if dump is broken. Decoded entries are held in memory, so restore needs memory for a full copy
+// of dumped entries in addition to cache.
 func (c *ShardedMap) Restore(r io.Reader) (int, error) {
+	entries, err := decodeEntries(r, c.t.Config.Serializer)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+
+	for i := range entries {
+		e := &entries[i]
+		if e.N != c.t.Config.Generation {
+			continue
+		}
+
+		h := xxhash.Sum64(e.K)
+		b := &c.hashedBuckets[h%shards]
+
+		e.S = c.t.entrySize(e.V)
+
+		b.Lock()
+		c.put(b, h, e)
+		b.Unlock()
+
+		n++
+	}
+
+	return n, nil
+}
+
+// RestoreLegacy loads cached entries from a dump without header and checksum, written by older versions.
+func (c *ShardedMap) RestoreLegacy(r io.Reader) (int, error) {
 	var (
 		decoder = gob.NewDecoder(r)
 		n       = 0
@@ -291,11 +799,17 @@ func (c *ShardedMap) Restore(r io.Reader) (int, error) {
 			return n, err
 		}
 
+		if e.N != c.t.Config.Generation {
+			continue
+		}
+
 		h := xxhash.Sum64(e.K)
 		b := &c.hashedBuckets[h%shards]
 
+		e.S = c.t.entrySize(e.V)
+
 		b.Lock()
-		b.data[h] = &e
+		c.put(b, h, &e)
 		b.Unlock()
 
 		n++
@@ -304,9 +818,40 @@ func (c *ShardedMap) Restore(r io.Reader) (int, error) {
 	return n, nil
 }
 
-type evictLeastEntry struct {
-	hash uint64
-	val  int64
+// RestoreMerge loads cache entries from io.Reader and merges them with existing entries using strategy.
+//
+// Dump is fully decoded before merge, so that cache is not modified if dump is broken,
+// this needs memory for a full copy of dumped entries. Number of stored entries is returned.
+func (c *ShardedMap) RestoreMerge(r io.Reader, strategy MergeStrategy) (int, error) {
+	entries, err := decodeEntries(r, c.t.Config.Serializer)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+
+	for i := range entries {
+		e := &entries[i]
+		if e.N != c.t.Config.Generation {
+			continue
+		}
+
+		h := xxhash.Sum64(e.K)
+		b := &c.hashedBuckets[h%shards]
+
+		b.Lock()
+
+		e.S = c.t.entrySize(e.V)
+
+		if existing, found := b.data[h]; !found || !bytes.Equal(existing.K, e.K) || strategy.replace(existing, e) {
+			c.put(b, h, e)
+			n++
+		}
+
+		b.Unlock()
+	}
+
+	return n, nil
 }
 
 func (c *shardedMap) evictMostExpired(evictFraction float64) int {
@@ -333,34 +878,93 @@ func (c *shardedMap) evictLeast(evictFraction float64, val func(i *TraitEntry) i
 		b.RUnlock()
 	}
 
-	entries := make([]evictLeastEntry, 0, cnt)
+	evictItems := c.t.evictCount(cnt, evictFraction)
+
+	minCreated := c.t.evictMinCreated()
+
+	// Only candidates for eviction are kept in memory, young entries complete the quota if there are not enough others.
+	entries := make(evictLeastEntries, 0, evictItems)
 
-	// Collect all keys and expirations.
+	var young evictLeastEntries
+
+	pinnable := c.t.Config.CanEvict != nil
+
+	// Bounded candidates of a bucket that are checked with Config.CanEvict after bucket lock is released.
+	var bucketEntries, bucketYoung evictLeastEntries
+
+	// Collect keys with least values.
 	for i := range c.hashedBuckets {
 		b := &c.hashedBuckets[i]
+		top, topYoung := &entries, &young
+
+		if pinnable {
+			top, topYoung = &bucketEntries, &bucketYoung
+		}
 
 		b.RLock()
 		for h, i := range b.data {
-			entries = append(entries, evictLeastEntry{hash: h, val: val(i)})
+			if i.T > minCreated {
+				topYoung.offer(evictLeastEntry{hash: h, val: val(i)}, evictItems)
+			} else {
+				top.offer(evictLeastEntry{hash: h, val: val(i)}, evictItems)
+			}
 		}
 		b.RUnlock()
+
+		if pinnable {
+			c.offerEvictable(b, bucketEntries, &entries, evictItems)
+			c.offerEvictable(b, bucketYoung, &young, evictItems)
+
+			bucketEntries, bucketYoung = bucketEntries[:0], bucketYoung[:0]
+		}
 	}
 
-	// Sort entries to put most expired in head.
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].val < entries[j].val
-	})
+	if r := evictItems - len(entries); r > 0 {
+		least := make(evictLeastEntries, 0, r)
+
+		for _, en := range young {
+			least.offer(en, r)
+		}
+
+		entries = append(entries, least...)
+	}
 
-	evictItems := int(float64(len(entries)) * evictFraction)
+	evicted := 0
 
-	for i := 0; i < evictItems; i++ {
-		h := entries[i].hash
+	for _, en := range entries {
+		h := en.hash
 		b := &c.hashedBuckets[h%shards]
 
 		b.Lock()
-		delete(b.data, h)
+		v, found := b.data[h]
+		if found {
+			delete(b.data, h)
+			c.t.addBytes(-v.S)
+		}
 		b.Unlock()
+
+		if found {
+			evicted++
+
+			c.t.evicted(v.K, v.V)
+		}
 	}
 
-	return evictItems
+	return evicted
+}
+
+// offerEvictable offers candidates of a bucket that are not pinned by Config.CanEvict.
+//
+// Config.CanEvict is called without bucket lock, so that it can read cache. Pinned candidates
+// are skipped, so eviction may remove fewer entries than requested.
+func (c *shardedMap) offerEvictable(b *hashedBucket, candidates evictLeastEntries, to *evictLeastEntries, k int) {
+	for _, en := range candidates {
+		b.RLock()
+		e, found := b.data[en.hash]
+		b.RUnlock()
+
+		if found && c.t.canEvict(e) {
+			to.offer(en, k)
+		}
+	}
 }
@@ -4,6 +4,7 @@
 package cache_test
 
 import (
+	"bytes"
 	"context"
 	"runtime"
 	"testing"
@@ -13,6 +14,7 @@ import (
 	"github.com/bool64/ctxd"
 	"github.com/bool64/stats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/swaggest/assertjson"
 )
 
@@ -65,7 +67,7 @@ func TestNewShardedMapOf(t *testing.T) {
 		"time":"<ignore-diff>","level":"debug",
 		"message":"cache hit",
 		"data":{
-		  "entry":{"key":"foo","val":"bar","exp":"<ignore-diff>"},
+		  "entry":{"key":"foo","val":"bar","exp":"<ignore-diff>","created":"<ignore-diff>"},
 		  "name":"test"
 		}
 	  },
@@ -108,3 +110,84 @@ cache_hit{name="test"} 1
 cache_items{name="test"} 0
 cache_write{name="test"} 1`, st.Metrics())
 }
+
+func TestShardedMapOf_ExpireMatching(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewShardedMapOf[int]()
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	assert.NoError(t, c.Write(ctx, []byte("bar"), 2))
+
+	assert.Equal(t, 1, c.ExpireMatching(ctx, func(e cache.EntryOf[int]) bool {
+		return e.Value() > 1
+	}))
+
+	_, err := c.Read(ctx, []byte("bar"))
+	assert.ErrorIs(t, err, cache.ErrExpired)
+
+	v, err := c.Read(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
+
+func TestShardedMapOf_softExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	c := cache.NewShardedMapOf[int](func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = 10 * time.Minute
+		cfg.ExpirationJitter = -1
+		cfg.SoftTTLFraction = 0.8
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), 1))
+
+	clock.Add(9 * time.Minute)
+
+	_, err := c.Read(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrSoftExpired)
+
+	v, _, ok := cache.StaleValueOf[int](err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+}
+
+func TestShardedMapOf_Drain(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewShardedMapOf[int]()
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), 1))
+
+	entries := c.Drain(ctx)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "foo", string(entries[0].Key()))
+	assert.Equal(t, 1, entries[0].Value())
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestShardedMapOf_Restore_serializer(t *testing.T) {
+	ctx := context.Background()
+	cfg := cache.Config{Serializer: jsonSerializer{}}
+	src := cache.NewShardedMapOf[string](cfg.Use)
+
+	require.NoError(t, src.Write(ctx, []byte("foo"), "bar"))
+
+	w := bytes.NewBuffer(nil)
+	n, err := src.Dump(w)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	dst := cache.NewShardedMapOf[string](cfg.Use)
+	n, err = dst.Restore(bytes.NewReader(w.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	v, err := dst.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	// Decoded value of wrong type fails restore.
+	_, err = cache.NewShardedMapOf[int](cfg.Use).Restore(bytes.NewReader(w.Bytes()))
+	assert.ErrorIs(t, err, cache.ErrUnexpectedType)
+}
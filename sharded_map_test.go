@@ -1,8 +1,10 @@
 package cache_test
 
 import (
+	"bytes"
 	"context"
 	"runtime"
+	"strconv"
 	"testing"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/bool64/ctxd"
 	"github.com/bool64/stats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/swaggest/assertjson"
 )
 
@@ -60,7 +63,7 @@ func TestNewShardedMap(t *testing.T) {
 		"time":"<ignore-diff>","level":"debug",
 		"message":"cache hit",
 		"data":{
-		  "entry":{"key":"foo","val":"bar","exp":"<ignore-diff>"},
+		  "entry":{"key":"foo","val":"bar","exp":"<ignore-diff>","created":"<ignore-diff>"},
 		  "name":"test"
 		}
 	  },
@@ -151,7 +154,7 @@ func TestNewShardedMap_noExpiration(t *testing.T) {
 		"time":"<ignore-diff>","level":"debug",
 		"message":"cache hit",
 		"data":{
-		  "entry":{"key":"foo","val":"bar","exp":"<ignore-diff>"},
+		  "entry":{"key":"foo","val":"bar","exp":"<ignore-diff>","created":"<ignore-diff>"},
 		  "name":"test"
 		}
 	  },
@@ -194,3 +197,243 @@ cache_hit{name="test"} 1
 cache_items{name="test"} 0
 cache_write{name="test"} 1`, st.Metrics())
 }
+
+func TestShardedMap_Write_maxValueSize(t *testing.T) {
+	ctx := context.Background()
+
+	c := cache.NewShardedMap(func(config *cache.Config) {
+		config.MaxValueSize = 3
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), []byte("abc")))
+	assert.Equal(t, cache.ErrValueTooLarge, c.Write(ctx, []byte("bar"), []byte("abcd")))
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestShardedMap_Write_maxKeySize(t *testing.T) {
+	ctx := context.Background()
+	st := stats.TrackerMock{}
+
+	c := cache.NewShardedMap(func(config *cache.Config) {
+		config.Name = "test"
+		config.Stats = &st
+		config.MaxKeySize = 3
+		config.KeyFunc = func(key []byte) []byte {
+			return append([]byte("hashed:"), key...)
+		}
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	assert.Equal(t, cache.ErrKeyTooLarge, c.Write(ctx, []byte("fooo"), 2))
+	assert.Equal(t, 1, c.Len())
+	assert.Equal(t, 1, st.Int(cache.MetricRejected, "name", "test"))
+
+	c = cache.NewShardedMap(func(config *cache.Config) {
+		config.MaxKeySize = 10
+		config.MaxKeySizeAfterKeyFunc = true
+		config.KeyFunc = func(key []byte) []byte {
+			return append([]byte("hashed:"), key...)
+		}
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	assert.Equal(t, cache.ErrKeyTooLarge, c.Write(ctx, []byte("fooo"), 2))
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestShardedMap_Write_countHardLimit(t *testing.T) {
+	ctx := context.Background()
+
+	c := cache.NewShardedMap(func(config *cache.Config) {
+		config.CountHardLimit = 10
+		config.EvictionStrategy = cache.EvictLeastRecentlyUsed
+	})
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err := c.Read(ctx, []byte("0"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Write(ctx, []byte("10"), 10))
+	assert.Equal(t, 10, c.Len())
+
+	// Least recently used entry is evicted.
+	_, err = c.Read(ctx, []byte("0"))
+	assert.NoError(t, err)
+	_, err = c.Read(ctx, []byte("1"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+
+	c = cache.NewShardedMap(func(config *cache.Config) {
+		config.CountHardLimit = 1
+		config.RejectOnFull = true
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("0"), 0))
+	assert.NoError(t, c.Write(ctx, []byte("0"), 1))
+	assert.Equal(t, cache.ErrCacheFull, c.Write(ctx, []byte("1"), 1))
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestShardedMap_Purge(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewShardedMap()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+	}
+
+	assert.Equal(t, 5, c.ExpireAllCount(ctx))
+	assert.Equal(t, 5, c.Purge(ctx))
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestShardedMap_DeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewShardedMap()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, c.Write(ctx, []byte("user:1:"+strconv.Itoa(i)), i))
+		assert.NoError(t, c.Write(ctx, []byte("user:2:"+strconv.Itoa(i)), i))
+	}
+
+	n, err := c.DeletePrefix(ctx, []byte("user:1:"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 5, c.Len())
+
+	_, err = c.Read(ctx, []byte("user:2:3"))
+	assert.NoError(t, err)
+}
+
+func TestShardedMap_keyFunc(t *testing.T) {
+	ctx := context.Background()
+
+	c := cache.NewShardedMap(func(config *cache.Config) {
+		config.KeyFunc = bytes.ToLower
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("Foo"), 1))
+
+	v, err := c.Read(ctx, []byte("FOO"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	assert.NoError(t, c.Delete(ctx, []byte("foo")))
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestShardedMap_WriteRead(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewShardedMap()
+
+	v, err := c.WriteRead(ctx, []byte("foo"), "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	v, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+}
+
+func TestShardedMap_WalkSorted(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewShardedMap()
+
+	for i := 9; i >= 0; i-- {
+		require.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+	}
+
+	var values []int
+
+	n, err := c.WalkSorted(nil, func(e cache.Entry) error {
+		values = append(values, e.Value().(int))
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, values)
+}
+
+func TestShardedMap_Preload(t *testing.T) {
+	ctx := context.Background()
+	m := &stats.TrackerMock{}
+
+	c := cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.Name = "test"
+		cfg.Stats = m
+	})
+
+	entries := make([]cache.Entry, 0, 100)
+	for i := 0; i < 100; i++ {
+		entries = append(entries, cache.TraitEntry{K: []byte(strconv.Itoa(i)), V: i})
+	}
+
+	n, err := c.Preload(ctx, entries, cache.UnlimitedTTL)
+	require.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, 100, c.Len())
+	assert.Equal(t, 100, m.Int(cache.MetricWrite, "name", "test"))
+
+	v, err := c.Read(ctx, []byte("42"))
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	n, err = c.Preload(canceled, entries, cache.DefaultTTL)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, n)
+}
+
+func TestShardedMap_ExpireMatching(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewShardedMap()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+	}
+
+	assert.Equal(t, 3, c.ExpireMatching(ctx, func(e cache.Entry) bool {
+		return e.Value().(int) < 3
+	}))
+
+	_, err := c.Read(ctx, []byte("2"))
+	assert.ErrorIs(t, err, cache.ErrExpired)
+
+	v, err := c.Read(ctx, []byte("3"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+}
+
+func TestShardedMap_Drain(t *testing.T) {
+	ctx := context.Background()
+	evicted := 0
+	c := cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.OnEvicted = func(key []byte, value interface{}, reason cache.EvictReason) {
+			assert.Equal(t, cache.EvictReasonDeleted, reason)
+
+			evicted++
+		}
+	})
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+	}
+
+	entries := c.Drain(ctx)
+	require.Len(t, entries, 100)
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, 100, evicted)
+
+	sum := 0
+	for _, e := range entries {
+		sum += e.Value().(int)
+	}
+
+	assert.Equal(t, 4950, sum)
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// flightCall is an in-flight or completed build of a value.
+type flightCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// flightGroup deduplicates concurrent builds of the same key.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do invokes fn once per key at a time, concurrent callers of the same key wait for the result.
+//
+// The shared result is true for callers that received a value built by another caller.
+// If fn panics, the panic is propagated and concurrent callers receive ErrCallbackPanic error.
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (interface{}, bool, error) {
+	g.mu.Lock()
+
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-c.done
+
+		return c.val, true, c.err
+	}
+
+	c := &flightCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			// Waiters receive an error, panic is propagated to the caller that invoked fn.
+			c.val, c.err = nil, fmt.Errorf("%w: build: %v", ErrCallbackPanic, r)
+		}
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+
+		close(c.done)
+
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	c.val, c.err = fn()
+
+	return c.val, false, c.err
+}
+
+// buildLimiter is a semaphore to limit concurrent builds of different keys, nil limiter is unlimited.
+type buildLimiter chan struct{}
+
+func newBuildLimiter(limit int) buildLimiter {
+	if limit <= 0 {
+		return nil
+	}
+
+	return make(buildLimiter, limit)
+}
+
+// acquire waits for a build slot or fails with ErrTooManyBuilds if reject is true.
+func (l buildLimiter) acquire(ctx context.Context, reject bool) error {
+	if l == nil {
+		return nil
+	}
+
+	if reject {
+		select {
+		case l <- struct{}{}:
+			return nil
+		default:
+			return ErrTooManyBuilds
+		}
+	}
+
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l buildLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
@@ -11,10 +11,21 @@ const (
 	MetricHit = "cache_hit"
 	// MetricWrite is a name of a metric to count cache write events.
 	MetricWrite = "cache_write"
-	// MetricDelete is a name of a metric to count cache delete events.
+	// MetricDelete is a name of a metric to count cache delete events,
+	// expired entries deleted by background job are counted in batches.
 	MetricDelete = "cache_delete"
+	// MetricRejected is a name of a metric to count rejected cache write events.
+	MetricRejected = "cache_rejected"
 	// MetricItems is a name of a gauge to count number of items in cache.
 	MetricItems = "cache_items"
+	// MetricHitRatio is a name of a gauge to report ratio of valid cache reads in Config.HitRatioWindow.
+	MetricHitRatio = "cache_hit_ratio"
+	// MetricAvgTTL is a name of a gauge to report average time to live in seconds of sampled entries,
+	// see Config.TTLSampleSize.
+	MetricAvgTTL = "cache_avg_ttl_seconds"
+	// MetricTTLRemaining is a name of a gauge to report quantiles of remaining time to expiration in seconds
+	// of sampled entries, "quantile" label has values "0.5", "0.9" and "0.99".
+	MetricTTLRemaining = "cache_ttl_remaining_seconds"
 
 	// MetricRefreshed is a name of a metric to count stale refresh events.
 	MetricRefreshed = "cache_refreshed"
@@ -22,17 +33,56 @@ const (
 	MetricBuild = "cache_build"
 	// MetricFailed is a name of a metric to count number of failed value builds.
 	MetricFailed = "cache_failed"
+	// MetricSingleFlightDedup is a name of a metric to count reads that waited for an in-flight build
+	// of the same key instead of starting own build.
+	MetricSingleFlightDedup = "cache_singleflight_dedup"
+
+	// MetricWriteBehindFlushed is a name of a metric to count pending writes persisted by WriteBehind.
+	MetricWriteBehindFlushed = "cache_write_behind_flushed"
+	// MetricWriteBehindFailed is a name of a metric to count pending writes of WriteBehind that were lost
+	// because of backend failure.
+	MetricWriteBehindFailed = "cache_write_behind_failed"
 
 	// MetricChanged is a name of a metric to count number of cache builds that changed cached value.
 	MetricChanged = "cache_changed"
 
-	// MetricEvict is a name of metric to count evictions.
+	// MetricEvict is a name of metric to count evictions, "trigger" label has EvictTrigger* value.
 	MetricEvict = "cache_evict"
 
+	// MetricEvictHeap is a name of metric to count evictions triggered by HeapInUseSoftLimit.
+	MetricEvictHeap = "cache_evict_heap"
+
 	// MetricEvictionElapsedSeconds is a name of metric to count eviction job time.
 	MetricEvictionElapsedSeconds = "cache_eviction_elapsed_seconds"
 )
 
+// CacheStats is a point-in-time snapshot of cache activity.
+//
+// Counters are cumulative since cache creation and are maintained regardless of Config.Stats.
+type CacheStats struct {
+	// Hits is a number of valid cache reads.
+	Hits int64 `json:"hits"`
+	// Misses is a number of reads of missing entries.
+	Misses int64 `json:"misses"`
+	// Expired is a number of reads of expired entries and entries expired with ExpireAll.
+	Expired int64 `json:"expired"`
+	// Writes is a number of cache writes.
+	Writes int64 `json:"writes"`
+	// Deletes is a number of deleted entries.
+	Deletes int64 `json:"deletes"`
+	// Evictions is a number of evicted entries.
+	Evictions int64 `json:"evictions"`
+	// Deduplicated is a number of reads that waited for an in-flight build of the same key.
+	Deduplicated int64 `json:"deduplicated"`
+	// Items is a number of entries in cache at the moment, including expired.
+	Items int `json:"items"`
+	// Bytes is an approximate size of stored values, it is only tracked with Config.MaxBytes.
+	Bytes int64 `json:"bytes,omitempty"`
+	// HitRatio is a ratio of valid cache reads to all reads in Config.HitRatioWindow,
+	// reads of expired entries are counted as misses. It is zero if there were no reads.
+	HitRatio float64 `json:"hitRatio"`
+}
+
 // NewStatsTracker creates logger instance from tracking functions.
 func NewStatsTracker(
 	add,
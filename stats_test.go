@@ -1,12 +1,16 @@
 package cache_test
 
 import (
+	"bytes"
 	"context"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/bool64/cache"
 	"github.com/bool64/stats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewStatsTracker(t *testing.T) {
@@ -19,3 +23,166 @@ func TestNewStatsTracker(t *testing.T) {
 
 	assert.Equal(t, map[string]float64{"foo_add{foo=\"bar\"}": 123, "foo_set{foo=\"bar\"}": 123}, m.LabeledValues())
 }
+
+func TestConfig_MetricKeyGroupFunc(t *testing.T) {
+	ctx := context.Background()
+	m := &stats.TrackerMock{}
+
+	cfg := func(cfg *cache.Config) {
+		cfg.Name = "test"
+		cfg.Stats = m
+		cfg.MetricKeyGroupFunc = func(key []byte) string {
+			return string(bytes.SplitN(key, []byte(":"), 2)[0])
+		}
+	}
+
+	for _, c := range []cache.ReadWriter{cache.NewShardedMap(cfg), cache.NewSyncMap(cfg)} {
+		assert.NoError(t, c.Write(ctx, []byte("user:1"), 1))
+
+		_, err := c.Read(ctx, []byte("user:1"))
+		assert.NoError(t, err)
+
+		_, err = c.Read(ctx, []byte("order:1"))
+		assert.Error(t, err)
+	}
+
+	assert.Equal(t, 2, m.Int(cache.MetricHit, "name", "test", "group", "user"))
+	assert.Equal(t, 2, m.Int(cache.MetricMiss, "name", "test", "group", "order"))
+}
+
+func TestConfig_Stats_deleteExpired(t *testing.T) {
+	ctx := context.Background()
+
+	for name, newCache := range map[string]func(cfg func(cfg *cache.Config)) cache.ReadWriter{
+		"sync_map": func(cfg func(cfg *cache.Config)) cache.ReadWriter {
+			return cache.NewSyncMap(cfg)
+		},
+		"sharded_map": func(cfg func(cfg *cache.Config)) cache.ReadWriter {
+			return cache.NewShardedMap(cfg)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := &stats.TrackerMock{}
+			clock := newFakeClock()
+
+			c := newCache(func(cfg *cache.Config) {
+				cfg.Name = "test"
+				cfg.Stats = m
+				cfg.Clock = clock
+				cfg.TimeToLive = time.Minute
+				cfg.ExpirationJitter = -1
+				cfg.DeleteExpiredAfter = time.Minute
+				cfg.DeleteExpiredJobInterval = time.Minute
+				cfg.IntervalJitter = -1
+			})
+
+			for i := 0; i < 10; i++ {
+				require.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+			}
+
+			// Janitor reports deleted expired entries in a single batch.
+			assert.Eventually(t, func() bool {
+				clock.Add(time.Minute)
+
+				return m.Int(cache.MetricDelete, "name", "test") == 10
+			}, time.Second, time.Millisecond)
+		})
+	}
+}
+
+func TestConfig_HitRatioWindow(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	c := cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Hour
+		cfg.HitRatioWindow = 5 * time.Minute
+	})
+
+	assert.Equal(t, 0.0, c.Stats().HitRatio)
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), 1))
+
+	read := func(key string) {
+		_, _ = c.Read(ctx, []byte(key))
+	}
+
+	read("foo")
+	read("foo")
+	read("foo")
+	read("bar")
+	assert.Equal(t, 0.75, c.Stats().HitRatio)
+
+	clock.Add(6 * time.Minute)
+	read("bar")
+	assert.Equal(t, 0.6, c.Stats().HitRatio)
+
+	// Reads before the window are not counted.
+	clock.Add(6 * time.Minute)
+	read("foo")
+	assert.Equal(t, 1.0, c.Stats().HitRatio)
+	assert.Equal(t, int64(4), c.Stats().Hits)
+}
+
+func TestConfig_Stats_hitRatio(t *testing.T) {
+	ctx := context.Background()
+	m := &stats.TrackerMock{}
+	clock := newFakeClock()
+
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Name = "test"
+		cfg.Stats = m
+		cfg.Clock = clock
+		cfg.IntervalJitter = -1
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), 1))
+
+	_, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+
+	_, err = c.Read(ctx, []byte("bar"))
+	require.Error(t, err)
+
+	assert.Eventually(t, func() bool {
+		clock.Add(time.Second)
+
+		return m.Value(cache.MetricHitRatio, "name", "test") == 0.5
+	}, time.Second, time.Millisecond)
+}
+
+func TestConfig_TTLSampleSize(t *testing.T) {
+	for _, newCache := range []func(options ...func(cfg *cache.Config)) cache.ReadWriter{
+		func(options ...func(cfg *cache.Config)) cache.ReadWriter { return cache.NewShardedMap(options...) },
+		func(options ...func(cfg *cache.Config)) cache.ReadWriter { return cache.NewSyncMap(options...) },
+	} {
+		ctx := cache.WithNoJitter(context.Background())
+		m := &stats.TrackerMock{}
+		clock := newFakeClock()
+
+		c := newCache(func(cfg *cache.Config) {
+			cfg.Name = "test"
+			cfg.Stats = m
+			cfg.Clock = clock
+			cfg.IntervalJitter = -1
+			cfg.ItemsCountReportInterval = time.Second
+			cfg.TTLSampleSize = 1000
+		})
+
+		for i := 0; i < 100; i++ {
+			require.NoError(t, c.Write(cache.WithTTL(ctx, time.Duration(i+1)*time.Minute, false), []byte(strconv.Itoa(i)), i))
+		}
+
+		assert.Eventually(t, func() bool {
+			clock.Add(time.Second)
+
+			return m.Value(cache.MetricAvgTTL, "name", "test") > 0
+		}, time.Second, time.Millisecond)
+
+		assert.Equal(t, 50.5*60, m.Value(cache.MetricAvgTTL, "name", "test"))
+		assert.InDelta(t, 50*60, m.Value(cache.MetricTTLRemaining, "name", "test", "quantile", "0.5"), 60)
+		assert.InDelta(t, 90*60, m.Value(cache.MetricTTLRemaining, "name", "test", "quantile", "0.9"), 60)
+		assert.InDelta(t, 99*60, m.Value(cache.MetricTTLRemaining, "name", "test", "quantile", "0.99"), 60)
+	}
+}
@@ -7,15 +7,20 @@ import (
 	"io"
 	"runtime"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/cespare/xxhash/v2"
 )
 
 var (
-	_ ReadWriter = &syncMap{}
-	_ Deleter    = &syncMap{}
-	_ Walker     = &syncMap{}
+	_ ReadWriter       = &syncMap{}
+	_ Deleter          = &syncMap{}
+	_ Walker           = &syncMap{}
+	_ WalkDumpRestorer = &SyncMap{}
+	_ io.Closer        = &SyncMap{}
 )
 
 // SyncMap is an in-memory cache backend. Please use NewSyncMap to create it.
@@ -23,151 +28,1404 @@ type SyncMap struct {
 	*syncMap
 }
 
+// keyLocks is a number of mutexes to serialize modifications of the same key.
+const keyLocks = 64
+
 type syncMap struct {
 	*InvalidationIndex
 
-	data sync.Map
+	// data holds *sync.Map of entries, it is swapped by ReplaceAll.
+	data atomic.Value
+	cnt  int64
+
+	// locks serialize modifications by key to keep cnt consistent, reads are not locked.
+	locks [keyLocks]sync.Mutex
+
+	t *Trait
+
+	flights flightGroup
+}
+
+// NewSyncMap creates an instance of in-memory cache with optional configuration.
+func NewSyncMap(options ...func(cfg *Config)) *SyncMap {
+	c := &syncMap{}
+	c.data.Store(&sync.Map{})
+	C := &SyncMap{
+		syncMap: c,
+	}
+
+	cfg := Config{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	evict := c.evictMostExpired
+
+	if cfg.EvictionStrategy != EvictMostExpired {
+		evict = c.evictLeastCounter
+	}
+
+	c.t = NewTrait(cfg, func(t *Trait) {
+		t.DeleteExpired = c.deleteExpired
+		t.deleteExpiredKeys = c.deleteExpiredKeys
+		t.sampleExpirations = c.sampleExpirations
+		t.Len = c.Len
+		t.Evict = evict
+		t.DecayCounters = c.decayCounters
+	})
+
+	c.InvalidationIndex = NewInvalidationIndex(c)
+
+	runtime.SetFinalizer(C, func(m *SyncMap) {
+		m.t.Close()
+	})
+
+	return C
+}
+
+// Close stops background jobs of cache, it is safe to call it multiple times.
+//
+// Cache remains operational after Close, but expired entries are not deleted and eviction does not happen.
+func (c *SyncMap) Close() error {
+	runtime.SetFinalizer(c, nil)
+	c.t.Close()
+
+	return nil
+}
+
+// Read gets value.
+func (c *syncMap) Read(ctx context.Context, key []byte) (interface{}, error) {
+	if SkipRead(ctx) {
+		return nil, ErrNotFound
+	}
+
+	key = c.t.key(ctx, key)
+
+	if cacheEntry, found := c.dataMap().Load(string(key)); found {
+		e := cacheEntry.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+
+		if isMiss(e) {
+			return c.readMiss(ctx, e)
+		}
+
+		return c.t.PrepareRead(ctx, e, true)
+	}
+
+	return c.t.prepareRead(ctx, key, nil, false)
+}
+
+// missMarker is a value of cached miss.
+type missMarker bool
+
+// isMiss checks if entry is a cached miss, such entries are not exposed as values.
+func isMiss(e *TraitEntry) bool {
+	_, ok := e.V.(missMarker)
+
+	return ok
+}
+
+// WriteMiss stores a marker of missing value by the key, marker expires after Config.MissTTL.
+//
+// Read of such key fails with ErrCachedMiss until marker expires.
+func (c *syncMap) WriteMiss(ctx context.Context, key []byte) error {
+	if c.t.Config.MissTTL != 0 {
+		ctx = WithTTL(ctx, c.t.Config.MissTTL, false)
+	}
+
+	return c.Write(ctx, key, missMarker(true))
+}
+
+func (c *syncMap) readMiss(ctx context.Context, e *TraitEntry) (interface{}, error) {
+	// Expired marker is reported as a regular miss, it must not be served as a stale value.
+	_, err := c.t.prepareRead(ctx, e.K, nil, false)
+
+	if e.E != 0 && e.E < ts(c.t.now()) {
+		return nil, err
+	}
+
+	return nil, ErrCachedMiss
+}
+
+// Peek gets value and its expiration time without affecting stats, logs and usage counters.
+//
+// Expired entry is returned as ErrWithExpiredItem error, cached miss is returned as ErrCachedMiss.
+func (c *syncMap) Peek(ctx context.Context, key []byte) (interface{}, time.Time, error) {
+	v, found := c.dataMap().Load(string(c.t.key(ctx, key)))
+	if !found {
+		return nil, time.Time{}, ErrNotFound
+	}
+
+	cacheEntry := v.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+	expireAt := atomic.LoadInt64(&cacheEntry.E)
+
+	expired := expireAt != 0 && expireAt < ts(c.t.now())
+
+	if isMiss(cacheEntry) {
+		if expired {
+			return nil, time.Time{}, ErrNotFound
+		}
+
+		return nil, tsTime(expireAt), ErrCachedMiss
+	}
+
+	if expired {
+		return nil, tsTime(expireAt), errExpired{entry: cacheEntry}
+	}
+
+	return cacheEntry.V, tsTime(expireAt), nil
+}
+
+// ReadMulti gets values of multiple keys.
+//
+// Only found and not expired values are returned in a map with string keys,
+// missing values do not cause an error.
+func (c *syncMap) ReadMulti(ctx context.Context, keys [][]byte) (map[string]interface{}, error) {
+	res := make(map[string]interface{}, len(keys))
+
+	if SkipRead(ctx) {
+		return res, nil
+	}
+
+	for _, key := range keys {
+		var (
+			v   interface{}
+			err error
+		)
+
+		k := c.t.key(ctx, key)
+
+		if cacheEntry, found := c.dataMap().Load(string(k)); found {
+			e := cacheEntry.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+
+			if isMiss(e) {
+				v, err = c.readMiss(ctx, e)
+			} else {
+				v, err = softExpiredHit(c.t.PrepareRead(ctx, e, true))
+			}
+		} else {
+			v, err = c.t.prepareRead(ctx, k, nil, false)
+		}
+
+		if err == nil {
+			res[string(key)] = v
+		}
+	}
+
+	return res, nil
+}
+
+// KeyValue is a pair of cache key and value.
+type KeyValue struct {
+	Key   []byte
+	Value interface{}
+}
+
+// WriteMulti sets multiple values by their keys.
+func (c *syncMap) WriteMulti(ctx context.Context, items []KeyValue) error {
+	for _, item := range items {
+		if err := c.Write(ctx, item.Key, item.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write sets value by the key.
+func (c *syncMap) Write(ctx context.Context, k []byte, v interface{}) error {
+	ttl, expireAt := c.t.expireAt(ctx)
+
+	return c.write(ctx, k, v, ttl, expireAt)
+}
+
+// WriteRead sets value by the key and returns stored value.
+//
+// It saves a subsequent Read that could observe a value of a concurrent writer.
+func (c *syncMap) WriteRead(ctx context.Context, k []byte, v interface{}) (interface{}, error) {
+	if err := c.Write(ctx, k, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// WriteWithTTL sets value by the key with explicit time to live instead of context TTL.
+//
+// ExpirationJitter is applied to ttl unless context has WithNoJitter, UnlimitedTTL stores entry that never expires.
+func (c *syncMap) WriteWithTTL(ctx context.Context, k []byte, v interface{}, ttl time.Duration) error {
+	ttl, expireAt := c.t.expireAtTTL(ctx, ttl)
+
+	return c.write(ctx, k, v, ttl, expireAt)
+}
+
+func (c *syncMap) write(ctx context.Context, k []byte, v interface{}, ttl time.Duration, expireAt int64) error {
+	_, err := c.writeIf(ctx, k, v, ttl, expireAt, nil)
+
+	return err
+}
+
+// WriteIfAbsent sets value by the key only if there is no valid entry with the same key.
+//
+// It reports whether the value was stored. Expired entries and cached misses are considered absent
+// and are overwritten.
+func (c *syncMap) WriteIfAbsent(ctx context.Context, k []byte, v interface{}) (bool, error) {
+	ttl, expireAt := c.t.expireAt(ctx)
+
+	return c.writeIf(ctx, k, v, ttl, expireAt, func(prev *TraitEntry) bool {
+		if isMiss(prev) {
+			return true
+		}
+
+		e := atomic.LoadInt64(&prev.E)
+
+		return e != 0 && e < ts(c.t.now())
+	})
+}
+
+// Preload stores entries with a shared time to live and returns number of stored entries.
+//
+// It is a faster alternative to Write for seeding cache, for example on startup from a database:
+// expiration is calculated once, write metric is reported once for the batch and entries are not logged.
+// Expiration of entries is ignored, ttl is applied instead, DefaultTTL uses Config.TimeToLive and UnlimitedTTL
+// stores entries that never expire. Values rejected by Config.MaxValueSize are skipped.
+//
+// If cache exceeds Config.CountHardLimit and can not evict, preloaded entries are removed and ErrCacheFull is returned.
+// Preload stops with context error if context is canceled, entries stored so far are kept.
+func (c *syncMap) Preload(ctx context.Context, entries []Entry, ttl time.Duration) (int, error) {
+	if SkipWrite(ctx) {
+		return 0, nil
+	}
+
+	ttl, expireAt := c.t.expireAtTTL(ctx, ttl)
+	now := ts(c.t.now())
+	grace := int64(GracePeriod(ctx))
+	stored := make([]*TraitEntry, 0, len(entries))
+
+	var err error
+
+	for i, pe := range entries {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+		}
+
+		k, keyErr := c.t.writeKey(ctx, pe.Key())
+		if keyErr != nil {
+			continue
+		}
+
+		v := pe.Value()
+
+		if c.t.prepareWrite(ctx, k, v) != nil {
+			continue
+		}
+
+		// Copy key to allow mutations of original entry.
+		key := make([]byte, len(k))
+		copy(key, k)
+
+		e := &TraitEntry{
+			V: v, K: key, E: expireAt, C: c.t.counter(),
+			G: grace, S: c.t.entrySize(v), N: c.t.Config.Generation, T: now,
+		}
+
+		c.storeIf(string(key), e, nil)
+
+		stored = append(stored, e)
+	}
+
+	if fitErr := c.t.fitHardLimit(ctx, func() {
+		for _, e := range stored {
+			e := e
+			c.removeIf(string(e.K), func(ce *TraitEntry) bool { return ce == e })
+		}
+	}); fitErr != nil {
+		return 0, fitErr
+	}
+
+	c.t.notifyPreloaded(ctx, len(stored), ttl)
+
+	return len(stored), err
+}
+
+// ReplaceAll atomically replaces all entries with new ones and returns number of stored entries.
+//
+// New entries are prepared aside and swapped at once, so that readers observe either old or new complete set
+// without a window of misses that DeleteAll followed by writes would have. Entries share expiration
+// of context TTL, values rejected by Config.MaxValueSize are skipped. Writes that happen concurrently
+// with preparation are discarded by the swap.
+//
+// Cache is not changed if context is canceled before the swap or if new entries exceed Config.CountHardLimit
+// and can not be evicted.
+func (c *syncMap) ReplaceAll(ctx context.Context, entries []Entry) (int, error) {
+	start := time.Now()
+	_, expireAt := c.t.expireAt(ctx)
+	now := ts(c.t.now())
+	grace := int64(GracePeriod(ctx))
+
+	data := &sync.Map{}
+	cnt := 0
+	size := int64(0)
+
+	for i, pe := range entries {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+		}
+
+		k, keyErr := c.t.writeKey(ctx, pe.Key())
+		if keyErr != nil {
+			continue
+		}
+
+		v := pe.Value()
+
+		if c.t.prepareWrite(ctx, k, v) != nil {
+			continue
+		}
+
+		// Copy key to allow mutations of original entry.
+		key := make([]byte, len(k))
+		copy(key, k)
+
+		e := &TraitEntry{
+			V: v, K: key, E: expireAt, C: c.t.counter(),
+			G: grace, S: c.t.entrySize(v), N: c.t.Config.Generation, T: now,
+		}
+
+		// Last entry of the same key wins.
+		if prev, loaded := data.LoadOrStore(string(key), e); loaded {
+			size -= prev.(*TraitEntry).S //nolint // Panic on type assertion failure is fine here.
+
+			data.Store(string(key), e)
+		} else {
+			cnt++
+		}
+
+		size += e.S
+	}
+
+	if limit := int(c.t.Config.CountHardLimit); limit > 0 && cnt > limit && (c.t.Config.RejectOnFull || c.t.Evict == nil) {
+		return 0, ErrCacheFull
+	}
+
+	// Holding all key locks so that concurrent modifications do not affect count and size of new data.
+	for i := range c.locks {
+		c.locks[i].Lock()
+	}
+
+	old := c.dataMap()
+	c.data.Store(data)
+	atomic.StoreInt64(&c.cnt, int64(cnt))
+	c.t.addBytes(size - atomic.LoadInt64(c.t.bytes))
+
+	for i := range c.locks {
+		c.locks[i].Unlock()
+	}
+
+	if c.t.expIndex != nil {
+		data.Range(func(key, value interface{}) bool {
+			e := value.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+
+			c.t.indexExpiration(key.(string), e.E, e.G) //nolint // Panic on type assertion failure is fine here.
+
+			return true
+		})
+	}
+
+	// Entries that are absent in new data are reported as deleted.
+	if c.t.Config.OnEvicted != nil {
+		old.Range(func(key, value interface{}) bool {
+			if _, found := data.Load(key); !found {
+				e := value.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+				c.t.notifyRemoved(e.K, e.V, EvictReasonDeleted)
+			}
+
+			return true
+		})
+	}
+
+	if err := c.t.fitHardLimit(ctx, func() {}); err != nil {
+		return cnt, err
+	}
+
+	c.t.notifyReplacedAll(ctx, start, cnt)
+
+	return cnt, nil
+}
+
+// Update atomically replaces value by the key with a result of fn.
+//
+// Function receives current valid value, found is false for missing or expired entries.
+// If fn returns ErrNoChange entry is left untouched and nil error is returned, other errors are returned as is.
+// Concurrent updates and writes of the same key wait for fn to complete.
+func (c *syncMap) Update(
+	ctx context.Context,
+	k []byte,
+	fn func(old interface{}, found bool) (interface{}, error),
+) error {
+	if c.t.skipWrite(ctx, k) {
+		return nil
+	}
+
+	ttl, expireAt := c.t.expireAt(ctx)
+
+	k, err := c.t.writeKey(ctx, k)
+	if err != nil {
+		return err
+	}
+
+	l := c.keyLock(string(k))
+	l.Lock()
+
+	var (
+		old   interface{}
+		found bool
+	)
+
+	if v, loaded := c.dataMap().Load(string(k)); loaded {
+		e := v.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+		exp := atomic.LoadInt64(&e.E)
+
+		if !isMiss(e) && (exp == 0 || exp >= ts(c.t.now())) {
+			old, found = e.V, true
+		}
+	}
+
+	v, err := fn(old, found)
+	if err == nil {
+		err = c.t.prepareWrite(ctx, k, v)
+	}
+
+	if err != nil {
+		l.Unlock()
+
+		if errors.Is(err, ErrNoChange) {
+			return nil
+		}
+
+		return err
+	}
+
+	// Copy key to allow mutations of original argument.
+	key := make([]byte, len(k))
+	copy(key, k)
+
+	e := &TraitEntry{
+		V: v, K: key, E: expireAt, C: c.t.counter(),
+		G: int64(GracePeriod(ctx)), S: c.t.entrySize(v), N: c.t.Config.Generation, T: ts(c.t.now()),
+	}
+
+	prev, loaded := c.dataMap().Load(string(k))
+	if loaded {
+		c.t.addBytes(e.S - prev.(*TraitEntry).S) //nolint // Panic on type assertion failure is fine here.
+	} else {
+		atomic.AddInt64(&c.cnt, 1)
+		c.t.addBytes(e.S)
+	}
+
+	c.dataMap().Store(string(k), e)
+	l.Unlock()
+
+	c.t.indexExpiration(string(k), e.E, e.G)
+
+	if !loaded {
+		if err := c.t.fitHardLimit(ctx, func() {
+			c.removeIf(string(k), func(ce *TraitEntry) bool { return ce == e })
+		}); err != nil {
+			return err
+		}
+	}
+
+	c.t.NotifyWritten(ctx, key, v, ttl)
+
+	return nil
+}
+
+// Increment atomically adds delta to int64 value by the key and returns the new value.
+//
+// Missing or expired entry is created with delta value and time to live from context,
+// existing entry keeps its expiration. ErrNotCounter is returned if existing value is not int64.
+func (c *syncMap) Increment(ctx context.Context, k []byte, delta int64) (int64, error) {
+	ttl, expireAt := c.t.expireAt(ctx)
+
+	k, err := c.t.writeKey(ctx, k)
+	if err != nil {
+		return 0, err
+	}
+
+	// Copy key to allow mutations of original argument.
+	key := make([]byte, len(k))
+	copy(key, k)
+
+	n := delta
+	e := &TraitEntry{
+		K: key, E: expireAt, C: c.t.counter(),
+		G: int64(GracePeriod(ctx)), N: c.t.Config.Generation, T: ts(c.t.now()),
+	}
+
+	l := c.keyLock(string(k))
+	l.Lock()
+
+	prevSize := int64(0)
+
+	v, loaded := c.dataMap().Load(string(k))
+	if loaded {
+		prev := v.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+		exp := atomic.LoadInt64(&prev.E)
+		prevSize = prev.S
+
+		if !isMiss(prev) && (exp == 0 || exp >= ts(c.t.now())) {
+			cnt, ok := prev.V.(int64)
+			if !ok {
+				l.Unlock()
+
+				return 0, ErrNotCounter
+			}
+
+			n = cnt + delta
+			e.E, e.C, e.G, e.T = exp, atomic.LoadInt64(&prev.C), prev.G, prev.T
+
+			ttl = 0
+			if exp != 0 {
+				ttl = tsTime(exp).Sub(c.t.now())
+			}
+		}
+	} else {
+		atomic.AddInt64(&c.cnt, 1)
+	}
+
+	e.V = n
+	e.S = c.t.entrySize(n)
+	c.t.addBytes(e.S - prevSize)
+
+	c.dataMap().Store(string(k), e)
+	l.Unlock()
+
+	c.t.indexExpiration(string(k), e.E, e.G)
+
+	if !loaded {
+		if err := c.t.fitHardLimit(ctx, func() {
+			c.removeIf(string(k), func(ce *TraitEntry) bool { return ce == e })
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	c.t.NotifyWritten(ctx, key, n, ttl)
+
+	return n, nil
+}
+
+// Decrement atomically subtracts delta from int64 value by the key and returns the new value.
+//
+// It behaves as Increment with negative delta.
+func (c *syncMap) Decrement(ctx context.Context, k []byte, delta int64) (int64, error) {
+	return c.Increment(ctx, k, -delta)
+}
+
+// writeIf stores value if there is no entry with the same key or if optional condition is satisfied
+// by the existing entry.
+func (c *syncMap) writeIf(
+	ctx context.Context,
+	k []byte,
+	v interface{},
+	ttl time.Duration,
+	expireAt int64,
+	cond func(prev *TraitEntry) bool,
+) (bool, error) {
+	if c.t.skipWrite(ctx, k) {
+		return false, nil
+	}
+
+	k, err := c.t.writeKey(ctx, k)
+	if err != nil {
+		return false, err
+	}
+
+	if err = c.t.prepareWrite(ctx, k, v); err != nil {
+		return false, err
+	}
+
+	if c.t.Config.SkipEqualWrites && c.refreshEqual(ctx, string(k), v, expireAt, cond) {
+		return true, nil
+	}
+
+	// Copy key to allow mutations of original argument.
+	key := make([]byte, len(k))
+	copy(key, k)
+
+	e := &TraitEntry{
+		V: v, K: key, E: expireAt, C: c.t.counter(),
+		G: int64(GracePeriod(ctx)), S: c.t.entrySize(v), N: c.t.Config.Generation, T: ts(c.t.now()),
+	}
+
+	stored, added := c.storeIf(string(k), e, cond)
+	if !stored {
+		return false, nil
+	}
+
+	if added {
+		if err := c.t.fitHardLimit(ctx, func() {
+			c.removeIf(string(k), func(ce *TraitEntry) bool { return ce == e })
+		}); err != nil {
+			return false, err
+		}
+	}
+
+	c.t.NotifyWritten(ctx, key, v, ttl)
+
+	return true, nil
+}
+
+// Touch updates expiration time of an existing entry without rewriting the value.
+//
+// It fails with ErrNotFound if entry is missing, expired or is a cached miss (see WriteMiss).
+// DefaultTTL uses time to live from context (see WithTTL) or Config.TimeToLive, UnlimitedTTL makes entry never expire.
+func (c *syncMap) Touch(ctx context.Context, key []byte, ttl time.Duration) error {
+	k := string(c.t.key(ctx, key))
+
+	v, found := c.dataMap().Load(k)
+	if !found {
+		return ErrNotFound
+	}
+
+	cacheEntry := v.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+	if isMiss(cacheEntry) {
+		return ErrNotFound
+	}
+
+	if ttl == DefaultTTL {
+		if ttl = c.t.TTL(ctx); ttl == 0 {
+			ttl = UnlimitedTTL
+		}
+	}
+
+	now := c.t.now()
+	expireAt := int64(0)
+
+	if ttl != UnlimitedTTL {
+		expireAt = ts(now.Add(ttl))
+	}
+
+	for {
+		e := atomic.LoadInt64(&cacheEntry.E)
+		if e != 0 && e < ts(now) {
+			return ErrNotFound
+		}
+
+		if atomic.CompareAndSwapInt64(&cacheEntry.E, e, expireAt) {
+			c.t.indexExpiration(k, expireAt, cacheEntry.G)
+
+			return nil
+		}
+	}
+}
+
+// Rename atomically moves entry to a new key keeping its value and expiration time.
+//
+// It fails with ErrNotFound if entry is missing, expired entry is moved and can still serve stale value.
+// Existing entry of a new key is replaced. Config.OnEvicted is not invoked for the old key.
+func (c *syncMap) Rename(ctx context.Context, oldKey, newKey []byte) error {
+	return c.move(ctx, oldKey, newKey, true)
+}
+
+// Copy atomically duplicates entry to a new key keeping its value and expiration time.
+//
+// It fails with ErrNotFound if entry is missing, existing entry of a new key is replaced.
+func (c *syncMap) Copy(ctx context.Context, src, dst []byte) error {
+	return c.move(ctx, src, dst, false)
+}
+
+func (c *syncMap) move(ctx context.Context, src, dst []byte, remove bool) error {
+	if c.t.skipWrite(ctx, dst) {
+		return nil
+	}
+
+	src = c.t.key(ctx, src)
+
+	dst, err := c.t.writeKey(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	s, d := string(src), string(dst)
+
+	unlock := c.lockKeys(s, d)
+
+	v, found := c.dataMap().Load(s)
+	if !found {
+		unlock()
+
+		return ErrNotFound
+	}
+
+	if s == d {
+		unlock()
+
+		return nil
+	}
+
+	prev := v.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+
+	// Copy key to allow mutations of original argument.
+	key := make([]byte, len(dst))
+	copy(key, dst)
+
+	e := &TraitEntry{
+		V: prev.V, K: key, E: atomic.LoadInt64(&prev.E), C: atomic.LoadInt64(&prev.C),
+		G: prev.G, S: prev.S, N: prev.N, T: prev.T,
+	}
+
+	existing, replaced := c.dataMap().Load(d)
+	if replaced {
+		c.t.addBytes(e.S - existing.(*TraitEntry).S) //nolint // Panic on type assertion failure is fine here.
+	} else {
+		atomic.AddInt64(&c.cnt, 1)
+		c.t.addBytes(e.S)
+	}
+
+	c.dataMap().Store(d, e)
+
+	if remove {
+		c.dataMap().Delete(s)
+		atomic.AddInt64(&c.cnt, -1)
+		c.t.addBytes(-prev.S)
+	}
+
+	unlock()
+
+	c.t.indexExpiration(d, e.E, e.G)
+
+	if remove {
+		c.t.unindexExpiration(s, e.E, e.G)
+	} else if !replaced {
+		if err := c.t.fitHardLimit(ctx, func() {
+			c.removeIf(d, func(ce *TraitEntry) bool { return ce == e })
+		}); err != nil {
+			return err
+		}
+	}
+
+	ttl := UnlimitedTTL
+	if e.E != 0 {
+		ttl = tsTime(e.E).Sub(c.t.now())
+	}
+
+	c.t.NotifyWritten(ctx, key, e.V, ttl)
+
+	if remove {
+		c.t.NotifyDeleted(ctx, src)
+	}
+
+	return nil
+}
+
+// lockKeys locks mutexes of two keys in a consistent order to avoid deadlocks and returns unlock function.
+func (c *syncMap) lockKeys(a, b string) func() {
+	i, j := xxhash.Sum64String(a)%keyLocks, xxhash.Sum64String(b)%keyLocks
+	if i == j {
+		c.locks[i].Lock()
+
+		return c.locks[i].Unlock
+	}
+
+	if i > j {
+		i, j = j, i
+	}
+
+	c.locks[i].Lock()
+	c.locks[j].Lock()
+
+	return func() {
+		c.locks[j].Unlock()
+		c.locks[i].Unlock()
+	}
+}
+
+func (c *syncMap) dataMap() *sync.Map {
+	return c.data.Load().(*sync.Map) //nolint // Panic on type assertion failure is fine here.
+}
+
+func (c *syncMap) keyLock(k string) *sync.Mutex {
+	return &c.locks[xxhash.Sum64String(k)%keyLocks]
+}
+
+// store puts restored entry to the map and maintains items count and size.
+//
+// Entry of a different Config.Generation is skipped and false is returned.
+func (c *syncMap) store(k string, e *TraitEntry) bool {
+	if e.N != c.t.Config.Generation {
+		return false
+	}
+
+	e.S = c.t.entrySize(e.V)
+	c.storeIf(k, e, nil)
+
+	return true
+}
+
+// storeIf puts entry to the map if there is no entry with the same key or if optional condition
+// is satisfied by the existing entry.
+//
+// It reports whether entry was stored and whether it was added as a new one.
+func (c *syncMap) storeIf(k string, e *TraitEntry, cond func(prev *TraitEntry) bool) (stored, added bool) {
+	l := c.keyLock(k)
+	l.Lock()
+	defer l.Unlock()
+
+	v, loaded := c.dataMap().LoadOrStore(k, e)
+	if !loaded {
+		atomic.AddInt64(&c.cnt, 1)
+		c.t.addBytes(e.S)
+		c.t.indexExpiration(k, e.E, e.G)
+
+		return true, true
+	}
+
+	prev := v.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+	if cond != nil && !cond(prev) {
+		return false, false
+	}
+
+	c.dataMap().Store(k, e)
+	c.t.addBytes(e.S - prev.S)
+	c.t.indexExpiration(k, e.E, e.G)
+
+	return true, false
+}
+
+// refreshEqual updates expiration of the existing entry if it has a value equal to v and satisfies optional
+// condition, see Config.SkipEqualWrites.
+func (c *syncMap) refreshEqual(
+	ctx context.Context,
+	k string,
+	v interface{},
+	expireAt int64,
+	cond func(prev *TraitEntry) bool,
+) bool {
+	l := c.keyLock(k)
+	l.Lock()
+	defer l.Unlock()
+
+	pv, found := c.dataMap().Load(k)
+	if !found {
+		return false
+	}
+
+	prev := pv.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+	if (cond != nil && !cond(prev)) || !c.t.equalWrite(ctx, prev.V, v, prev.N, prev.G) {
+		return false
+	}
+
+	atomic.StoreInt64(&prev.E, expireAt)
+	c.t.indexExpiration(k, expireAt, prev.G)
+
+	return true
+}
+
+// removeIf deletes entry from the map if it satisfies optional condition and maintains items count.
+func (c *syncMap) removeIf(k string, cond func(e *TraitEntry) bool) (*TraitEntry, bool) {
+	l := c.keyLock(k)
+	l.Lock()
+	defer l.Unlock()
+
+	v, found := c.dataMap().Load(k)
+	if !found {
+		return nil, false
+	}
+
+	e := v.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+	if cond != nil && !cond(e) {
+		return nil, false
+	}
+
+	c.dataMap().Delete(k)
+	atomic.AddInt64(&c.cnt, -1)
+	c.t.addBytes(-e.S)
+
+	return e, true
+}
+
+// ReadOrWrite reads value by the key or builds and writes it on cache miss.
+//
+// Concurrent calls for the same missing (or expired) key are deduplicated, build is invoked once
+// and its result is shared among the waiting callers. Failed build result is not stored.
+func (c *syncMap) ReadOrWrite(
+	ctx context.Context,
+	key []byte,
+	build func(ctx context.Context) (interface{}, error),
+) (interface{}, error) {
+	// Soft expired value is still valid, it is not rebuilt ahead.
+	v, err := softExpiredHit(c.Read(ctx, key))
+	if err == nil {
+		return v, nil
+	}
+
+	if errors.Is(err, ErrCachedMiss) || (!errors.Is(err, ErrNotFound) && !errors.Is(err, ErrExpired)) {
+		return nil, err
+	}
+
+	// Builds are deduplicated by effective key, so that callers with different key prefixes do not share values.
+	k := string(c.t.key(ctx, key))
+
+	v, shared, err := c.flights.do(k, func() (interface{}, error) {
+		// Checking again in case another build has just finished.
+		if cacheEntry, found := c.dataMap().Load(k); found && !SkipRead(ctx) {
+			e := cacheEntry.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+			if e.E == 0 || e.E > ts(c.t.now()) {
+				if isMiss(e) {
+					return nil, ErrCachedMiss
+				}
+
+				return e.V, nil
+			}
+		}
+
+		val, buildErr := build(ctx)
+		if buildErr != nil {
+			return nil, buildErr
+		}
+
+		if writeErr := c.Write(ctx, key, val); writeErr != nil {
+			return nil, writeErr
+		}
+
+		return val, nil
+	})
+
+	if shared {
+		c.t.notifyDeduplicated(ctx)
+	}
+
+	return v, err
+}
+
+// Delete removes value by the key.
+//
+// It fails with ErrNotFound if key does not exist, unless Config.IgnoreMissingDelete is enabled.
+func (c *syncMap) Delete(ctx context.Context, key []byte) error {
+	key = c.t.key(ctx, key)
+
+	e, found := c.removeIf(string(key), nil)
+	if !found {
+		return c.t.missingDelete()
+	}
+
+	c.t.unindexExpiration(string(key), atomic.LoadInt64(&e.E), e.G)
+
+	c.t.notifyRemoved(e.K, e.V, EvictReasonDeleted)
+	c.t.NotifyDeleted(ctx, key)
+
+	return nil
+}
+
+// ctxCheckInterval is a number of iterations between context checks in long-running loops.
+const ctxCheckInterval = 1000
+
+// ExpireAll marks all entries as expired, they can still serve stale values.
+//
+// Iteration stops if context is canceled.
+func (c *syncMap) ExpireAll(ctx context.Context) {
+	c.ExpireAllCount(ctx)
+}
+
+// ExpireAllCount marks all entries as expired and returns number of expired entries.
+func (c *syncMap) ExpireAllCount(ctx context.Context) int {
+	start := time.Now()
+	cnt := c.expireIf(ctx, nil)
+
+	c.t.NotifyExpiredAll(ctx, start, cnt)
+
+	return cnt
+}
+
+// ExpireMatching marks entries that satisfy predicate as expired and returns number of expired entries.
+//
+// Expired entries can still serve stale values, other entries are not affected.
+// Iteration stops if context is canceled.
+func (c *syncMap) ExpireMatching(ctx context.Context, predicate func(e Entry) bool) int {
+	start := time.Now()
+	cnt := c.expireIf(ctx, predicate)
+
+	c.t.notifyExpiredMatching(ctx, start, cnt)
+
+	return cnt
+}
+
+// expireIf marks entries as expired if optional predicate is satisfied.
+func (c *syncMap) expireIf(ctx context.Context, predicate func(e Entry) bool) int {
+	startTS := ts(c.t.now())
+	cnt := 0
+	i := 0
+
+	c.dataMap().Range(func(key, value interface{}) bool {
+		if i%ctxCheckInterval == 0 && ctx.Err() != nil {
+			return false
+		}
+
+		i++
+
+		cacheEntry := value.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+
+		if predicate != nil && !predicate(cacheEntry) {
+			return true
+		}
+
+		cacheEntry.E = startTS
+		c.t.indexExpiration(key.(string), startTS, cacheEntry.G) //nolint // Panic on type assertion failure is fine here.
+		cnt++
+
+		return true
+	})
+
+	return cnt
+}
+
+// DeleteAll erases all entries.
+//
+// Iteration stops if context is canceled.
+func (c *syncMap) DeleteAll(ctx context.Context) {
+	c.Purge(ctx)
+}
+
+// Purge erases all entries and returns number of deleted entries.
+func (c *syncMap) Purge(ctx context.Context) int {
+	return c.purge(ctx, nil)
+}
+
+// Drain removes all entries and returns them, for example to hand off cache to another process.
+// Cached misses are removed, but not returned.
+//
+// Every entry is removed individually, so entry that is written concurrently is either returned or kept in cache.
+// Iteration stops if context is canceled.
+func (c *syncMap) Drain(ctx context.Context) []Entry {
+	entries := make([]Entry, 0, c.Len())
+
+	c.purge(ctx, func(e *TraitEntry) {
+		entries = append(entries, e)
+	})
+
+	return entries
+}
+
+// purge erases all entries and calls optional collect for every removed entry.
+func (c *syncMap) purge(ctx context.Context, collect func(e *TraitEntry)) int {
+	start := time.Now()
+	cnt := 0
+	i := 0
+
+	c.dataMap().Range(func(key, _ interface{}) bool {
+		if i%ctxCheckInterval == 0 && ctx.Err() != nil {
+			return false
+		}
+
+		i++
+
+		if e, found := c.removeIf(key.(string), nil); found {
+			if collect != nil && !isMiss(e) {
+				collect(e)
+			}
+
+			c.t.notifyRemoved(e.K, e.V, EvictReasonDeleted)
+
+			cnt++
+		}
+
+		return true
+	})
+
+	c.t.NotifyDeletedAll(ctx, start, cnt)
+
+	return cnt
+}
+
+// Keys returns a page of keys in lexicographical order and a cursor for the next page.
+//
+// Keys are returned starting after cursor, empty cursor starts from the beginning.
+// Empty nextCursor is returned on the last page. Limit of zero or less returns all remaining keys.
+//
+// Every call iterates all entries without holding locks, so entries that are added or removed
+// between the calls may be missing from pagination. Memory usage is proportional to limit.
+func (c *syncMap) Keys(ctx context.Context, cursor string, limit int) (keys [][]byte, nextCursor string, err error) {
+	var (
+		page []string
+		more bool
+		i    int
+	)
 
-	t *Trait
-}
+	truncate := func() {
+		sort.Strings(page)
 
-// NewSyncMap creates an instance of in-memory cache with optional configuration.
-func NewSyncMap(options ...func(cfg *Config)) *SyncMap {
-	c := &syncMap{}
-	C := &SyncMap{
-		syncMap: c,
+		if limit > 0 && len(page) > limit {
+			page = page[:limit]
+			more = true
+		}
 	}
 
-	cfg := Config{}
-	for _, option := range options {
-		option(&cfg)
-	}
+	c.dataMap().Range(func(key, value interface{}) bool {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return false
+			}
+		}
 
-	evict := c.evictMostExpired
+		i++
 
-	if cfg.EvictionStrategy != EvictMostExpired {
-		evict = c.evictLeastCounter
-	}
+		k := key.(string) //nolint // Panic on type assertion failure is fine here.
+		if k <= cursor || isMiss(value.(*TraitEntry)) {
+			return true
+		}
 
-	c.t = NewTrait(cfg, func(t *Trait) {
-		t.DeleteExpired = c.deleteExpired
-		t.Len = c.Len
-		t.Evict = evict
-	})
+		page = append(page, k)
 
-	c.InvalidationIndex = NewInvalidationIndex(c)
+		if limit > 0 && len(page) >= 2*limit {
+			truncate()
+		}
 
-	runtime.SetFinalizer(C, func(m *SyncMap) {
-		close(m.t.Closed)
+		return true
 	})
 
-	return C
-}
+	if err != nil {
+		return nil, "", err
+	}
 
-// Read gets value.
-func (c *syncMap) Read(ctx context.Context, key []byte) (interface{}, error) {
-	if SkipRead(ctx) {
-		return nil, ErrNotFound
+	truncate()
+
+	keys = make([][]byte, len(page))
+	for i, k := range page {
+		keys[i] = []byte(k)
 	}
 
-	if cacheEntry, found := c.data.Load(string(key)); found {
-		return c.t.PrepareRead(ctx, cacheEntry.(*TraitEntry), true)
+	if more {
+		nextCursor = page[len(page)-1]
 	}
 
-	return c.t.PrepareRead(ctx, nil, false)
+	return keys, nextCursor, nil
 }
 
-// Write sets value by the key.
-func (c *syncMap) Write(ctx context.Context, k []byte, v interface{}) error {
-	// Copy key to allow mutations of original argument.
-	key := make([]byte, len(k))
-	copy(key, k)
+// DeletePrefix removes entries with keys that have prefix and returns number of deleted entries.
+//
+// Iteration stops with context error if context is canceled.
+func (c *syncMap) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	start := time.Now()
+	cnt := 0
+	i := 0
+	p := string(prefix)
 
-	ttl, expireAt := c.t.expireAt(ctx)
+	var err error
 
-	c.data.Store(string(k), &TraitEntry{V: v, K: key, E: expireAt})
-	c.t.NotifyWritten(ctx, key, v, ttl)
+	c.dataMap().Range(func(key, _ interface{}) bool {
+		if i%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return false
+			}
+		}
 
-	return nil
-}
+		i++
 
-// Delete removes values by the key.
-func (c *syncMap) Delete(ctx context.Context, key []byte) error {
-	c.data.Delete(string(key))
+		k := key.(string) //nolint // Panic on type assertion failure is fine here.
+		if !strings.HasPrefix(k, p) {
+			return true
+		}
 
-	c.t.NotifyDeleted(ctx, key)
+		if e, found := c.removeIf(k, nil); found {
+			c.t.notifyRemoved(e.K, e.V, EvictReasonDeleted)
 
-	return nil
+			cnt++
+		}
+
+		return true
+	})
+
+	c.t.NotifyDeletedPrefix(ctx, start, prefix, cnt)
+
+	return cnt, err
 }
 
-// ExpireAll marks all entries as expired, they can still serve stale values.
-func (c *syncMap) ExpireAll(ctx context.Context) {
-	start := time.Now()
-	startTS := ts(start)
+func (c *syncMap) deleteExpired(before time.Time) {
+	beforeTS := ts(before)
+
 	cnt := 0
 
-	c.data.Range(func(key, value interface{}) bool {
+	c.dataMap().Range(func(key, value interface{}) bool {
 		cacheEntry := value.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+		if c.t.deleteExpiredBefore(atomic.LoadInt64(&cacheEntry.E), cacheEntry.G, beforeTS) &&
+			!c.t.pinnedExpired(cacheEntry) {
+			if e, found := c.removeIf(key.(string), c.expiredEntry(cacheEntry, beforeTS)); found {
+				cnt++
 
-		cacheEntry.E = startTS
-		cnt++
+				c.t.notifyRemoved(e.K, e.V, EvictReasonExpired)
+			}
+		}
 
 		return true
 	})
 
-	c.t.NotifyExpiredAll(ctx, start, cnt)
+	// Trait is not accessed for empty cache, as it may be not yet assigned when job starts.
+	if cnt > 0 {
+		c.t.notifyDeletedExpired(cnt)
+	}
 }
 
-// DeleteAll erases all entries.
-func (c *syncMap) DeleteAll(ctx context.Context) {
-	start := time.Now()
+// deleteExpiredKeys deletes expired entries of keys from expiration index and returns number of deleted entries.
+//
+// Entries that are not yet expired, for example updated after indexing, are indexed again.
+func (c *syncMap) deleteExpiredKeys(keys []string, beforeTS int64) int {
 	cnt := 0
 
-	c.data.Range(func(key, _ interface{}) bool {
-		c.data.Delete(key)
-		cnt++
+	for _, k := range keys {
+		v, found := c.dataMap().Load(k)
+		if !found {
+			continue
+		}
 
-		return true
-	})
+		cacheEntry := v.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+		if c.t.deleteExpiredBefore(atomic.LoadInt64(&cacheEntry.E), cacheEntry.G, beforeTS) &&
+			!c.t.pinnedExpired(cacheEntry) {
+			if e, found := c.removeIf(k, c.expiredEntry(cacheEntry, beforeTS)); found {
+				cnt++
 
-	c.t.NotifyDeletedAll(ctx, start, cnt)
-}
+				c.t.notifyRemoved(e.K, e.V, EvictReasonExpired)
 
-func (c *syncMap) deleteExpired(before time.Time) {
-	beforeTS := ts(before)
+				continue
+			}
+		}
 
-	c.data.Range(func(key, value interface{}) bool {
-		cacheEntry := value.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
-		if cacheEntry.E < beforeTS {
-			c.data.Delete(key)
+		if v, found := c.dataMap().Load(k); found {
+			e := v.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+			c.t.indexExpiration(k, atomic.LoadInt64(&e.E), e.G)
 		}
+	}
+
+	return cnt
+}
+
+// expiredEntry returns removeIf condition for expired entry that was checked with Config.CanEvict.
+//
+// Config.CanEvict is called outside of key lock, so that it can access cache, entry that was replaced
+// or touched meanwhile is kept.
+func (c *syncMap) expiredEntry(checked *TraitEntry, beforeTS int64) func(e *TraitEntry) bool {
+	return func(e *TraitEntry) bool {
+		return e == checked && c.t.deleteExpiredBefore(atomic.LoadInt64(&e.E), e.G, beforeTS)
+	}
+}
+
+// sampleExpirations calls fn for expiration and creation timestamps of up to n entries.
+func (c *syncMap) sampleExpirations(n int, fn func(expireAt, createdAt int64)) {
+	i := 0
+
+	c.dataMap().Range(func(key, value interface{}) bool {
+		e := value.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+		fn(atomic.LoadInt64(&e.E), e.T)
+
+		i++
+
+		return i < n
+	})
+}
+
+func (c *syncMap) decayCounters() {
+	c.dataMap().Range(func(key, value interface{}) bool {
+		halveCounter(&value.(*TraitEntry).C) //nolint // Panic on type assertion failure is fine here.
 
 		return true
 	})
 }
 
+// Stats returns a snapshot of cache activity counters.
+func (c *syncMap) Stats() CacheStats {
+	return c.t.Stats()
+}
+
 // Len returns number of elements including expired.
 func (c *syncMap) Len() int {
-	cnt := 0
+	return int(atomic.LoadInt64(&c.cnt))
+}
 
-	c.data.Range(func(key, value interface{}) bool {
-		cnt++
+// Walk walks cached entries, cached misses are skipped.
+func (c *syncMap) Walk(walkFn func(e Entry) error) (int, error) {
+	return c.WalkContext(bgCtx, walkFn)
+}
+
+// WalkContext walks cached entries and stops with context error if context is canceled.
+func (c *syncMap) WalkContext(ctx context.Context, walkFn func(e Entry) error) (int, error) {
+	n := 0
+
+	var lastErr error
+
+	c.dataMap().Range(func(key, value interface{}) bool {
+		if n%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				lastErr = err
+
+				return false
+			}
+		}
+
+		e := value.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+		if isMiss(e) {
+			return true
+		}
+
+		err := walkFn(e)
+		if err != nil {
+			lastErr = err
+
+			return false
+		}
+
+		n++
 
 		return true
 	})
 
-	return cnt
+	return n, lastErr
 }
 
-// Walk walks cached entries.
-func (c *syncMap) Walk(walkFn func(e Entry) error) (int, error) {
+// WalkSnapshot walks entries that were in cache at the beginning of the call and returns a number of processed entries.
+//
+// Unlike Walk, it first collects references to entries in a brief pass and then calls walkFn for them,
+// so that the walked set is not affected by entries written or deleted during a slow walk.
+// It needs memory for references to all entries (8 bytes per entry on 64-bit platforms).
+func (c *syncMap) WalkSnapshot(walkFn func(e Entry) error) (int, error) {
+	entries := make([]*TraitEntry, 0, c.Len())
+
+	c.dataMap().Range(func(_, value interface{}) bool {
+		if e := value.(*TraitEntry); !isMiss(e) { //nolint // Panic on type assertion failure is fine here.
+			entries = append(entries, e)
+		}
+
+		return true
+	})
+
+	for i, e := range entries {
+		if err := walkFn(e); err != nil {
+			return i, err
+		}
+	}
+
+	return len(entries), nil
+}
+
+// WalkExpired walks expired entries that are not yet deleted and returns a number of processed entries.
+//
+// It may be useful to inspect a backlog of background cleanup job.
+func (c *syncMap) WalkExpired(walkFn func(e Entry) error) (int, error) {
 	n := 0
+	now := ts(c.t.now())
 
 	var lastErr error
 
-	c.data.Range(func(key, value interface{}) bool {
-		err := walkFn(value.(*TraitEntry))
-		if err != nil {
+	c.dataMap().Range(func(key, value interface{}) bool {
+		e := value.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+		if e.E == 0 || e.E >= now || isMiss(e) {
+			return true
+		}
+
+		if err := walkFn(e); err != nil {
 			lastErr = err
 
 			return false
@@ -181,15 +1439,165 @@ func (c *syncMap) Walk(walkFn func(e Entry) error) (int, error) {
 	return n, lastErr
 }
 
+// WalkSorted walks cached entries in order defined by less, entries are ordered by key if less is nil.
+//
+// Entries are collected and sorted before walking to have a deterministic order, for example for snapshot tests,
+// so it needs memory for references to all entries.
+func (c *syncMap) WalkSorted(less func(a, b Entry) bool, walkFn func(e Entry) error) (int, error) {
+	return walkSorted(c.Walk, less, walkFn)
+}
+
+// Oldest returns a snapshot of entry with the earliest creation time, false is returned if cache is empty.
+//
+// It scans all entries, entries without creation time (restored from dumps of older versions) are skipped.
+func (c *syncMap) Oldest() (Entry, bool) {
+	return c.extremeEntry(func(created, found int64) bool { return created < found })
+}
+
+// Newest returns a snapshot of entry with the latest creation time, false is returned if cache is empty.
+//
+// It scans all entries, entries without creation time (restored from dumps of older versions) are skipped.
+func (c *syncMap) Newest() (Entry, bool) {
+	return c.extremeEntry(func(created, found int64) bool { return created > found })
+}
+
+// extremeEntry finds entry which creation time is preferred by better.
+func (c *syncMap) extremeEntry(better func(created, found int64) bool) (Entry, bool) {
+	var found *TraitEntry
+
+	c.dataMap().Range(func(key, value interface{}) bool {
+		e := value.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
+
+		if e.T != 0 && !isMiss(e) && (found == nil || better(e.T, found.T)) {
+			found = e
+		}
+
+		return true
+	})
+
+	if found == nil {
+		return nil, false
+	}
+
+	return TraitEntry{
+		K: found.K,
+		V: found.V,
+		E: atomic.LoadInt64(&found.E),
+		G: found.G,
+		N: found.N,
+		T: found.T,
+	}, true
+}
+
+// walkSorted collects entries with walk and calls walkFn for them in order defined by less.
+func walkSorted(
+	walk func(walkFn func(e Entry) error) (int, error),
+	less func(a, b Entry) bool,
+	walkFn func(e Entry) error,
+) (int, error) {
+	var entries []Entry
+
+	if _, err := walk(func(e Entry) error {
+		entries = append(entries, e)
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	if less == nil {
+		less = func(a, b Entry) bool {
+			return string(a.Key()) < string(b.Key())
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(entries[i], entries[j])
+	})
+
+	for i, e := range entries {
+		if err := walkFn(e); err != nil {
+			return i, err
+		}
+	}
+
+	return len(entries), nil
+}
+
 // Dump saves cached entries and returns a number of processed entries.
 //
 // Dump uses encoding/gob to serialize cache entries, therefore it is necessary to
 // register cached types in advance with GobRegister.
+// Custom serialization can be configured with Config.Serializer.
+//
+// Nil values are dumped, but entries with typed nil pointer values (for example (*T)(nil)) are skipped
+// and not counted, because encoding/gob can not encode them.
 func (c *SyncMap) Dump(w io.Writer) (int, error) {
-	encoder := gob.NewEncoder(w)
+	return c.DumpContext(bgCtx, w)
+}
+
+// DumpWithOptions saves cached entries with encoding options and returns a number of processed entries.
+func (c *SyncMap) DumpWithOptions(w io.Writer, opts DumpOptions) (int, error) {
+	return c.dump(bgCtx, w, opts)
+}
+
+// DumpContext saves cached entries and returns a number of processed entries.
+//
+// Dump stops with context error if context is canceled.
+func (c *SyncMap) DumpContext(ctx context.Context, w io.Writer) (int, error) {
+	return c.dump(ctx, w, DumpOptions{})
+}
+
+func (c *SyncMap) dump(ctx context.Context, w io.Writer, opts DumpOptions) (int, error) {
+	return encodeDump(w, opts, c.t.Config.Serializer, func(encoder entryEncoder) (int, error) {
+		if opts.Sorted {
+			return walkSorted(func(walkFn func(e Entry) error) (int, error) {
+				return c.WalkContext(ctx, walkFn)
+			}, nil, func(e Entry) error {
+				return encoder.Encode(e)
+			})
+		}
 
-	return c.Walk(func(e Entry) error {
-		return encoder.Encode(e)
+		if opts.Snapshot {
+			n := 0
+
+			return c.WalkSnapshot(func(e Entry) error {
+				if n%ctxCheckInterval == 0 {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+				}
+
+				n++
+
+				return encoder.Encode(e)
+			})
+		}
+
+		return c.WalkContext(ctx, func(e Entry) error {
+			return encoder.Encode(e)
+		})
+	})
+}
+
+// DumpLive saves cached entries that are not expired and returns a number of saved entries.
+func (c *SyncMap) DumpLive(w io.Writer) (int, error) {
+	now := ts(c.t.now())
+
+	return encodeDump(w, DumpOptions{}, c.t.Config.Serializer, func(encoder entryEncoder) (int, error) {
+		n := 0
+
+		_, err := c.Walk(func(e Entry) error {
+			if exp := e.(*TraitEntry).E; exp != 0 && exp < now { //nolint // Panic on type assertion failure is fine here.
+				return nil
+			}
+
+			n++
+
+			return encoder.Encode(e)
+		})
+
+		return n, err
 	})
 }
 
@@ -197,7 +1605,55 @@ func (c *SyncMap) Dump(w io.Writer) (int, error) {
 //
 // Restore uses encoding/gob to unserialize cache entries, therefore it is necessary to
 // register cached types in advance with GobRegister.
+// Custom serialization can be configured with Config.Serializer.
 func (c *SyncMap) Restore(r io.Reader) (int, error) {
+	return c.RestoreContext(bgCtx, r)
+}
+
+// RestoreContext loads cached entries and returns number of processed entries.
+//
+// Dump is fully decoded and validated before entries are stored, so that cache is not modified
+// if dump is broken. Decoded entries are held in memory, so restore needs memory for a full copy
+// of dumped entries in addition to cache. Restore stops with context error if context is canceled.
+func (c *SyncMap) RestoreContext(ctx context.Context, r io.Reader) (int, error) {
+	var entries []TraitEntry
+
+	err := decodeDump(r, c.t.Config.Serializer, func(decoder entryDecoder) error {
+		if len(entries)%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		var e TraitEntry
+
+		if err := decoder.Decode(&e); err != nil {
+			return err
+		}
+
+		entries = append(entries, e)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+
+	for i := range entries {
+		e := &entries[i]
+
+		if c.store(string(e.K), e) {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// RestoreLegacy loads cached entries from a dump without header and checksum, written by older versions.
+func (c *SyncMap) RestoreLegacy(r io.Reader) (int, error) {
 	var (
 		decoder = gob.NewDecoder(r)
 		e       TraitEntry
@@ -216,9 +1672,39 @@ func (c *SyncMap) Restore(r io.Reader) (int, error) {
 
 		e := e
 
-		c.data.Store(string(e.K), &e)
+		if c.store(string(e.K), &e) {
+			n++
+		}
+	}
 
-		n++
+	return n, nil
+}
+
+// RestoreMerge loads cache entries from io.Reader and merges them with existing entries using strategy.
+//
+// Dump is fully decoded before merge, so that cache is not modified if dump is broken,
+// this needs memory for a full copy of dumped entries. Number of stored entries is returned.
+func (c *SyncMap) RestoreMerge(r io.Reader, strategy MergeStrategy) (int, error) {
+	entries, err := decodeEntries(r, c.t.Config.Serializer)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+
+	for i := range entries {
+		e := &entries[i]
+		if e.N != c.t.Config.Generation {
+			continue
+		}
+
+		e.S = c.t.entrySize(e.V)
+
+		if stored, _ := c.storeIf(string(e.K), e, func(prev *TraitEntry) bool {
+			return strategy.replace(prev, e)
+		}); stored {
+			n++
+		}
 	}
 
 	return n, nil
@@ -237,32 +1723,50 @@ func (c *syncMap) evictLeastCounter(evictFraction float64) int {
 }
 
 func (c *syncMap) evictLeast(evictFraction float64, val func(i *TraitEntry) int64) int {
-	type en struct {
-		key string
-		val int64
-	}
+	evictItems := c.t.evictCount(c.Len(), evictFraction)
 
-	keysCnt := c.Len()
-	entries := make([]en, 0, keysCnt)
+	minCreated := c.t.evictMinCreated()
 
-	// Collect all keys and expirations.
-	c.data.Range(func(key, value interface{}) bool {
+	// Only candidates for eviction are kept in memory, young entries complete the quota if there are not enough others.
+	entries := make(evictLeastKeys, 0, evictItems)
+
+	var young evictLeastKeys
+
+	// Collect entries with least values.
+	c.dataMap().Range(func(key, value interface{}) bool {
 		i := value.(*TraitEntry) //nolint // Panic on type assertion failure is fine here.
-		entries = append(entries, en{val: val(i), key: string(i.K)})
+		if !c.t.canEvict(i) {
+			return true
+		}
+
+		if i.T > minCreated {
+			young.offer(evictLeastKey{val: val(i), entry: i}, evictItems)
+		} else {
+			entries.offer(evictLeastKey{val: val(i), entry: i}, evictItems)
+		}
 
 		return true
 	})
 
-	// Sort entries to put most expired in head.
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].val < entries[j].val
-	})
+	if r := evictItems - len(entries); r > 0 {
+		least := make(evictLeastKeys, 0, r)
+
+		for _, en := range young {
+			least.offer(en, r)
+		}
 
-	evictItems := int(float64(len(entries)) * evictFraction)
+		entries = append(entries, least...)
+	}
+
+	evicted := 0
 
-	for i := 0; i < evictItems; i++ {
-		c.data.Delete(entries[i].key)
+	for _, en := range entries {
+		if e, found := c.removeIf(string(en.entry.K), nil); found {
+			evicted++
+
+			c.t.evicted(e.K, e.V)
+		}
 	}
 
-	return evictItems
+	return evicted
 }
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+)
+
+// DumpJSON saves cached entries as JSON lines and returns a number of processed entries.
+//
+// Every line is a JSON object with "key", "val" and "exp" (expiration timestamp in ns) properties.
+func (c *SyncMap) DumpJSON(w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	return c.Walk(func(e Entry) error {
+		return enc.Encode(e)
+	})
+}
+
+// RestoreJSON loads cached entries from JSON lines and returns number of processed entries.
+//
+// Values are decoded into generic types of encoding/json, e.g. map[string]interface{} for objects
+// and float64 for numbers, please use RestoreJSONTyped to restore concrete types.
+func (c *SyncMap) RestoreJSON(r io.Reader) (int, error) {
+	return c.RestoreJSONTyped(r, nil)
+}
+
+// RestoreJSONTyped loads cached entries from JSON lines and returns number of processed entries.
+//
+// Function newValue receives entry key and returns a pointer to new instance of value type
+// to decode into, stored value is dereferenced. If newValue is nil or returns nil, generic
+// types of encoding/json are used.
+func (c *SyncMap) RestoreJSONTyped(r io.Reader, newValue func(key []byte) interface{}) (int, error) {
+	var (
+		dec = json.NewDecoder(bufio.NewReader(r))
+		n   = 0
+	)
+
+	for {
+		var line struct {
+			K Key             `json:"key"`
+			V json.RawMessage `json:"val"`
+			E int64           `json:"exp"`
+			G int64           `json:"grace"`
+			N uint64          `json:"gen"`
+			T int64           `json:"created"`
+		}
+
+		err := dec.Decode(&line)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return n, err
+		}
+
+		e := TraitEntry{K: line.K, E: line.E, G: line.G, N: line.N, T: line.T}
+
+		var p interface{}
+
+		if newValue != nil {
+			p = newValue(line.K)
+		}
+
+		if p != nil {
+			if err := json.Unmarshal(line.V, p); err != nil {
+				return n, err
+			}
+
+			e.V = reflect.ValueOf(p).Elem().Interface()
+		} else if err := json.Unmarshal(line.V, &e.V); err != nil {
+			return n, err
+		}
+
+		if c.store(string(e.K), &e) {
+			n++
+		}
+	}
+
+	return n, nil
+}
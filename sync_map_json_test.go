@@ -0,0 +1,85 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncMap_DumpJSON(t *testing.T) {
+	type dog struct {
+		Name string `json:"name"`
+	}
+
+	ctx := context.Background()
+	c1 := cache.NewSyncMap()
+
+	require.NoError(t, c1.Write(ctx, []byte("foo"), dog{Name: "Snoopy"}))
+	require.NoError(t, c1.Write(cache.WithTTL(ctx, time.Hour, false), []byte("bar"), dog{Name: "Hachiko"}))
+
+	w := bytes.NewBuffer(nil)
+
+	n, err := c1.DumpJSON(w)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Contains(t, w.String(), `{"key":"foo","val":{"name":"Snoopy"},"exp":`)
+
+	dump := w.String()
+
+	c2 := cache.NewSyncMap()
+	n, err = c2.RestoreJSON(bytes.NewBufferString(dump))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	v, err := c2.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Snoopy"}, v)
+
+	c3 := cache.NewSyncMap()
+	n, err = c3.RestoreJSONTyped(bytes.NewBufferString(dump), func(key []byte) interface{} {
+		return new(dog)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	v, err = c3.Read(ctx, []byte("bar"))
+	require.NoError(t, err)
+	assert.Equal(t, dog{Name: "Hachiko"}, v)
+}
+
+func TestSyncMap_RestoreJSON_roundTrip(t *testing.T) {
+	ctx := cache.WithGracePeriod(context.Background(), time.Hour)
+	c1 := cache.NewSyncMap()
+
+	require.NoError(t, c1.Write(cache.WithTTL(ctx, time.Minute, false), []byte("foo"), "bar"))
+
+	w := bytes.NewBuffer(nil)
+	_, err := c1.DumpJSON(w)
+	require.NoError(t, err)
+
+	c2 := cache.NewSyncMap()
+	n, err := c2.RestoreJSON(w)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	var entries []cache.Entry
+
+	for _, c := range []*cache.SyncMap{c1, c2} {
+		_, err = c.Walk(func(e cache.Entry) error {
+			entries = append(entries, e)
+
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	require.Len(t, entries, 2)
+	assert.Equal(t, entries[0], entries[1])
+	assert.False(t, entries[1].CreatedAt().IsZero())
+	assert.Equal(t, time.Hour, time.Duration(entries[1].(*cache.TraitEntry).G))
+}
@@ -1,8 +1,15 @@
 package cache_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -10,6 +17,7 @@ import (
 	"github.com/bool64/ctxd"
 	"github.com/bool64/stats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/swaggest/assertjson"
 )
 
@@ -62,7 +70,7 @@ func TestNewSyncMap(t *testing.T) {
 		"time":"<ignore-diff>","level":"debug",
 		"message":"cache hit",
 		"data":{
-		  "entry":{"key":"foo","val":"bar","exp":"<ignore-diff>"},
+		  "entry":{"key":"foo","val":"bar","exp":"<ignore-diff>","created":"<ignore-diff>"},
 		  "name":"test"
 		}
 	  },
@@ -105,3 +113,1398 @@ cache_hit{name="test"} 1
 cache_items{name="test"} 0
 cache_write{name="test"} 1`, st.Metrics())
 }
+
+func TestSyncMap_ReadOrWrite(t *testing.T) {
+	st := stats.TrackerMock{}
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.Stats = &st
+		config.Name = "test"
+	})
+
+	ctx := context.Background()
+	builds := int64(0)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			v, err := c.ReadOrWrite(ctx, []byte("foo"), func(ctx context.Context) (interface{}, error) {
+				atomic.AddInt64(&builds, 1)
+				time.Sleep(10 * time.Millisecond)
+
+				return "bar", nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, "bar", v)
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&builds))
+	assert.Equal(t, 1, st.Int(cache.MetricWrite))
+	assert.Greater(t, st.Int(cache.MetricSingleFlightDedup, "name", "test"), 0)
+	assert.Equal(t, int64(st.Int(cache.MetricSingleFlightDedup, "name", "test")), c.Stats().Deduplicated)
+
+	// Failed build is not stored and next call retries.
+	_, err := c.ReadOrWrite(ctx, []byte("baz"), func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+
+	v, err := c.ReadOrWrite(ctx, []byte("baz"), func(ctx context.Context) (interface{}, error) {
+		return 123, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 123, v)
+}
+
+func TestSyncMap_ReadOrWrite_keyPrefix(t *testing.T) {
+	c := cache.NewSyncMap()
+	ctx := context.Background()
+
+	started := sync.WaitGroup{}
+	started.Add(2)
+
+	wg := sync.WaitGroup{}
+
+	for _, tenant := range []string{"a", "b"} {
+		wg.Add(1)
+
+		go func(tenant string) {
+			defer wg.Done()
+
+			v, err := c.ReadOrWrite(cache.WithKeyPrefix(ctx, []byte(tenant+":")), []byte("k"),
+				func(ctx context.Context) (interface{}, error) {
+					// Both builds are in flight at the same time.
+					started.Done()
+					started.Wait()
+
+					return tenant, nil
+				})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tenant, v)
+		}(tenant)
+	}
+
+	wg.Wait()
+
+	for _, tenant := range []string{"a", "b"} {
+		v, err := c.Read(ctx, []byte(tenant+":k"))
+		require.NoError(t, err)
+		assert.Equal(t, tenant, v)
+	}
+}
+
+func TestSyncMap_ReadOrWrite_panic(t *testing.T) {
+	c := cache.NewSyncMap()
+	ctx := context.Background()
+
+	building := make(chan struct{})
+	release := make(chan struct{})
+	wg := sync.WaitGroup{}
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+
+		assert.PanicsWithValue(t, "failed", func() {
+			_, _ = c.ReadOrWrite(ctx, []byte("foo"), func(ctx context.Context) (interface{}, error) {
+				close(building)
+				<-release
+
+				panic("failed")
+			})
+		})
+	}()
+
+	<-building
+
+	go func() {
+		defer wg.Done()
+
+		_, err := c.ReadOrWrite(ctx, []byte("foo"), func(ctx context.Context) (interface{}, error) {
+			return nil, errors.New("unexpected build")
+		})
+		assert.ErrorIs(t, err, cache.ErrCallbackPanic)
+	}()
+
+	// Waiting for the second call to join the build.
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	_, err := c.Read(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+}
+
+func TestSyncMap_Len(t *testing.T) {
+	c := cache.NewSyncMap()
+	ctx := context.Background()
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	assert.NoError(t, c.Write(ctx, []byte("foo"), 2))
+	assert.NoError(t, c.Write(ctx, []byte("bar"), 3))
+	assert.Equal(t, 2, c.Len())
+
+	assert.ErrorIs(t, c.Delete(ctx, []byte("baz")), cache.ErrNotFound)
+	assert.Equal(t, 2, c.Len())
+
+	assert.NoError(t, c.Delete(ctx, []byte("foo")))
+	assert.Equal(t, 1, c.Len())
+
+	c.DeleteAll(ctx)
+	assert.Equal(t, 0, c.Len())
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			k := []byte(strconv.Itoa(i % 10))
+
+			if i%3 == 0 {
+				if err := c.Delete(ctx, k); err != nil {
+					assert.ErrorIs(t, err, cache.ErrNotFound)
+				}
+			} else {
+				assert.NoError(t, c.Write(ctx, k, i))
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	n, err := c.Walk(func(e cache.Entry) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, n, c.Len())
+}
+
+func TestSyncMap_ReadMulti(t *testing.T) {
+	st := stats.TrackerMock{}
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.Stats = &st
+		config.Name = "test"
+	})
+
+	ctx := context.Background()
+
+	assert.NoError(t, c.WriteMulti(ctx, []cache.KeyValue{
+		{Key: []byte("foo"), Value: 1},
+		{Key: []byte("bar"), Value: 2},
+	}))
+	assert.NoError(t, c.Write(cache.WithTTL(ctx, -time.Second, false), []byte("baz"), 3))
+
+	v, err := c.ReadMulti(ctx, [][]byte{[]byte("foo"), []byte("bar"), []byte("baz"), []byte("qux")})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": 1, "bar": 2}, v)
+
+	assert.Equal(t, 2, st.Int(cache.MetricHit))
+	assert.Equal(t, 1, st.Int(cache.MetricExpired))
+	assert.Equal(t, 1, st.Int(cache.MetricMiss))
+
+	v, err = c.ReadMulti(cache.WithSkipRead(ctx), [][]byte{[]byte("foo")})
+	assert.NoError(t, err)
+	assert.Empty(t, v)
+}
+
+func TestSyncMap_ReadMulti_softExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+		cfg.SoftTTLFraction = 0.5
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	clock.Add(45 * time.Second)
+
+	v, err := c.ReadMulti(ctx, [][]byte{[]byte("foo")})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": 1}, v)
+}
+
+func TestSyncMap_WalkContext(t *testing.T) {
+	c := cache.NewSyncMap()
+	ctx := context.Background()
+
+	for i := 0; i < 5000; i++ {
+		assert.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	n := 0
+
+	_, err := c.WalkContext(cctx, func(e cache.Entry) error {
+		n++
+		if n == 10 {
+			cancel()
+		}
+
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, n, 5000)
+
+	_, err = c.DumpContext(cctx, io.Discard)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	c.DeleteAll(cctx)
+	assert.Equal(t, 5000, c.Len())
+
+	c.DeleteAll(ctx)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestSyncMap_Stats(t *testing.T) {
+	c := cache.NewSyncMap()
+	ctx := context.Background()
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	assert.NoError(t, c.Write(ctx, []byte("bar"), 2))
+
+	_, err := c.Read(ctx, []byte("foo"))
+	assert.NoError(t, err)
+
+	_, err = c.Read(ctx, []byte("baz"))
+	assert.Error(t, err)
+
+	assert.NoError(t, c.Delete(ctx, []byte("foo")))
+	c.ExpireAll(ctx)
+
+	_, err = c.Read(ctx, []byte("bar"))
+	assert.Error(t, err)
+
+	assert.Equal(t, cache.CacheStats{
+		Hits:     1,
+		Misses:   1,
+		Expired:  2,
+		Writes:   2,
+		Deletes:  1,
+		Items:    1,
+		HitRatio: 1.0 / 3,
+	}, c.Stats())
+}
+
+func TestSyncMap_Peek(t *testing.T) {
+	st := stats.TrackerMock{}
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.Stats = &st
+		config.EvictionStrategy = cache.EvictLeastFrequentlyUsed
+	})
+	ctx := context.Background()
+
+	assert.NoError(t, c.Write(cache.WithTTL(ctx, time.Hour, false), []byte("foo"), 1))
+
+	v, exp, err := c.Peek(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+	assert.True(t, exp.After(time.Now()))
+
+	_, _, err = c.Peek(ctx, []byte("bar"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+
+	c.ExpireAll(ctx)
+
+	_, _, err = c.Peek(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrExpired)
+	assert.Equal(t, 1, err.(cache.ErrWithExpiredItem).Value())
+
+	assert.Equal(t, 0, st.Int(cache.MetricHit))
+	assert.Equal(t, 0, st.Int(cache.MetricMiss))
+
+	_, err = c.Walk(func(e cache.Entry) error {
+		assert.Equal(t, int64(0), e.(*cache.TraitEntry).C)
+
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestSyncMap_WriteMiss(t *testing.T) {
+	st := stats.TrackerMock{}
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.Stats = &st
+		config.MissTTL = time.Hour
+	})
+	ctx := context.Background()
+
+	assert.NoError(t, c.WriteMiss(ctx, []byte("foo")))
+
+	_, err := c.Read(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrCachedMiss)
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+	assert.Equal(t, 1, st.Int(cache.MetricMiss))
+
+	v, err := c.ReadMulti(ctx, [][]byte{[]byte("foo")})
+	assert.NoError(t, err)
+	assert.Empty(t, v)
+
+	_, err = c.ReadOrWrite(ctx, []byte("foo"), func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("unexpected build")
+	})
+	assert.ErrorIs(t, err, cache.ErrCachedMiss)
+
+	c.ExpireAll(ctx)
+
+	_, err = c.Read(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+	assert.NotErrorIs(t, err, cache.ErrCachedMiss)
+	assert.NotErrorIs(t, err, cache.ErrExpired)
+
+	w := bytes.NewBuffer(nil)
+	_, err = c.Dump(w)
+	assert.NoError(t, err)
+}
+
+func TestSyncMap_WriteMiss_notExposed(t *testing.T) {
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.MissTTL = time.Hour
+	})
+	ctx := context.Background()
+
+	assert.NoError(t, c.Write(ctx, []byte("bar"), 1))
+	assert.NoError(t, c.WriteMiss(ctx, []byte("foo")))
+
+	_, _, err := c.Peek(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrCachedMiss)
+
+	var keys []string
+
+	n, err := c.Walk(func(e cache.Entry) error {
+		keys = append(keys, string(e.Key()))
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"bar"}, keys)
+
+	k, _, err := c.Keys(ctx, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("bar")}, k)
+
+	w := bytes.NewBuffer(nil)
+	n, err = c.DumpJSON(w)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NotContains(t, w.String(), "foo")
+
+	assert.Len(t, c.Drain(ctx), 1)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestSyncMap_WriteWithTTL(t *testing.T) {
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.ExpirationJitter = -1
+	})
+	ctx := cache.WithTTL(context.Background(), time.Minute, false)
+
+	assert.NoError(t, c.WriteWithTTL(ctx, []byte("foo"), 1, time.Hour))
+	assert.NoError(t, c.WriteWithTTL(ctx, []byte("bar"), 2, cache.UnlimitedTTL))
+	assert.NoError(t, c.WriteWithTTL(ctx, []byte("baz"), 3, -time.Second))
+
+	_, exp, err := c.Peek(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), time.Until(exp).Seconds(), 5)
+
+	_, err = c.Walk(func(e cache.Entry) error {
+		if string(e.Key()) == "bar" {
+			assert.Equal(t, int64(0), e.(*cache.TraitEntry).E)
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+
+	_, err = c.Read(ctx, []byte("baz"))
+	assert.ErrorIs(t, err, cache.ErrExpired)
+}
+
+func TestSyncMap_Touch(t *testing.T) {
+	c := cache.NewSyncMap()
+	ctx := context.Background()
+
+	assert.NoError(t, c.Write(cache.WithTTL(ctx, time.Minute, false), []byte("foo"), 1))
+	assert.NoError(t, c.Touch(ctx, []byte("foo"), time.Hour))
+
+	_, exp, err := c.Peek(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), time.Until(exp).Seconds(), 5)
+
+	assert.ErrorIs(t, c.Touch(ctx, []byte("bar"), time.Hour), cache.ErrNotFound)
+
+	c.ExpireAll(ctx)
+	assert.ErrorIs(t, c.Touch(ctx, []byte("foo"), time.Hour), cache.ErrNotFound)
+}
+
+func TestSyncMap_Touch_defaultTTL(t *testing.T) {
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.TimeToLive = time.Hour
+		cfg.ExpirationJitter = -1
+	})
+	ctx := context.Background()
+
+	assert.NoError(t, c.Write(cache.WithTTL(ctx, time.Minute, false), []byte("foo"), 1))
+	assert.NoError(t, c.Touch(ctx, []byte("foo"), cache.DefaultTTL))
+
+	_, exp, err := c.Peek(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), time.Until(exp).Seconds(), 5)
+
+	assert.NoError(t, c.Touch(cache.WithTTL(ctx, 2*time.Hour, false), []byte("foo"), cache.DefaultTTL))
+
+	_, exp, err = c.Peek(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.InDelta(t, (2 * time.Hour).Seconds(), time.Until(exp).Seconds(), 5)
+}
+
+func TestSyncMap_Touch_miss(t *testing.T) {
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.MissTTL = time.Minute
+	})
+	ctx := context.Background()
+
+	assert.NoError(t, c.WriteMiss(ctx, []byte("foo")))
+	assert.ErrorIs(t, c.Touch(ctx, []byte("foo"), time.Hour), cache.ErrNotFound)
+
+	_, err := c.Read(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+}
+
+func TestSyncMap_Touch_keyPrefix(t *testing.T) {
+	clock := newFakeClock()
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.ExpirationJitter = -1
+		cfg.DeleteExpiredAfter = time.Minute
+		cfg.DeleteExpiredJobInterval = 10 * time.Second
+		cfg.ExpirationIndexBucket = 10 * time.Second
+		cfg.IntervalJitter = -1
+	})
+	ctx := cache.WithKeyPrefix(context.Background(), []byte("tenant:"))
+
+	assert.NoError(t, c.Write(cache.WithTTL(ctx, time.Hour, false), []byte("foo"), 1))
+	assert.NoError(t, c.Touch(ctx, []byte("foo"), 10*time.Second))
+
+	// Entry is deleted by expiration index with updated time, well before initial expiration.
+	deadline := clock.Now().Add(5 * time.Minute)
+
+	assert.Eventually(t, func() bool {
+		if clock.Now().Before(deadline) {
+			clock.Add(10 * time.Second)
+		}
+
+		return c.Len() == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestSyncMap_Rename(t *testing.T) {
+	c := cache.NewSyncMap(cache.Config{MaxBytes: 1000}.Use)
+	ctx := context.Background()
+
+	require.NoError(t, c.Write(cache.WithTTL(ctx, time.Hour, false), []byte("old"), "foo"))
+	require.NoError(t, c.Write(ctx, []byte("new"), "bar"))
+
+	_, exp, err := c.Peek(ctx, []byte("old"))
+	require.NoError(t, err)
+
+	require.NoError(t, c.Rename(ctx, []byte("old"), []byte("new")))
+	assert.Equal(t, 1, c.Len())
+	assert.Equal(t, int64(3), c.Stats().Bytes)
+
+	v, newExp, err := c.Peek(ctx, []byte("new"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", v)
+	assert.Equal(t, exp, newExp)
+
+	_, err = c.Read(ctx, []byte("old"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+	assert.ErrorIs(t, c.Rename(ctx, []byte("old"), []byte("new")), cache.ErrNotFound)
+
+	require.NoError(t, c.Copy(ctx, []byte("new"), []byte("copy")))
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int64(6), c.Stats().Bytes)
+
+	v, newExp, err = c.Peek(ctx, []byte("copy"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", v)
+	assert.Equal(t, exp, newExp)
+
+	require.NoError(t, c.Rename(ctx, []byte("copy"), []byte("copy")))
+	assert.ErrorIs(t, c.Copy(ctx, []byte("missing"), []byte("copy")), cache.ErrNotFound)
+
+	// Concurrent renames of the same keys do not deadlock.
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			a, b := []byte("new"), []byte("copy")
+			if i%2 == 0 {
+				a, b = b, a
+			}
+
+			_ = c.Rename(ctx, a, b)
+			_ = c.Write(ctx, a, "baz")
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestSyncMap_Write_maxValueSize(t *testing.T) {
+	ctx := context.Background()
+	st := stats.TrackerMock{}
+
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.Stats = &st
+		config.MaxValueSize = 5
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), "12345"))
+	assert.Equal(t, cache.ErrValueTooLarge, c.Write(ctx, []byte("bar"), "123456"))
+	assert.Equal(t, cache.ErrValueTooLarge, c.Write(ctx, []byte("baz"), struct{ A, B string }{A: "abc", B: "def"}))
+
+	_, err := c.Read(ctx, []byte("bar"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+	assert.Equal(t, 1, c.Len())
+	assert.Equal(t, 2, st.Int(cache.MetricRejected))
+
+	c = cache.NewSyncMap(func(config *cache.Config) {
+		config.MaxValueSize = 5
+		config.Sizer = func(value interface{}) int {
+			return 1
+		}
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("bar"), "123456"))
+}
+
+func TestSyncMap_Write_countHardLimit(t *testing.T) {
+	ctx := context.Background()
+	st := stats.TrackerMock{}
+
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.Stats = &st
+		config.CountHardLimit = 100
+	})
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				assert.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i*100+j)), j))
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	// Concurrent writers may make eviction slightly more aggressive.
+	assert.LessOrEqual(t, c.Len(), 100)
+	assert.Equal(t, 1000, c.Len()+st.Int(cache.MetricEvict))
+	assert.Equal(t, st.Int(cache.MetricEvict), st.Int(cache.MetricEvict, "name", "", "trigger", cache.EvictTriggerWrite))
+}
+
+func TestSyncMap_Write_rejectOnFull(t *testing.T) {
+	ctx := context.Background()
+
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.CountHardLimit = 100
+		config.RejectOnFull = true
+	})
+
+	wg := sync.WaitGroup{}
+	rejected := int64(0)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				if err := c.Write(ctx, []byte(strconv.Itoa(i*100+j)), j); err != nil {
+					assert.Equal(t, cache.ErrCacheFull, err)
+					atomic.AddInt64(&rejected, 1)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.LessOrEqual(t, c.Len(), 100)
+	assert.Equal(t, int64(1000-c.Len()), rejected)
+}
+
+func TestSyncMap_WriteIfAbsent(t *testing.T) {
+	ctx := context.Background()
+	st := stats.TrackerMock{}
+
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.Stats = &st
+	})
+
+	ok, err := c.WriteIfAbsent(ctx, []byte("foo"), 1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c.WriteIfAbsent(ctx, []byte("foo"), 2)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	v, err := c.Read(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	// Expired entry is overwritten.
+	c.ExpireAll(ctx)
+
+	ok, err = c.WriteIfAbsent(ctx, []byte("foo"), 3)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	v, err = c.Read(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.Equal(t, 1, c.Len())
+	assert.Equal(t, 2, st.Int(cache.MetricWrite))
+
+	// Only one of concurrent writers succeeds.
+	wg := sync.WaitGroup{}
+	stored := int64(0)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			ok, err := c.WriteIfAbsent(ctx, []byte("bar"), i)
+			assert.NoError(t, err)
+
+			if ok {
+				atomic.AddInt64(&stored, 1)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int64(1), stored)
+}
+
+func TestSyncMap_Update(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewSyncMap()
+
+	inc := func(old interface{}, found bool) (interface{}, error) {
+		if !found {
+			return 1, nil
+		}
+
+		return old.(int) + 1, nil
+	}
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			assert.NoError(t, c.Update(ctx, []byte("cnt"), inc))
+		}()
+	}
+
+	wg.Wait()
+
+	v, err := c.Read(ctx, []byte("cnt"))
+	assert.NoError(t, err)
+	assert.Equal(t, 100, v)
+
+	assert.NoError(t, c.Update(ctx, []byte("cnt"), func(old interface{}, found bool) (interface{}, error) {
+		assert.True(t, found)
+
+		return nil, cache.ErrNoChange
+	}))
+
+	failed := errors.New("failed")
+	assert.Equal(t, failed, c.Update(ctx, []byte("cnt"), func(old interface{}, found bool) (interface{}, error) {
+		return nil, failed
+	}))
+
+	v, err = c.Read(ctx, []byte("cnt"))
+	assert.NoError(t, err)
+	assert.Equal(t, 100, v)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestSyncMap_Close(t *testing.T) {
+	ctx := context.Background()
+	st := stats.TrackerMock{}
+
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.Stats = &st
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+
+	// Items count is reported by closing goroutine.
+	assert.Eventually(t, func() bool {
+		return st.Int(cache.MetricItems) == 1
+	}, time.Second, time.Millisecond)
+
+	// Cache is still operational.
+	assert.NoError(t, c.Write(ctx, []byte("baz"), "qux"))
+
+	v, err := c.Read(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", v)
+}
+
+func TestSyncMap_Purge(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewSyncMap()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+	}
+
+	assert.Equal(t, 5, c.ExpireAllCount(ctx))
+
+	_, err := c.Read(ctx, []byte("1"))
+	assert.True(t, errors.Is(err, cache.ErrExpired))
+
+	assert.Equal(t, 5, c.Purge(ctx))
+	assert.Equal(t, 0, c.Purge(ctx))
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestSyncMap_WalkExpired(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewSyncMap()
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+	}
+
+	n, err := c.WalkExpired(func(e cache.Entry) error {
+		return errors.New("unexpected")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	c.ExpireAll(ctx)
+	assert.NoError(t, c.Write(ctx, []byte("fresh"), 1))
+
+	var keys []string
+
+	n, err = c.WalkExpired(func(e cache.Entry) error {
+		keys = append(keys, string(e.Key()))
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.NotContains(t, keys, "fresh")
+
+	n, err = c.WalkExpired(func(e cache.Entry) error {
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, 0, n)
+}
+
+func TestSyncMap_DeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	st := stats.TrackerMock{}
+
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.Stats = &st
+	})
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, c.Write(ctx, []byte("user:1:"+strconv.Itoa(i)), i))
+		assert.NoError(t, c.Write(ctx, []byte("user:2:"+strconv.Itoa(i)), i))
+	}
+
+	n, err := c.DeletePrefix(ctx, []byte("user:1:"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 5, c.Len())
+	assert.Equal(t, 5, st.Int(cache.MetricDelete))
+
+	_, err = c.Read(ctx, []byte("user:1:3"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+	_, err = c.Read(ctx, []byte("user:2:3"))
+	assert.NoError(t, err)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = c.DeletePrefix(cctx, []byte("user:"))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 5, c.Len())
+}
+
+func TestSyncMap_Keys(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewSyncMap()
+
+	var expected []string
+
+	for i := 0; i < 25; i++ {
+		k := fmt.Sprintf("key%02d", i)
+		expected = append(expected, k)
+		assert.NoError(t, c.Write(ctx, []byte(k), i))
+	}
+
+	var (
+		actual []string
+		cursor string
+		pages  int
+	)
+
+	for {
+		keys, next, err := c.Keys(ctx, cursor, 10)
+		require.NoError(t, err)
+
+		pages++
+
+		for _, k := range keys {
+			actual = append(actual, string(k))
+		}
+
+		if next == "" {
+			break
+		}
+
+		cursor = next
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, expected, actual)
+
+	keys, next, err := c.Keys(ctx, "", 0)
+	require.NoError(t, err)
+	assert.Len(t, keys, 25)
+	assert.Empty(t, next)
+}
+
+func TestSyncMap_keyFunc(t *testing.T) {
+	ctx := context.Background()
+
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.KeyFunc = func(key []byte) []byte {
+			return bytes.ToLower(bytes.TrimSuffix(key, []byte("/")))
+		}
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("/Foo/"), 1))
+
+	v, err := c.Read(ctx, []byte("/foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	_, err = c.Walk(func(e cache.Entry) error {
+		assert.Equal(t, "/foo", string(e.Key()))
+
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Delete(ctx, []byte("/FOO")))
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestSyncMap_WriteRead(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.MaxValueSize = 3
+	})
+
+	v, err := c.WriteRead(ctx, []byte("foo"), "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	v, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	v, err = c.WriteRead(ctx, []byte("foo"), "barbaz")
+	assert.Equal(t, cache.ErrValueTooLarge, err)
+	assert.Nil(t, v)
+}
+
+func TestSyncMap_Increment(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+	})
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := c.Increment(ctx, []byte("hits"), 2)
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+
+	clock.Add(30 * time.Second)
+
+	n, err := c.Decrement(ctx, []byte("hits"), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(199), n)
+
+	// Existing counter keeps expiration.
+	_, exp, err := c.Peek(ctx, []byte("hits"))
+	require.NoError(t, err)
+	assert.True(t, clock.Now().Add(30*time.Second).Equal(exp))
+
+	clock.Add(time.Minute)
+
+	// Expired counter starts over.
+	n, err = c.Increment(ctx, []byte("hits"), 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+
+	_, err = c.Increment(ctx, []byte("foo"), 1)
+	assert.Equal(t, cache.ErrNotCounter, err)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestSyncMap_Walk_createdAt(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+	})
+
+	createdAt := clock.Now()
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	clock.Add(time.Minute)
+	require.NoError(t, c.Write(ctx, []byte("baz"), "qux"))
+
+	_, err := c.Walk(func(e cache.Entry) error {
+		if string(e.Key()) == "foo" {
+			assert.True(t, createdAt.Equal(e.CreatedAt()))
+		} else {
+			assert.True(t, createdAt.Add(time.Minute).Equal(e.CreatedAt()))
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.True(t, (&cache.TraitEntry{}).CreatedAt().IsZero())
+}
+
+func TestSyncMap_WalkSorted(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	newCache := func() *cache.SyncMap {
+		return cache.NewSyncMap(func(cfg *cache.Config) {
+			cfg.Clock = clock
+			cfg.ExpirationJitter = -1
+		})
+	}
+
+	c1 := newCache()
+	c2 := newCache()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, c1.WriteWithTTL(ctx, []byte(strconv.Itoa(i)), i, time.Duration(100-i)*time.Minute))
+		require.NoError(t, c2.WriteWithTTL(ctx, []byte(strconv.Itoa(99-i)), 99-i, time.Duration(i+1)*time.Minute))
+	}
+
+	var keys []string
+
+	n, err := c1.WalkSorted(nil, func(e cache.Entry) error {
+		keys = append(keys, string(e.Key()))
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, []string{"0", "1", "10", "11"}, keys[:4])
+
+	var values []int
+
+	_, err = c1.WalkSorted(func(a, b cache.Entry) bool {
+		return a.ExpireAt().Before(b.ExpireAt())
+	}, func(e cache.Entry) error {
+		values = append(values, e.Value().(int))
+
+		if len(values) == 3 {
+			return errors.New("enough")
+		}
+
+		return nil
+	})
+	assert.EqualError(t, err, "enough")
+	assert.Equal(t, []int{99, 98, 97}, values)
+
+	d1, d2 := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+
+	_, err = c1.DumpWithOptions(d1, cache.DumpOptions{Sorted: true})
+	require.NoError(t, err)
+
+	_, err = c2.DumpWithOptions(d2, cache.DumpOptions{Sorted: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, d1.Bytes(), d2.Bytes())
+}
+
+func TestConfig_IgnoreMissingDelete(t *testing.T) {
+	ctx := context.Background()
+
+	for _, ignore := range []bool{false, true} {
+		m := &stats.TrackerMock{}
+		cfg := func(cfg *cache.Config) {
+			cfg.IgnoreMissingDelete = ignore
+			cfg.Stats = m
+		}
+
+		for _, c := range []cache.Deleter{cache.NewSyncMap(cfg), cache.NewShardedMap(cfg)} {
+			err := c.Delete(ctx, []byte("foo"))
+
+			if ignore {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, cache.ErrNotFound)
+			}
+		}
+
+		// Missing keys are not counted as deleted.
+		assert.Equal(t, 0, m.Int(cache.MetricDelete))
+	}
+}
+
+func TestSyncMap_Preload(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	m := &stats.TrackerMock{}
+
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Name = "test"
+		cfg.Stats = m
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Hour
+		cfg.ExpirationJitter = -1
+		cfg.MaxValueSize = 10
+	})
+
+	entries := make([]cache.Entry, 0, 10)
+	for i := 0; i < 10; i++ {
+		entries = append(entries, cache.TraitEntry{K: []byte(strconv.Itoa(i)), V: i})
+	}
+
+	entries = append(entries, cache.TraitEntry{K: []byte("big"), V: "too large value"})
+
+	n, err := c.Preload(cache.WithKeyPrefix(ctx, []byte("p:")), entries, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, 10, c.Len())
+	assert.Equal(t, 10, m.Int(cache.MetricWrite, "name", "test"))
+
+	v, exp, err := c.Peek(ctx, []byte("p:3"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.True(t, clock.Now().Add(time.Minute).Equal(exp))
+
+	_, err = c.Read(ctx, []byte("p:big"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+
+	// Preloaded entries are removed if cache can not fit them.
+	c = cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.CountHardLimit = 5
+		cfg.RejectOnFull = true
+	})
+
+	n, err = c.Preload(ctx, entries, cache.DefaultTTL)
+	assert.ErrorIs(t, err, cache.ErrCacheFull)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestSyncMap_ExpireMatching(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewSyncMap()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, c.Write(ctx, []byte("user:"+strconv.Itoa(i)), i))
+		require.NoError(t, c.Write(ctx, []byte("order:"+strconv.Itoa(i)), i))
+	}
+
+	n := c.ExpireMatching(ctx, func(e cache.Entry) bool {
+		return bytes.HasPrefix(e.Key(), []byte("user:")) && e.Value().(int)%2 == 0
+	})
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 20, c.Len())
+
+	_, err := c.Read(ctx, []byte("user:2"))
+	assert.ErrorIs(t, err, cache.ErrExpired)
+
+	v, err := c.Read(ctx, []byte("user:3"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	v, err = c.Read(ctx, []byte("order:2"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
+
+func TestSyncMap_ReplaceAll(t *testing.T) {
+	ctx := context.Background()
+
+	var evicted []string
+
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.OnEvicted = func(key []byte, _ interface{}, reason cache.EvictReason) {
+			assert.Equal(t, cache.EvictReasonDeleted, reason)
+
+			evicted = append(evicted, string(key))
+		}
+	})
+
+	dataset := func(version, n int) []cache.Entry {
+		entries := make([]cache.Entry, 0, n)
+		for i := 0; i < n; i++ {
+			entries = append(entries, cache.TraitEntry{K: []byte(strconv.Itoa(i)), V: version})
+		}
+
+		return entries
+	}
+
+	n, err := c.ReplaceAll(ctx, dataset(1, 100))
+	require.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, 100, c.Len())
+
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				// Readers never observe a missing entry while dataset is replaced.
+				for k := 0; k < 90; k++ {
+					_, err := c.Read(ctx, []byte(strconv.Itoa(k)))
+					assert.NoError(t, err)
+				}
+			}
+		}()
+	}
+
+	for v := 2; v < 10; v++ {
+		_, err := c.ReplaceAll(ctx, dataset(v, 100))
+		require.NoError(t, err)
+	}
+
+	close(done)
+	wg.Wait()
+
+	n, err = c.ReplaceAll(ctx, dataset(10, 90))
+	require.NoError(t, err)
+	assert.Equal(t, 90, n)
+	assert.Equal(t, 90, c.Len())
+	assert.Len(t, evicted, 10)
+
+	v, err := c.Read(ctx, []byte("5"))
+	require.NoError(t, err)
+	assert.Equal(t, 10, v)
+
+	_, err = c.Read(ctx, []byte("95"))
+	assert.ErrorIs(t, err, cache.ErrNotFound)
+
+	// Cache is not changed if new dataset does not fit.
+	c = cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.CountHardLimit = 50
+		cfg.RejectOnFull = true
+	})
+
+	_, err = c.ReplaceAll(ctx, dataset(1, 10))
+	require.NoError(t, err)
+
+	_, err = c.ReplaceAll(ctx, dataset(2, 100))
+	assert.ErrorIs(t, err, cache.ErrCacheFull)
+	assert.Equal(t, 10, c.Len())
+}
+
+func TestSyncMap_Oldest(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Clock = clock
+	})
+
+	_, found := c.Oldest()
+	assert.False(t, found)
+
+	_, found = c.Newest()
+	assert.False(t, found)
+
+	createdAt := clock.Now()
+
+	for _, k := range []string{"foo", "bar", "baz"} {
+		require.NoError(t, c.Write(ctx, []byte(k), k))
+		clock.Add(time.Minute)
+	}
+
+	e, found := c.Oldest()
+	require.True(t, found)
+	assert.Equal(t, "foo", string(e.Key()))
+	assert.True(t, createdAt.Equal(e.CreatedAt()))
+
+	e, found = c.Newest()
+	require.True(t, found)
+	assert.Equal(t, "baz", e.Value())
+	assert.True(t, createdAt.Add(2*time.Minute).Equal(e.CreatedAt()))
+}
+
+func TestSyncMap_Drain(t *testing.T) {
+	ctx := context.Background()
+	st := stats.TrackerMock{}
+	c := cache.NewSyncMap(func(cfg *cache.Config) {
+		cfg.Stats = &st
+		cfg.Name = "test"
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	require.NoError(t, c.Write(ctx, []byte("baz"), "qux"))
+
+	entries := c.Drain(ctx)
+	require.Len(t, entries, 2)
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, 2, st.Int(cache.MetricDelete, "name", "test"))
+
+	values := map[string]interface{}{}
+	for _, e := range entries {
+		values[string(e.Key())] = e.Value()
+	}
+
+	assert.Equal(t, map[string]interface{}{"foo": "bar", "baz": "qux"}, values)
+	assert.Empty(t, c.Drain(ctx))
+}
+
+func TestSyncMap_Write_maxKeySize(t *testing.T) {
+	ctx := context.Background()
+
+	c := cache.NewSyncMap(func(config *cache.Config) {
+		config.MaxKeySize = 3
+	})
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), 1))
+	assert.Equal(t, cache.ErrKeyTooLarge, c.Write(ctx, []byte("fooo"), 2))
+
+	_, err := c.Increment(ctx, []byte("fooo"), 1)
+	assert.Equal(t, cache.ErrKeyTooLarge, err)
+
+	assert.Equal(t, cache.ErrKeyTooLarge, c.Update(ctx, []byte("fooo"), func(old interface{}, found bool) (interface{}, error) {
+		return 3, nil
+	}))
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestSyncMap_WalkSnapshot(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewSyncMap()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, c.Write(ctx, []byte(strconv.Itoa(i)), i))
+	}
+
+	visited := map[string]bool{}
+
+	n, err := c.WalkSnapshot(func(e cache.Entry) error {
+		visited[string(e.Key())] = true
+
+		// Concurrent changes do not affect walked set.
+		if len(visited) == 1 {
+			for i := 0; i < 100; i++ {
+				if err := c.Delete(ctx, []byte(strconv.Itoa(i))); err != nil {
+					return err
+				}
+
+				if err := c.Write(ctx, []byte("new"+strconv.Itoa(i)), i); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Len(t, visited, 100)
+	assert.False(t, visited["new0"])
+
+	buf := bytes.NewBuffer(nil)
+
+	n, err = c.DumpWithOptions(buf, cache.DumpOptions{Snapshot: true})
+	require.NoError(t, err)
+	assert.Equal(t, 100, n)
+
+	c2 := cache.NewSyncMap()
+
+	n, err = c2.Restore(buf)
+	require.NoError(t, err)
+	assert.Equal(t, 100, n)
+
+	v, err := c2.Read(ctx, []byte("new1"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// TieredConfig is optional configuration for NewTiered.
+type TieredConfig struct {
+	// Name is added to logs and stats.
+	Name string
+
+	// L1TTL is time to live of values populated into L1 from L2, default 1 minute.
+	L1TTL time.Duration
+
+	// AsyncWriteback enables populating L1 in background after L2 hit.
+	AsyncWriteback bool
+
+	// Logger collects messages with context.
+	Logger Logger
+
+	// Stats tracks stats.
+	Stats StatsTracker
+}
+
+// Use is a functional option for NewTiered to apply configuration.
+func (tc TieredConfig) Use(cfg *TieredConfig) {
+	*cfg = tc
+}
+
+var (
+	_ ReadWriter = &Tiered{}
+	_ Deleter    = &Tiered{}
+)
+
+// Tiered is a read-through hierarchy of a fast local cache (L1) and a slower shared cache (L2).
+//
+// Please use NewTiered to create instance.
+type Tiered struct {
+	l1, l2 ReadWriter
+	config TieredConfig
+
+	logTrait
+
+	stat StatsTracker
+}
+
+// NewTiered creates a two-tier cache instance.
+//
+// Read tries L1 first and falls back to L2, values found in L2 are written to L1 with L1TTL.
+// Write and Delete are applied to both tiers.
+func NewTiered(l1, l2 ReadWriter, options ...func(cfg *TieredConfig)) *Tiered {
+	cfg := TieredConfig{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	if cfg.L1TTL == 0 {
+		cfg.L1TTL = time.Minute
+	}
+
+	t := &Tiered{
+		l1:     l1,
+		l2:     l2,
+		config: cfg,
+		stat:   cfg.Stats,
+	}
+
+	t.logTrait.setup(cfg.Logger)
+
+	return t
+}
+
+// Read gets value from L1 or L2.
+func (t *Tiered) Read(ctx context.Context, key []byte) (interface{}, error) {
+	v, err := softExpiredHit(t.l1.Read(ctx, key))
+	if err == nil {
+		t.count(ctx, MetricHit, "l1")
+
+		return v, nil
+	}
+
+	if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrExpired) {
+		return nil, err
+	}
+
+	t.count(ctx, MetricMiss, "l1")
+
+	v, l2Err := softExpiredHit(t.l2.Read(ctx, key))
+	if l2Err != nil {
+		t.count(ctx, MetricMiss, "l2")
+
+		// Keeping stale value of L1 if L2 has nothing better.
+		if errors.Is(l2Err, ErrNotFound) && errors.Is(err, ErrExpired) {
+			return nil, err
+		}
+
+		return nil, l2Err
+	}
+
+	t.count(ctx, MetricHit, "l2")
+
+	if t.config.AsyncWriteback {
+		go t.writeback(detachedContext{ctx}, key, v)
+	} else {
+		t.writeback(ctx, key, v)
+	}
+
+	return v, nil
+}
+
+func (t *Tiered) writeback(ctx context.Context, key []byte, v interface{}) {
+	if err := t.l1.Write(t.l1Ctx(ctx), key, v); err != nil && t.logError != nil {
+		t.logError(ctx, "failed to populate L1 cache",
+			"error", err,
+			"key", key,
+			"name", t.config.Name)
+	}
+}
+
+// Write sets value to L2 and L1.
+func (t *Tiered) Write(ctx context.Context, key []byte, v interface{}) error {
+	if err := t.l2.Write(ctx, key, v); err != nil {
+		return err
+	}
+
+	return t.l1.Write(t.l1Ctx(ctx), key, v)
+}
+
+// Delete removes value from L1 and L2.
+//
+// It fails with ErrNotFound if key does not exist in L2.
+func (t *Tiered) Delete(ctx context.Context, key []byte) error {
+	if d, ok := t.l1.(Deleter); ok {
+		if err := d.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+
+	if d, ok := t.l2.(Deleter); ok {
+		return d.Delete(ctx, key)
+	}
+
+	return nil
+}
+
+// l1Ctx limits time to live of L1 entry with L1TTL.
+func (t *Tiered) l1Ctx(ctx context.Context) context.Context {
+	if ttl := TTL(ctx); ttl > 0 && ttl < t.config.L1TTL {
+		return ctx
+	}
+
+	return WithTTL(ctx, t.config.L1TTL, false)
+}
+
+func (t *Tiered) count(ctx context.Context, name string, tier string) {
+	if t.stat != nil {
+		t.stat.Add(ctx, name, 1, "name", t.config.Name, "tier", tier)
+	}
+}
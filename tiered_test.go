@@ -0,0 +1,106 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+	st := stats.TrackerMock{}
+	l1 := cache.NewShardedMap()
+	l2 := cache.NewShardedMap()
+
+	c := cache.NewTiered(l1, l2, func(cfg *cache.TieredConfig) {
+		cfg.Name = "tiered"
+		cfg.Stats = &st
+		cfg.L1TTL = time.Second
+	})
+
+	_, err := c.Read(ctx, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+	require.NoError(t, l2.Write(ctx, []byte("foo"), "bar"))
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	// Populated from L2.
+	v, err = l1.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	v, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	require.NoError(t, c.Write(ctx, []byte("baz"), 123))
+	assert.Equal(t, 2, l1.Len())
+	assert.Equal(t, 2, l2.Len())
+
+	require.NoError(t, c.Delete(ctx, []byte("baz")))
+	assert.Equal(t, 1, l1.Len())
+	assert.Equal(t, 1, l2.Len())
+	assert.True(t, errors.Is(c.Delete(ctx, []byte("baz")), cache.ErrNotFound))
+
+	assert.Equal(t, 1, st.Int(cache.MetricHit, "name", "tiered", "tier", "l1"))
+	assert.Equal(t, 1, st.Int(cache.MetricHit, "name", "tiered", "tier", "l2"))
+	assert.Equal(t, 2, st.Int(cache.MetricMiss, "name", "tiered", "tier", "l1"))
+	assert.Equal(t, 1, st.Int(cache.MetricMiss, "name", "tiered", "tier", "l2"))
+}
+
+func TestTiered_Read_softExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cfg := func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = time.Minute
+		cfg.ExpirationJitter = -1
+		cfg.SoftTTLFraction = 0.5
+	}
+
+	l1 := cache.NewShardedMap(cfg)
+	l2 := cache.NewShardedMap(cfg)
+	c := cache.NewTiered(l1, l2)
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	clock.Add(45 * time.Second)
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	require.NoError(t, l1.Delete(ctx, []byte("foo")))
+
+	v, err = c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+}
+
+func TestTiered_Read_asyncWriteback(t *testing.T) {
+	ctx := context.Background()
+	l1 := cache.NewShardedMap()
+	l2 := cache.NewShardedMap()
+
+	c := cache.NewTiered(l1, l2, func(cfg *cache.TieredConfig) {
+		cfg.AsyncWriteback = true
+	})
+
+	require.NoError(t, l2.Write(ctx, []byte("foo"), "bar"))
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	assert.Eventually(t, func() bool {
+		return l1.Len() == 1
+	}, time.Second, time.Millisecond)
+}
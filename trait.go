@@ -1,11 +1,17 @@
 package cache
 
 import (
+	"bytes"
 	"context"
+	"encoding/gob"
 	"errors"
+	"math"
 	"math/rand"
+	"reflect"
 	"runtime"
 	"runtime/debug"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -13,22 +19,33 @@ import (
 var bgCtx = context.Background()
 
 func (c *Trait) reportItemsCount() {
+	// Items count is not available without Len.
+	if c.Len == nil {
+		return
+	}
+
 	for {
-		interval := c.Config.ItemsCountReportInterval
+		interval := c.intervalJitter(c.Config.ItemsCountReportInterval)
 
 		select {
-		case <-time.After(interval):
+		case <-c.after(interval):
 			count := c.Len()
 
 			if c.Log.logDebug != nil {
 				c.Log.logDebug(context.Background(), "cache items count",
 					"name", c.Config.Name,
-					"count", c.Len(),
+					"count", count,
 				)
 			}
 
 			if c.Stat != nil {
 				c.Stat.Set(context.Background(), MetricItems, float64(count), "name", c.Config.Name)
+
+				if ratio, ok := c.hitRatio(); ok {
+					c.Stat.Set(context.Background(), MetricHitRatio, ratio, "name", c.Config.Name)
+				}
+
+				c.reportTTL()
 			}
 		case <-c.Closed:
 			if c.Log.logDebug != nil {
@@ -45,12 +62,70 @@ func (c *Trait) reportItemsCount() {
 	}
 }
 
+// ttlQuantiles are reported with MetricTTLRemaining.
+var ttlQuantiles = []struct {
+	label string
+	q     float64
+}{
+	{label: "0.5", q: 0.5},
+	{label: "0.9", q: 0.9},
+	{label: "0.99", q: 0.99},
+}
+
+// reportTTL samples entries to report average time to live and quantiles of remaining time to expiration.
+func (c *Trait) reportTTL() {
+	if c.Config.TTLSampleSize <= 0 || c.sampleExpirations == nil {
+		return
+	}
+
+	var (
+		now       = ts(c.now())
+		ttlSum    int64
+		ttlCnt    int64
+		remaining = make([]int64, 0, c.Config.TTLSampleSize)
+	)
+
+	c.sampleExpirations(c.Config.TTLSampleSize, func(expireAt, createdAt int64) {
+		// Entries with unlimited TTL are not counted.
+		if expireAt == 0 {
+			return
+		}
+
+		if createdAt != 0 && expireAt > createdAt {
+			ttlSum += expireAt - createdAt
+			ttlCnt++
+		}
+
+		if expireAt > now {
+			remaining = append(remaining, expireAt-now)
+		}
+	})
+
+	if ttlCnt > 0 {
+		c.Stat.Set(bgCtx, MetricAvgTTL, time.Duration(ttlSum/ttlCnt).Seconds(), "name", c.Config.Name)
+	}
+
+	if len(remaining) == 0 {
+		return
+	}
+
+	sort.Slice(remaining, func(i, j int) bool {
+		return remaining[i] < remaining[j]
+	})
+
+	for _, q := range ttlQuantiles {
+		r := remaining[int(q.q*float64(len(remaining)-1))]
+
+		c.Stat.Set(bgCtx, MetricTTLRemaining, time.Duration(r).Seconds(), "name", c.Config.Name, "quantile", q.label)
+	}
+}
+
 func (c *Trait) janitor() {
 	for {
-		interval := c.Config.DeleteExpiredJobInterval
+		interval := c.intervalJitter(c.Config.DeleteExpiredJobInterval)
 
 		select {
-		case <-time.After(interval):
+		case <-c.after(interval):
 			c.invokeCleanup()
 
 		case <-c.Closed:
@@ -64,12 +139,45 @@ func (c *Trait) janitor() {
 	}
 }
 
+func (c *Trait) decayCounters() {
+	for {
+		select {
+		case <-c.after(c.Config.LFUDecayInterval):
+			c.DecayCounters()
+
+		case <-c.Closed:
+			return
+		}
+	}
+}
+
+// halveCounter divides usage counter by two.
+func halveCounter(cnt *int64) {
+	for {
+		v := atomic.LoadInt64(cnt)
+		if atomic.CompareAndSwapInt64(cnt, v, v/2) {
+			return
+		}
+	}
+}
+
 func (c *Trait) invokeCleanup() {
 	// Delete expired job is skipped for UnlimitedTTL with a proof of no expirations were set before.
 	// This is an optimization to avoid full scan and make eviction checks/cleanups cheap.
 	if c.DeleteExpired != nil && (c.Config.TimeToLive != UnlimitedTTL || atomic.LoadInt64(&c.expirationsSet) > 0) {
-		expirationBoundary := time.Now().Add(-c.Config.DeleteExpiredAfter)
-		c.DeleteExpired(expirationBoundary)
+		expirationBoundary := c.now().Add(-c.Config.DeleteExpiredAfter)
+
+		atomic.StoreInt64(c.pinned, 0)
+
+		if c.expIndex != nil && c.deleteExpiredKeys != nil {
+			if keys := c.expIndex.take(ts(expirationBoundary)); len(keys) > 0 {
+				c.notifyDeletedExpired(c.deleteExpiredKeys(keys, ts(expirationBoundary)))
+			}
+		} else {
+			c.DeleteExpired(expirationBoundary)
+		}
+
+		c.notifyPinned()
 	}
 
 	if c.Evict == nil {
@@ -79,9 +187,10 @@ func (c *Trait) invokeCleanup() {
 	start := time.Now()
 	ho := c.heapInUseOverflow()
 	currentCnt, co := c.countOverflow()
+	currentBytes, bo := c.bytesOverflow()
 	so := c.sysOverflow()
 
-	if ho || so || co || (c.Config.EvictionNeeded != nil && c.Config.EvictionNeeded()) {
+	if ho || so || co || bo || c.evictionNeeded() {
 		frac := c.Config.EvictFraction
 		if frac == 0 {
 			frac = 0.1
@@ -95,18 +204,368 @@ func (c *Trait) invokeCleanup() {
 			frac = 1 - targetCnt/float64(currentCnt)
 		}
 
-		cnt := c.Evict(frac)
+		// Same as for count, eviction by size aims at the level below MaxBytes, assuming entries of similar size.
+		if bo && !co {
+			targetBytes := float64(c.Config.MaxBytes) * (1 - frac)
+			frac = 1 - targetBytes/float64(currentBytes)
+		}
+
+		reason := EvictReasonCapacity
+		if ho || so || bo {
+			reason = EvictReasonMemory
+		}
+
+		cnt := c.evict(frac, reason)
 
 		if so {
 			debug.FreeOSMemory()
 		}
 
+		trigger := EvictTriggerCustom
+
+		switch {
+		case ho:
+			trigger = EvictTriggerHeap
+		case so:
+			trigger = EvictTriggerSys
+		case co:
+			trigger = EvictTriggerCount
+		case bo:
+			trigger = EvictTriggerBytes
+		}
+
+		c.notifyEvicted(bgCtx, start, cnt, trigger)
+	}
+}
+
+// evict invokes Evict and notifies Config.OnEvicted about evicted entries after eviction lock is released.
+func (c *Trait) evict(fraction float64, reason EvictReason) int {
+	c.evicting.Lock()
+	cnt := c.Evict(fraction)
+	evicted := c.takeEvicted()
+	c.evicting.Unlock()
+
+	for _, e := range evicted {
+		c.notifyRemoved(e.key, e.value, reason)
+	}
+
+	return cnt
+}
+
+// evicted is called by backend during Evict to collect evicted entry for Config.OnEvicted.
+func (c *Trait) evicted(key []byte, value interface{}) {
+	if c.Config.OnEvicted == nil {
+		return
+	}
+
+	c.evicting.evicted = append(c.evicting.evicted, evictedEntry{key: key, value: value})
+}
+
+func (c *Trait) takeEvicted() []evictedEntry {
+	evicted := c.evicting.evicted
+	c.evicting.evicted = nil
+
+	return evicted
+}
+
+// skipWrite checks if write is disabled in context.
+func (c *Trait) skipWrite(ctx context.Context, key []byte) bool {
+	if !SkipWrite(ctx) {
+		return false
+	}
+
+	if c.Log.logDebug != nil {
+		c.Log.logDebug(ctx, "cache write skipped",
+			"name", c.Config.Name,
+			"key", string(key),
+		)
+	}
+
+	return true
+}
+
+// writeKey checks size of a written key with Config.MaxKeySize and transforms it with key.
+func (c *Trait) writeKey(ctx context.Context, k []byte) ([]byte, error) {
+	if !c.Config.MaxKeySizeAfterKeyFunc {
+		if err := c.checkKeySize(ctx, k); err != nil {
+			return nil, err
+		}
+	}
+
+	k = c.key(ctx, k)
+
+	if c.Config.MaxKeySizeAfterKeyFunc {
+		if err := c.checkKeySize(ctx, k); err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// checkKeySize rejects keys longer than Config.MaxKeySize.
+func (c *Trait) checkKeySize(ctx context.Context, key []byte) error {
+	if c.Config.MaxKeySize <= 0 || len(key) <= c.Config.MaxKeySize {
+		return nil
+	}
+
+	if c.Log.logWarn != nil {
+		c.Log.logWarn(ctx, "rejected cache key",
+			"name", c.Config.Name,
+			"keyPrefix", string(key[:c.Config.MaxKeySize]),
+			"size", len(key),
+			"maxSize", c.Config.MaxKeySize,
+		)
+	}
+
+	if c.Stat != nil {
+		c.Stat.Add(ctx, MetricRejected, 1, "name", c.Config.Name)
+	}
+
+	return ErrKeyTooLarge
+}
+
+// key prepends context key prefix and applies Config.KeyFunc to a key.
+func (c *Trait) key(ctx context.Context, k []byte) []byte {
+	if p := KeyPrefix(ctx); len(p) > 0 {
+		k = append(p[:len(p):len(p)], k...)
+	}
+
+	if c.Config.KeyFunc == nil {
+		return k
+	}
+
+	return c.keyFunc(k)
+}
+
+// keyFunc applies Config.KeyFunc, original key is kept if recovered callback panics.
+func (c *Trait) keyFunc(k []byte) (res []byte) {
+	res = k
+
+	defer c.recoverCallback("KeyFunc")
+
+	return c.Config.KeyFunc(k)
+}
+
+// evictionNeeded checks Config.EvictionNeeded.
+func (c *Trait) evictionNeeded() (needed bool) {
+	if c.Config.EvictionNeeded == nil {
+		return false
+	}
+
+	defer c.recoverCallback("EvictionNeeded")
+
+	return c.Config.EvictionNeeded()
+}
+
+// recoverCallback recovers and logs panic of a user callback if Config.RecoverCallbacks is enabled.
+//
+// It must be deferred directly.
+func (c *Trait) recoverCallback(callback string) {
+	if !c.Config.RecoverCallbacks {
+		return
+	}
+
+	if r := recover(); r != nil && c.Log.logImportant != nil {
+		c.Log.logImportant(bgCtx, "cache callback panicked",
+			"name", c.Config.Name,
+			"callback", callback,
+			"panic", r,
+		)
+	}
+}
+
+// canEvict consults Config.CanEvict, panic of Config.CanEvict allows eviction with RecoverCallbacks.
+func (c *Trait) canEvict(e Entry) (can bool) {
+	if c.Config.CanEvict == nil {
+		return true
+	}
+
+	can = true
+
+	defer c.recoverCallback("CanEvict")
+
+	return c.Config.CanEvict(e)
+}
+
+// pinnedExpired reports whether expired entry is pinned by Config.CanEvict and counts it for notifyPinned.
+func (c *Trait) pinnedExpired(e Entry) bool {
+	if c.canEvict(e) {
+		return false
+	}
+
+	atomic.AddInt64(c.pinned, 1)
+
+	return true
+}
+
+// notifyPinned logs a warning if expired entries pinned by Config.CanEvict exceed Config.PinnedWarnThreshold.
+func (c *Trait) notifyPinned() {
+	threshold := c.Config.PinnedWarnThreshold
+	if threshold == 0 {
+		threshold = 1000
+	}
+
+	if cnt := atomic.LoadInt64(c.pinned); cnt > int64(threshold) && c.Log.logWarn != nil {
+		c.Log.logWarn(bgCtx, "too many expired cache entries are pinned",
+			"name", c.Config.Name,
+			"count", cnt,
+		)
+	}
+}
+
+// equalWrite reports whether write of a value can only refresh expiration of the existing entry,
+// see Config.SkipEqualWrites.
+func (c *Trait) equalWrite(ctx context.Context, prev, value interface{}, prevGeneration uint64, prevGrace int64) bool {
+	if prevGeneration != c.Config.Generation || prevGrace != int64(GracePeriod(ctx)) {
+		return false
+	}
+
+	return c.valuesEqual(prev, value)
+}
+
+// valuesEqual compares values with Config.Equal, panic of Config.Equal counts as not equal with RecoverCallbacks.
+func (c *Trait) valuesEqual(a, b interface{}) (equal bool) {
+	if c.Config.Equal != nil {
+		defer c.recoverCallback("Equal")
+
+		return c.Config.Equal(a, b)
+	}
+
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+
+		return ok && av == bv
+	case []byte:
+		bv, ok := b.([]byte)
+
+		return ok && bytes.Equal(av, bv)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// notifyRemoved invokes Config.OnEvicted for a removed entry, it must be called outside of locks.
+func (c *Trait) notifyRemoved(key []byte, value interface{}, reason EvictReason) {
+	if c.Config.OnEvicted == nil {
+		return
+	}
+
+	if _, ok := value.(missMarker); ok {
+		return
+	}
+
+	defer c.recoverCallback("OnEvicted")
+
+	c.Config.OnEvicted(key, value, reason)
+}
+
+func (c *Trait) notifyEvicted(ctx context.Context, start time.Time, cnt int, trigger string) {
+	atomic.AddInt64(&c.counters.evictions, int64(cnt))
+
+	if c.Stat != nil {
+		c.Stat.Add(ctx, MetricEvict, float64(cnt), "name", c.Config.Name, "trigger", trigger)
+		c.Stat.Add(ctx, MetricEvictionElapsedSeconds, time.Since(start).Seconds(),
+			"name", c.Config.Name)
+
+		if trigger == EvictTriggerHeap {
+			c.Stat.Add(ctx, MetricEvictHeap, float64(cnt), "name", c.Config.Name)
+		}
+	}
+
+	if c.Config.OnEvict != nil {
+		defer c.recoverCallback("OnEvict")
+
+		c.Config.OnEvict(trigger, cnt)
+	}
+}
+
+// fitHardLimit is called after a new entry was added to keep count of entries within Config.CountHardLimit.
+//
+// Excessive entries are evicted, or the new entry is removed with undo and ErrCacheFull is returned
+// if Config.RejectOnFull is enabled.
+func (c *Trait) fitHardLimit(ctx context.Context, undo func()) error {
+	if c.Config.CountHardLimit == 0 || c.Len == nil {
+		return nil
+	}
+
+	limit := int(c.Config.CountHardLimit)
+
+	if c.Len() <= limit {
+		return nil
+	}
+
+	if c.Config.RejectOnFull || c.Evict == nil {
+		undo()
+
 		if c.Stat != nil {
-			c.Stat.Add(context.Background(), MetricEvict, float64(cnt), "name", c.Config.Name)
-			c.Stat.Add(context.Background(), MetricEvictionElapsedSeconds, time.Since(start).Seconds(),
-				"name", c.Config.Name)
+			c.Stat.Add(ctx, MetricRejected, 1, "name", c.Config.Name)
 		}
+
+		return ErrCacheFull
+	}
+
+	// Serializing eviction so that concurrent writers do not evict more than needed.
+	c.evicting.Lock()
+
+	start := time.Now()
+
+	cnt := c.Len()
+	if cnt <= limit {
+		c.evicting.Unlock()
+
+		return nil
+	}
+
+	// Half an entry is added to compensate rounding down of evicted count.
+	evicted := c.Evict((float64(cnt-limit) + 0.5) / float64(cnt))
+	removed := c.takeEvicted()
+	c.evicting.Unlock()
+
+	for _, e := range removed {
+		c.notifyRemoved(e.key, e.value, EvictReasonCapacity)
+	}
+
+	c.notifyEvicted(ctx, start, evicted, EvictTriggerWrite)
+
+	return nil
+}
+
+// evictMinCreated returns creation timestamp after which entries are protected from eviction by Config.EvictMinAge.
+func (c *Trait) evictMinCreated() int64 {
+	if c.Config.EvictMinAge <= 0 {
+		return math.MaxInt64
+	}
+
+	return ts(c.now().Add(-c.Config.EvictMinAge))
+}
+
+// evictCount calculates number of entries to evict with a fraction of total count.
+//
+// At least Config.EvictMinItems (default 1) are evicted for a positive fraction, so that eviction of a small cache
+// makes progress.
+func (c *Trait) evictCount(total int, fraction float64) int {
+	if fraction <= 0 {
+		return 0
+	}
+
+	cnt := int(float64(total) * fraction)
+
+	minItems := c.Config.EvictMinItems
+	if minItems == 0 {
+		minItems = 1
 	}
+
+	if cnt < minItems {
+		cnt = minItems
+	}
+
+	if cnt > total {
+		cnt = total
+	}
+
+	return cnt
 }
 
 func (c *Trait) heapInUseOverflow() bool {
@@ -114,10 +573,7 @@ func (c *Trait) heapInUseOverflow() bool {
 		return false
 	}
 
-	m := runtime.MemStats{}
-	runtime.ReadMemStats(&m)
-
-	return m.HeapInuse > c.Config.HeapInUseSoftLimit
+	return c.readMemStats().HeapInuse > c.Config.HeapInUseSoftLimit
 }
 
 func (c *Trait) sysOverflow() bool {
@@ -125,10 +581,49 @@ func (c *Trait) sysOverflow() bool {
 		return false
 	}
 
-	m := runtime.MemStats{}
-	runtime.ReadMemStats(&m)
+	return c.readMemStats().Sys > c.Config.SysMemSoftLimit
+}
+
+// readMemStats returns memory statistics that are cached for Config.MemStatsCacheTTL,
+// so that successive checks do not stop the world to read runtime.MemStats.
+func (c *Trait) readMemStats() runtime.MemStats {
+	c.memStats.Lock()
+	defer c.memStats.Unlock()
+
+	now := time.Now()
+
+	if c.Config.MemStatsCacheTTL < 0 || now.Sub(c.memStats.readAt) >= c.Config.MemStatsCacheTTL {
+		runtime.ReadMemStats(&c.memStats.m)
+		c.memStats.readAt = now
+	}
+
+	return c.memStats.m
+}
+
+func (c *Trait) bytesOverflow() (int64, bool) {
+	if c.Config.MaxBytes <= 0 {
+		return 0, false
+	}
+
+	total := atomic.LoadInt64(c.bytes)
 
-	return m.Sys > c.Config.SysMemSoftLimit
+	return total, total > c.Config.MaxBytes
+}
+
+// entrySize estimates size of value for Config.MaxBytes, zero is returned if limit is not set.
+func (c *Trait) entrySize(value interface{}) int64 {
+	if c.Config.MaxBytes <= 0 {
+		return 0
+	}
+
+	return int64(c.sizeOf(value))
+}
+
+// addBytes changes total size of stored values, delta is a difference of entry sizes.
+func (c *Trait) addBytes(delta int64) {
+	if delta != 0 {
+		atomic.AddInt64(c.bytes, delta)
+	}
 }
 
 func (c *Trait) countOverflow() (int, bool) {
@@ -148,12 +643,141 @@ type Trait struct {
 	DeleteExpired func(before time.Time)
 	Len           func() int
 	Evict         func(fraction float64) int
+	DecayCounters func()
+
+	// deleteExpiredKeys replaces DeleteExpired if expiration index is enabled.
+	deleteExpiredKeys func(keys []string, beforeTS int64) int
+
+	// sampleExpirations calls fn for up to n entries to report Config.TTLSampleSize metrics.
+	sampleExpirations func(n int, fn func(expireAt, createdAt int64))
 
 	Config Config
 	Stat   StatsTracker
 	Log    logTrait
 
 	expirationsSet int64
+	rndState       *uint64
+	counters       *counters
+	hitWindow      *hitRatioWindow
+	evicting       *evictState
+	bytes          *int64
+	pinned         *int64
+	memStats       *memStatsCache
+	expIndex       *expirationIndex
+	closeOnce      *sync.Once
+	rnd            *rand.Rand
+	rndMu          *sync.Mutex
+}
+
+// evictState serializes evictions and collects evicted entries to notify Config.OnEvicted after eviction.
+type evictState struct {
+	sync.Mutex
+	evicted []evictedEntry
+}
+
+// memStatsCache keeps last reading of runtime.MemStats.
+type memStatsCache struct {
+	sync.Mutex
+	readAt time.Time
+	m      runtime.MemStats
+}
+
+type evictedEntry struct {
+	key   []byte
+	value interface{}
+}
+
+// counters accumulate cache activity for Stats snapshot.
+type counters struct {
+	hits      int64
+	misses    int64
+	expired   int64
+	writes    int64
+	deletes   int64
+	evictions int64
+
+	deduplicated int64
+
+	// expiredReads excludes entries expired with ExpireAll to calculate hit ratio.
+	expiredReads int64
+}
+
+// Stats returns a snapshot of cumulative cache activity counters and hit ratio of recent reads.
+func (c *Trait) Stats() CacheStats {
+	s := CacheStats{
+		Hits:      atomic.LoadInt64(&c.counters.hits),
+		Misses:    atomic.LoadInt64(&c.counters.misses),
+		Expired:   atomic.LoadInt64(&c.counters.expired),
+		Writes:    atomic.LoadInt64(&c.counters.writes),
+		Deletes:   atomic.LoadInt64(&c.counters.deletes),
+		Evictions: atomic.LoadInt64(&c.counters.evictions),
+
+		Deduplicated: atomic.LoadInt64(&c.counters.deduplicated),
+	}
+
+	if c.Len != nil {
+		s.Items = c.Len()
+	}
+
+	if c.Config.MaxBytes > 0 {
+		s.Bytes = atomic.LoadInt64(c.bytes)
+	}
+
+	s.HitRatio, _ = c.hitRatio()
+
+	return s
+}
+
+// hitRatio returns ratio of valid reads in Config.HitRatioWindow and false if there were no reads.
+func (c *Trait) hitRatio() (float64, bool) {
+	hits := atomic.LoadInt64(&c.counters.hits)
+	reads := hits + atomic.LoadInt64(&c.counters.misses) + atomic.LoadInt64(&c.counters.expiredReads)
+
+	return c.hitWindow.ratio(ts(c.now()), hits, reads)
+}
+
+// hitRatioWindow keeps snapshots of read counters to calculate hit ratio over a rolling window.
+type hitRatioWindow struct {
+	mu    sync.Mutex
+	width int64
+	snaps []readsSnapshot
+}
+
+type readsSnapshot struct {
+	at    int64
+	hits  int64
+	reads int64
+}
+
+func newHitRatioWindow(width, now int64) *hitRatioWindow {
+	return &hitRatioWindow{
+		width: width,
+		snaps: []readsSnapshot{{at: now}},
+	}
+}
+
+// ratio records current counters and returns hit ratio since the latest snapshot that is older than window.
+func (w *hitRatioWindow) ratio(now, hits, reads int64) (float64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Base snapshot is kept at the start of the window.
+	for len(w.snaps) > 1 && w.snaps[1].at <= now-w.width {
+		w.snaps = w.snaps[1:]
+	}
+
+	base := w.snaps[0]
+
+	// Snapshots are throttled to limit memory for frequent calls.
+	if now-w.snaps[len(w.snaps)-1].at >= w.width/10 {
+		w.snaps = append(w.snaps, readsSnapshot{at: now, hits: hits, reads: reads})
+	}
+
+	if reads == base.reads {
+		return 0, false
+	}
+
+	return float64(hits-base.hits) / float64(reads-base.reads), true
 }
 
 // NewTrait instantiates new Trait.
@@ -170,18 +794,58 @@ func NewTrait(config Config, options ...func(t *Trait)) *Trait {
 		config.ItemsCountReportInterval = time.Minute
 	}
 
+	if config.HitRatioWindow == 0 {
+		config.HitRatioWindow = 5 * time.Minute
+	}
+
+	if config.MemStatsCacheTTL == 0 {
+		config.MemStatsCacheTTL = time.Second
+	}
+
 	if config.ExpirationJitter == 0 {
 		config.ExpirationJitter = 0.1
 	}
 
+	if config.IntervalJitter == 0 {
+		config.IntervalJitter = 0.1
+	}
+
 	if config.TimeToLive == 0 {
 		config.TimeToLive = 5 * time.Minute
 	}
 
+	if config.Clock == nil {
+		config.Clock = realClock{}
+	}
+
 	t := &Trait{
 		Config: config,
 		Stat:   config.Stats,
 		Closed: make(chan struct{}),
+
+		evicting:  &evictState{},
+		counters:  &counters{},
+		bytes:     new(int64),
+		pinned:    new(int64),
+		memStats:  &memStatsCache{},
+		closeOnce: &sync.Once{},
+		rndState:  new(uint64),
+	}
+
+	// Counters and hit ratio window are shared by pointer, because TraitOf copies Trait
+	// while items count report is already running.
+	t.hitWindow = newHitRatioWindow(int64(config.HitRatioWindow), ts(config.Clock.Now()))
+
+	if config.ExpirationIndexBucket > 0 {
+		t.expIndex = newExpirationIndex(int64(config.ExpirationIndexBucket))
+	}
+
+	// Random state is shared by pointer, because TraitOf copies Trait while background jobs are already running.
+	*t.rndState = uint64(time.Now().UnixNano())
+
+	if config.RandSource != nil {
+		t.rnd = rand.New(config.RandSource) //nolint:gosec // Jitter does not need crypto randomness.
+		t.rndMu = &sync.Mutex{}
 	}
 	t.Log.setup(config.Logger)
 
@@ -197,25 +861,113 @@ func NewTrait(config Config, options ...func(t *Trait)) *Trait {
 		go t.janitor()
 	}
 
+	if t.DecayCounters != nil && config.EvictionStrategy == EvictLeastFrequentlyUsed && config.LFUDecayInterval > 0 {
+		go t.decayCounters()
+	}
+
 	return t
 }
 
+// Close stops background jobs, it is safe to call it multiple times.
+func (c *Trait) Close() {
+	c.closeOnce.Do(func() {
+		close(c.Closed)
+	})
+}
+
 // PrepareRead handles cached entry.
 func (c *Trait) PrepareRead(ctx context.Context, cacheEntry *TraitEntry, found bool) (interface{}, error) {
-	if !found {
-		if c.Log.logDebug != nil {
+	return c.prepareRead(ctx, nil, cacheEntry, found)
+}
+
+// Key returns effective key of an entry with context prefix (see WithKeyPrefix) and Config.KeyFunc applied.
+//
+// It is intended for cache backends implemented outside of this package.
+func (c *Trait) Key(ctx context.Context, key []byte) []byte {
+	return c.key(ctx, key)
+}
+
+// PrepareWrite validates write and returns effective key of an entry, false is returned if write
+// is skipped with WithSkipWrite.
+//
+// It fails with ErrKeyTooLarge or ErrValueTooLarge if Config.MaxKeySize or Config.MaxValueSize is exceeded.
+// It is intended for cache backends implemented outside of this package.
+func (c *Trait) PrepareWrite(ctx context.Context, key []byte, value interface{}) ([]byte, bool, error) {
+	if c.skipWrite(ctx, key) {
+		return nil, false, nil
+	}
+
+	key, err := c.writeKey(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := c.prepareWrite(ctx, key, value); err != nil {
+		return nil, false, err
+	}
+
+	return key, true, nil
+}
+
+// NewEntry creates an entry of a written value with expiration time, grace period, generation and creation time,
+// time to live of an entry is returned for NotifyWritten.
+//
+// It is intended for cache backends implemented outside of this package.
+func (c *Trait) NewEntry(ctx context.Context, key []byte, value interface{}) (*TraitEntry, time.Duration) {
+	ttl, expireAt := c.expireAt(ctx)
+
+	return &TraitEntry{
+		K: key, V: value, E: expireAt, G: int64(GracePeriod(ctx)), N: c.Config.Generation, T: ts(c.now()),
+	}, ttl
+}
+
+// DeleteExpiredBefore checks if entry with expiration time and grace period should be deleted
+// by background job with a deletion boundary.
+//
+// It is intended for cache backends implemented outside of this package, please also consult CanEvict.
+func (c *Trait) DeleteExpiredBefore(expireAt, grace int64, before time.Time) bool {
+	return expireAt != 0 && c.deleteExpiredBefore(expireAt, grace, ts(before))
+}
+
+// CanEvict checks if expired entry can be deleted with Config.CanEvict, pinned entries are counted
+// for Config.PinnedWarnThreshold.
+//
+// It must be called outside of backend locks.
+func (c *Trait) CanEvict(e Entry) bool {
+	return !c.pinnedExpired(e)
+}
+
+// NotifyRemoved invokes Config.OnEvicted for a removed entry, it must be called outside of backend locks.
+func (c *Trait) NotifyRemoved(key []byte, value interface{}, reason EvictReason) {
+	c.notifyRemoved(key, value, reason)
+}
+
+// MissingDelete returns result of Delete for a non-existent key, nil with Config.IgnoreMissingDelete or ErrNotFound.
+func (c *Trait) MissingDelete() error {
+	return c.missingDelete()
+}
+
+// NotifyDeletedExpired collects metrics of expired entries deleted by background job.
+func (c *Trait) NotifyDeletedExpired(cnt int) {
+	c.notifyDeletedExpired(cnt)
+}
+
+// prepareRead handles cached entry, key is used for metrics and can be nil.
+func (c *Trait) prepareRead(ctx context.Context, key []byte, cacheEntry *TraitEntry, found bool) (interface{}, error) {
+	now := ts(c.now())
+
+	// Entry of a different generation or older than allowed by context is considered missing.
+	if !found || (cacheEntry != nil && (cacheEntry.N != c.Config.Generation || tooStale(ctx, cacheEntry.T, now))) {
+		if c.logReadDebug() {
 			c.Log.logDebug(ctx, "cache miss", "name", c.Config.Name)
 		}
 
-		if c.Stat != nil {
-			c.Stat.Add(ctx, MetricMiss, 1, "name", c.Config.Name)
-		}
+		atomic.AddInt64(&c.counters.misses, 1)
+		c.countRead(ctx, MetricMiss, key)
 
 		return nil, ErrNotFound
 	}
 
-	now := ts(time.Now())
-
 	if cacheEntry != nil && c.Config.EvictionStrategy != EvictMostExpired {
 		switch c.Config.EvictionStrategy {
 		case EvictLeastRecentlyUsed:
@@ -226,10 +978,13 @@ func (c *Trait) PrepareRead(ctx context.Context, cacheEntry *TraitEntry, found b
 	}
 
 	if cacheEntry.E != 0 && cacheEntry.E < now {
-		if c.Log.logDebug != nil {
+		if c.logReadDebug() {
 			c.Log.logDebug(ctx, "cache key expired", "name", c.Config.Name)
 		}
 
+		atomic.AddInt64(&c.counters.expired, 1)
+		atomic.AddInt64(&c.counters.expiredReads, 1)
+
 		if c.Stat != nil {
 			c.Stat.Add(ctx, MetricExpired, 1, "name", c.Config.Name)
 		}
@@ -237,11 +992,18 @@ func (c *Trait) PrepareRead(ctx context.Context, cacheEntry *TraitEntry, found b
 		return nil, errExpired{entry: cacheEntry}
 	}
 
-	if c.Stat != nil {
-		c.Stat.Add(ctx, MetricHit, 1, "name", c.Config.Name)
+	atomic.AddInt64(&c.counters.hits, 1)
+	c.countRead(ctx, MetricHit, cacheEntry.K)
+
+	if softAt := c.softExpireAt(cacheEntry.E, cacheEntry.T); softAt != 0 && softAt <= now {
+		if c.logReadDebug() {
+			c.Log.logDebug(ctx, "cache key soft expired", "name", c.Config.Name)
+		}
+
+		return nil, errSoftExpired{entry: cacheEntry, at: softAt}
 	}
 
-	if c.Log.logDebug != nil {
+	if c.logReadDebug() {
 		c.Log.logDebug(ctx, "cache hit",
 			"name", c.Config.Name,
 			"entry", cacheEntry,
@@ -251,14 +1013,48 @@ func (c *Trait) PrepareRead(ctx context.Context, cacheEntry *TraitEntry, found b
 	return cacheEntry.V, nil
 }
 
+// keyGroup applies Config.MetricKeyGroupFunc, empty group is returned if recovered callback panics.
+func (c *Trait) keyGroup(key []byte) (group string) {
+	defer c.recoverCallback("MetricKeyGroupFunc")
+
+	return c.Config.MetricKeyGroupFunc(key)
+}
+
+// countRead adds read metric with optional key group label.
+func (c *Trait) countRead(ctx context.Context, name string, key []byte) {
+	if c.Stat == nil {
+		return
+	}
+
+	if c.Config.MetricKeyGroupFunc != nil && key != nil {
+		c.Stat.Add(ctx, name, 1, "name", c.Config.Name, "group", c.keyGroup(key))
+
+		return
+	}
+
+	c.Stat.Add(ctx, name, 1, "name", c.Config.Name)
+}
+
 func (c *Trait) expireAt(ctx context.Context) (time.Duration, int64) {
 	if ttl := c.TTL(ctx); ttl != 0 {
-		return ttl, ts(time.Now().Add(ttl))
+		return ttl, ts(c.now().Add(ttl))
 	}
 
 	return 0, 0
 }
 
+// counter returns initial usage counter for a new entry.
+//
+// With EvictLeastRecentlyUsed new entry is considered as recently used, so that it is not evicted before
+// entries that were served long ago.
+func (c *Trait) counter() int64 {
+	if c.Config.EvictionStrategy == EvictLeastRecentlyUsed {
+		return ts(c.now())
+	}
+
+	return 0
+}
+
 // TTL calculates time to live for a new entry.
 func (c *Trait) TTL(ctx context.Context) time.Duration {
 	ttl := TTL(ctx)
@@ -270,8 +1066,87 @@ func (c *Trait) TTL(ctx context.Context) time.Duration {
 		ttl = c.Config.TimeToLive
 	}
 
-	if c.Config.ExpirationJitter > 0 {
-		ttl += time.Duration(float64(ttl) * c.Config.ExpirationJitter * (rand.Float64() - 0.5)) //nolint:gosec
+	return c.jitter(ctx, c.scaleTTL(ctx, ttl))
+}
+
+// expireAtTTL calculates expiration timestamp for explicit ttl.
+//
+// UnlimitedTTL disables expiration and DefaultTTL is replaced with config TimeToLive.
+func (c *Trait) expireAtTTL(ctx context.Context, ttl time.Duration) (time.Duration, int64) {
+	if ttl == DefaultTTL {
+		ttl = c.Config.TimeToLive
+	}
+
+	if ttl == UnlimitedTTL {
+		return 0, 0
+	}
+
+	ttl = c.jitter(ctx, c.scaleTTL(ctx, ttl))
+
+	return ttl, ts(c.now().Add(ttl))
+}
+
+// randFloat64 returns a pseudo-random number in [0.0,1.0) from Config.RandSource or from internal generator.
+//
+// Internal generator is a lock-free splitmix64 to avoid contention of concurrent writes.
+func (c *Trait) randFloat64() float64 {
+	if c.rnd == nil {
+		z := atomic.AddUint64(c.rndState, 0x9e3779b97f4a7c15)
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		z ^= z >> 31
+
+		return float64(z>>11) / (1 << 53)
+	}
+
+	// rand.Source is not safe for concurrent use.
+	c.rndMu.Lock()
+	defer c.rndMu.Unlock()
+
+	return c.rnd.Float64()
+}
+
+// logReadDebug checks if debug log of a cache read should be emitted with Config.LogSampleRate.
+func (c *Trait) logReadDebug() bool {
+	if c.Log.logDebug == nil {
+		return false
+	}
+
+	if c.Config.LogSampleRate <= 0 || c.Config.LogSampleRate >= 1 {
+		return true
+	}
+
+	return c.randFloat64() < c.Config.LogSampleRate
+}
+
+// scaleTTL applies TTL multiplier from context.
+//
+// Result is clamped to a positive duration that does not overflow expiration timestamp.
+func (c *Trait) scaleTTL(ctx context.Context, ttl time.Duration) time.Duration {
+	f := TTLMultiplier(ctx)
+	if f == 1 || ttl <= 0 {
+		return ttl
+	}
+
+	scaled := float64(ttl) * f
+
+	// Reserving room for jitter.
+	limit := (math.MaxInt64 - ts(c.now())) / 2
+
+	switch {
+	case scaled >= float64(limit):
+		return time.Duration(limit)
+	case scaled < 1:
+		return 1
+	}
+
+	return time.Duration(scaled)
+}
+
+// jitter randomly alters ttl with configured ExpirationJitter, unless disabled with WithNoJitter.
+func (c *Trait) jitter(ctx context.Context, ttl time.Duration) time.Duration {
+	if c.Config.ExpirationJitter > 0 && !NoJitter(ctx) {
+		ttl += time.Duration(float64(ttl) * c.Config.ExpirationJitter * (c.randFloat64() - 0.5))
 	}
 
 	if c.Config.TimeToLive == UnlimitedTTL && ttl != 0 {
@@ -281,6 +1156,158 @@ func (c *Trait) TTL(ctx context.Context) time.Duration {
 	return ttl
 }
 
+// deleteExpiredBefore checks if entry has expired before deletion boundary.
+//
+// Grace period of entry, if set, overrides Config.DeleteExpiredAfter that was used for boundary.
+func (c *Trait) deleteExpiredBefore(expireAt, grace, beforeTS int64) bool {
+	return c.deleteDue(expireAt, grace) < beforeTS
+}
+
+// deleteDue returns timestamp of entry deletion, that is comparable with deletion boundary of background job.
+func (c *Trait) deleteDue(expireAt, grace int64) int64 {
+	if grace == 0 {
+		return expireAt
+	}
+
+	return expireAt + grace - int64(c.Config.DeleteExpiredAfter)
+}
+
+// softExpireAt returns soft expiration timestamp of entry, or 0 if Config.SoftTTLFraction is not set.
+//
+// Soft expiration is derived from creation and expiration timestamps, so it is not stored in entry.
+func (c *Trait) softExpireAt(expireAt, created int64) int64 {
+	f := c.Config.SoftTTLFraction
+	if f <= 0 || f >= 1 || expireAt == 0 || created == 0 || expireAt <= created {
+		return 0
+	}
+
+	return created + int64(float64(expireAt-created)*f)
+}
+
+// indexExpiration adds entry to expiration index if it is enabled with Config.ExpirationIndexBucket.
+func (c *Trait) indexExpiration(key string, expireAt, grace int64) {
+	if c.expIndex == nil || expireAt == 0 {
+		return
+	}
+
+	c.expIndex.add(key, c.deleteDue(expireAt, grace))
+}
+
+// unindexExpiration removes deleted entry from expiration index.
+func (c *Trait) unindexExpiration(key string, expireAt, grace int64) {
+	if c.expIndex == nil || expireAt == 0 {
+		return
+	}
+
+	c.expIndex.remove(key, c.deleteDue(expireAt, grace))
+}
+
+// intervalJitter randomly alters interval of background job with configured IntervalJitter.
+func (c *Trait) intervalJitter(interval time.Duration) time.Duration {
+	if c.Config.IntervalJitter > 0 {
+		interval += time.Duration(float64(interval) * c.Config.IntervalJitter * (c.randFloat64() - 0.5))
+	}
+
+	return interval
+}
+
+// prepareWrite validates value before write.
+func (c *Trait) prepareWrite(ctx context.Context, key []byte, value interface{}) error {
+	if err := c.checkSize(ctx, key, value); err != nil {
+		return err
+	}
+
+	c.autoGobRegister(ctx, value)
+
+	return nil
+}
+
+// checkSize rejects values larger than Config.MaxValueSize.
+func (c *Trait) checkSize(ctx context.Context, key []byte, value interface{}) error {
+	if c.Config.MaxValueSize <= 0 {
+		return nil
+	}
+
+	size := c.sizeOf(value)
+	if size <= c.Config.MaxValueSize {
+		return nil
+	}
+
+	if c.Log.logWarn != nil {
+		c.Log.logWarn(ctx, "rejected cache value",
+			"name", c.Config.Name,
+			"key", string(key),
+			"size", size,
+			"maxSize", c.Config.MaxValueSize,
+		)
+	}
+
+	if c.Stat != nil {
+		c.Stat.Add(ctx, MetricRejected, 1, "name", c.Config.Name)
+	}
+
+	return ErrValueTooLarge
+}
+
+// sizeOf estimates value size with Config.Sizer.
+func (c *Trait) sizeOf(value interface{}) (size int) {
+	if c.Config.Sizer != nil {
+		defer c.recoverCallback("Sizer")
+
+		return c.Config.Sizer(value)
+	}
+
+	return valueSize(value)
+}
+
+// autoGobRegister registers value type with gob if Config.AutoGobRegister is enabled.
+func (c *Trait) autoGobRegister(ctx context.Context, value interface{}) {
+	if !c.Config.AutoGobRegister {
+		return
+	}
+
+	if err := autoGobRegister(value); err != nil && c.Log.logWarn != nil {
+		c.Log.logWarn(ctx, "failed to register cached type",
+			"name", c.Config.Name,
+			"error", err,
+		)
+	}
+}
+
+// valueSize is a default Config.Sizer.
+//
+// It returns length of byte slices and strings and length of gob encoding for other values.
+func valueSize(value interface{}) int {
+	switch v := value.(type) {
+	case []byte:
+		return len(v)
+	case string:
+		return len(v)
+	case nil:
+		return 0
+	}
+
+	if isNilPointer(value) {
+		return 0
+	}
+
+	var cnt countingWriter
+
+	if err := gob.NewEncoder(&cnt).Encode(value); err != nil {
+		return 0
+	}
+
+	return int(cnt)
+}
+
+type countingWriter int
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+
+	return len(p), nil
+}
+
 // NotifyWritten collects logs and metrics.
 func (c *Trait) NotifyWritten(ctx context.Context, key []byte, value interface{}, ttl time.Duration) {
 	if c.Log.logDebug != nil {
@@ -292,11 +1319,48 @@ func (c *Trait) NotifyWritten(ctx context.Context, key []byte, value interface{}
 		)
 	}
 
+	atomic.AddInt64(&c.counters.writes, 1)
+
 	if c.Stat != nil {
 		c.Stat.Add(ctx, MetricWrite, 1, "name", c.Config.Name)
 	}
 }
 
+// notifyPreloaded collects logs and metrics of a batch of written entries.
+func (c *Trait) notifyPreloaded(ctx context.Context, cnt int, ttl time.Duration) {
+	if c.Log.logDebug != nil {
+		c.Log.logDebug(ctx, "preloaded cache",
+			"name", c.Config.Name,
+			"count", cnt,
+			"ttl", ttl,
+		)
+	}
+
+	atomic.AddInt64(&c.counters.writes, int64(cnt))
+
+	if c.Stat != nil && cnt > 0 {
+		c.Stat.Add(ctx, MetricWrite, float64(cnt), "name", c.Config.Name)
+	}
+}
+
+// notifyDeduplicated collects metrics of a read that waited for an in-flight build.
+func (c *Trait) notifyDeduplicated(ctx context.Context) {
+	atomic.AddInt64(&c.counters.deduplicated, 1)
+
+	if c.Stat != nil {
+		c.Stat.Add(ctx, MetricSingleFlightDedup, 1, "name", c.Config.Name)
+	}
+}
+
+// missingDelete returns result of Delete for a non-existent key.
+func (c *Trait) missingDelete() error {
+	if c.Config.IgnoreMissingDelete {
+		return nil
+	}
+
+	return ErrNotFound
+}
+
 // NotifyDeleted collects logs and metrics.
 func (c *Trait) NotifyDeleted(ctx context.Context, key []byte) {
 	if c.Log.logDebug != nil {
@@ -306,11 +1370,26 @@ func (c *Trait) NotifyDeleted(ctx context.Context, key []byte) {
 		)
 	}
 
+	atomic.AddInt64(&c.counters.deletes, 1)
+
 	if c.Stat != nil {
 		c.Stat.Add(ctx, MetricDelete, 1, "name", c.Config.Name)
 	}
 }
 
+// notifyDeletedExpired collects metrics of a batch of expired entries deleted by background job.
+func (c *Trait) notifyDeletedExpired(cnt int) {
+	if cnt == 0 {
+		return
+	}
+
+	atomic.AddInt64(&c.counters.deletes, int64(cnt))
+
+	if c.Stat != nil {
+		c.Stat.Add(bgCtx, MetricDelete, float64(cnt), "name", c.Config.Name)
+	}
+}
+
 // NotifyExpiredAll collects logs and metrics.
 func (c *Trait) NotifyExpiredAll(ctx context.Context, start time.Time, cnt int) {
 	if c.Log.logImportant != nil {
@@ -321,11 +1400,47 @@ func (c *Trait) NotifyExpiredAll(ctx context.Context, start time.Time, cnt int)
 		)
 	}
 
+	atomic.AddInt64(&c.counters.expired, int64(cnt))
+
 	if c.Stat != nil {
 		c.Stat.Add(ctx, MetricExpired, float64(cnt), "name", c.Config.Name)
 	}
 }
 
+// notifyExpiredMatching collects logs and metrics of entries expired with ExpireMatching.
+func (c *Trait) notifyExpiredMatching(ctx context.Context, start time.Time, cnt int) {
+	if c.Log.logDebug != nil {
+		c.Log.logDebug(ctx, "expired matching entries in cache",
+			"name", c.Config.Name,
+			"elapsed", time.Since(start).String(),
+			"count", cnt,
+		)
+	}
+
+	atomic.AddInt64(&c.counters.expired, int64(cnt))
+
+	if c.Stat != nil && cnt > 0 {
+		c.Stat.Add(ctx, MetricExpired, float64(cnt), "name", c.Config.Name)
+	}
+}
+
+// notifyReplacedAll collects logs and metrics of entries written with ReplaceAll.
+func (c *Trait) notifyReplacedAll(ctx context.Context, start time.Time, cnt int) {
+	if c.Log.logImportant != nil {
+		c.Log.logImportant(ctx, "replaced all entries in cache",
+			"name", c.Config.Name,
+			"elapsed", time.Since(start).String(),
+			"count", cnt,
+		)
+	}
+
+	atomic.AddInt64(&c.counters.writes, int64(cnt))
+
+	if c.Stat != nil && cnt > 0 {
+		c.Stat.Add(ctx, MetricWrite, float64(cnt), "name", c.Config.Name)
+	}
+}
+
 // NotifyDeletedAll collects logs and metrics.
 func (c *Trait) NotifyDeletedAll(ctx context.Context, start time.Time, cnt int) {
 	if c.Log.logImportant != nil {
@@ -336,11 +1451,31 @@ func (c *Trait) NotifyDeletedAll(ctx context.Context, start time.Time, cnt int)
 		)
 	}
 
+	atomic.AddInt64(&c.counters.deletes, int64(cnt))
+
 	if c.Stat != nil {
 		c.Stat.Add(ctx, MetricDelete, float64(cnt), "name", c.Config.Name)
 	}
 }
 
+// NotifyDeletedPrefix collects logs and metrics.
+func (c *Trait) NotifyDeletedPrefix(ctx context.Context, start time.Time, prefix []byte, cnt int) {
+	if c.Log.logDebug != nil {
+		c.Log.logDebug(ctx, "deleted cache entries by prefix",
+			"name", c.Config.Name,
+			"prefix", string(prefix),
+			"elapsed", time.Since(start).String(),
+			"count", cnt,
+		)
+	}
+
+	atomic.AddInt64(&c.counters.deletes, int64(cnt))
+
+	if c.Stat != nil && cnt > 0 {
+		c.Stat.Add(ctx, MetricDelete, float64(cnt), "name", c.Config.Name)
+	}
+}
+
 // Key os a key of cached entry.
 type Key []byte
 
@@ -349,12 +1484,23 @@ func (ks Key) MarshalText() ([]byte, error) {
 	return ks, nil
 }
 
+// UnmarshalText loads bytes from text.
+func (ks *Key) UnmarshalText(text []byte) error {
+	*ks = append((*ks)[:0], text...)
+
+	return nil
+}
+
 // TraitEntry is a cache entry.
 type TraitEntry struct {
 	K Key         `json:"key" description:"Key."`
 	V interface{} `json:"val" description:"Value."`
 	E int64       `json:"exp" description:"Expiration timestamp (ns)."`
 	C int64       `json:"-" description:"Usage count or last serve timestamp (ns)."`
+	G int64       `json:"grace,omitempty" description:"Delay of deletion after expiration (ns)."`
+	S int64       `json:"-" description:"Approximate size of value, only set with Config.MaxBytes."`
+	N uint64      `json:"gen,omitempty" description:"Generation of entry, see Config.Generation."`
+	T int64       `json:"created,omitempty" description:"Creation timestamp (ns)."`
 }
 
 var _ Entry = TraitEntry{}
@@ -374,6 +1520,15 @@ func (e TraitEntry) ExpireAt() time.Time {
 	return tsTime(e.E)
 }
 
+// CreatedAt returns time of entry write, zero time is returned if it is unknown.
+func (e TraitEntry) CreatedAt() time.Time {
+	if e.T == 0 {
+		return time.Time{}
+	}
+
+	return tsTime(e.T)
+}
+
 type errExpired struct {
 	entry *TraitEntry
 }
@@ -394,6 +1549,28 @@ func (e errExpired) Is(err error) bool {
 	return errors.Is(err, ErrExpired)
 }
 
+type errSoftExpired struct {
+	entry *TraitEntry
+	at    int64
+}
+
+func (e errSoftExpired) Error() string {
+	return ErrSoftExpired.Error()
+}
+
+func (e errSoftExpired) Value() interface{} {
+	return e.entry.V
+}
+
+// ExpiredAt returns time of soft expiration.
+func (e errSoftExpired) ExpiredAt() time.Time {
+	return tsTime(e.at)
+}
+
+func (e errSoftExpired) Is(err error) bool {
+	return errors.Is(err, ErrSoftExpired)
+}
+
 func ts(t time.Time) int64 {
 	return t.UnixNano()
 }
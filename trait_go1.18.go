@@ -6,6 +6,7 @@ package cache
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync/atomic"
 	"time"
 )
@@ -26,20 +27,25 @@ func NewTraitOf[V any](config Config, options ...func(t *Trait)) *TraitOf[V] {
 
 // PrepareRead handles cached entry.
 func (c *TraitOf[V]) PrepareRead(ctx context.Context, cacheEntry *TraitEntryOf[V], found bool) (v V, err error) {
-	if !found {
-		if c.Log.logDebug != nil {
+	return c.prepareRead(ctx, nil, cacheEntry, found)
+}
+
+// prepareRead handles cached entry, key is used for metrics and can be nil.
+func (c *TraitOf[V]) prepareRead(ctx context.Context, key []byte, cacheEntry *TraitEntryOf[V], found bool) (v V, err error) {
+	now := ts(c.now())
+
+	// Entry of a different generation or older than allowed by context is considered missing.
+	if !found || (cacheEntry != nil && (cacheEntry.N != c.Config.Generation || tooStale(ctx, cacheEntry.T, now))) {
+		if c.logReadDebug() {
 			c.Log.logDebug(ctx, "cache miss", "name", c.Config.Name)
 		}
 
-		if c.Stat != nil {
-			c.Stat.Add(ctx, MetricMiss, 1, "name", c.Config.Name)
-		}
+		atomic.AddInt64(&c.counters.misses, 1)
+		c.countRead(ctx, MetricMiss, key)
 
 		return v, ErrNotFound
 	}
 
-	now := ts(time.Now())
-
 	if cacheEntry != nil && c.Config.EvictionStrategy != EvictMostExpired {
 		switch c.Config.EvictionStrategy {
 		case EvictLeastRecentlyUsed:
@@ -50,10 +56,13 @@ func (c *TraitOf[V]) PrepareRead(ctx context.Context, cacheEntry *TraitEntryOf[V
 	}
 
 	if cacheEntry.E != 0 && cacheEntry.E < now {
-		if c.Log.logDebug != nil {
+		if c.logReadDebug() {
 			c.Log.logDebug(ctx, "cache key expired", "name", c.Config.Name)
 		}
 
+		atomic.AddInt64(&c.counters.expired, 1)
+		atomic.AddInt64(&c.counters.expiredReads, 1)
+
 		if c.Stat != nil {
 			c.Stat.Add(ctx, MetricExpired, 1, "name", c.Config.Name)
 		}
@@ -61,11 +70,18 @@ func (c *TraitOf[V]) PrepareRead(ctx context.Context, cacheEntry *TraitEntryOf[V
 		return v, errExpiredOf[V]{entry: cacheEntry}
 	}
 
-	if c.Stat != nil {
-		c.Stat.Add(ctx, MetricHit, 1, "name", c.Config.Name)
+	atomic.AddInt64(&c.counters.hits, 1)
+	c.countRead(ctx, MetricHit, cacheEntry.K)
+
+	if softAt := c.softExpireAt(cacheEntry.E, cacheEntry.T); softAt != 0 && softAt <= now {
+		if c.logReadDebug() {
+			c.Log.logDebug(ctx, "cache key soft expired", "name", c.Config.Name)
+		}
+
+		return v, errSoftExpiredOf[V]{entry: cacheEntry, at: softAt}
 	}
 
-	if c.Log.logDebug != nil {
+	if c.logReadDebug() {
 		c.Log.logDebug(ctx, "cache hit",
 			"name", c.Config.Name,
 			"entry", cacheEntry,
@@ -86,17 +102,41 @@ func (c *TraitOf[V]) NotifyWritten(ctx context.Context, key []byte, value V, ttl
 		)
 	}
 
+	atomic.AddInt64(&c.counters.writes, 1)
+
 	if c.Stat != nil {
 		c.Stat.Add(ctx, MetricWrite, 1, "name", c.Config.Name)
 	}
 }
 
+// canEvictOf consults Config.CanEvict.
+func (c *TraitOf[V]) canEvictOf(e *TraitEntryOf[V]) bool {
+	if c.Config.CanEvict == nil {
+		return true
+	}
+
+	return c.canEvict(e.untyped())
+}
+
+// pinnedExpiredOf reports whether expired entry is pinned by Config.CanEvict.
+func (c *TraitOf[V]) pinnedExpiredOf(e *TraitEntryOf[V]) bool {
+	if c.Config.CanEvict == nil {
+		return false
+	}
+
+	return c.pinnedExpired(e.untyped())
+}
+
 // TraitEntryOf is a cache entry.
 type TraitEntryOf[V any] struct {
-	K Key   `json:"key" description:"Cache entry key."`
-	V V     `json:"val" description:"Cache entry value."`
-	E int64 `json:"exp" description:"Expiration timestamp, ns."`
-	C int64 `json:"-" description:"Usage count or last serve timestamp (ns)."`
+	K Key    `json:"key" description:"Cache entry key."`
+	V V      `json:"val" description:"Cache entry value."`
+	E int64  `json:"exp" description:"Expiration timestamp, ns."`
+	C int64  `json:"-" description:"Usage count or last serve timestamp (ns)."`
+	G int64  `json:"grace,omitempty" description:"Delay of deletion after expiration, ns."`
+	S int64  `json:"-" description:"Approximate size of value, only set with Config.MaxBytes."`
+	N uint64 `json:"gen,omitempty" description:"Generation of entry, see Config.Generation."`
+	T int64  `json:"created,omitempty" description:"Creation timestamp, ns."`
 }
 
 var _ EntryOf[any] = TraitEntryOf[any]{}
@@ -116,6 +156,32 @@ func (e TraitEntryOf[V]) ExpireAt() time.Time {
 	return tsTime(e.E)
 }
 
+// CreatedAt returns time of entry write, zero time is returned if it is unknown.
+func (e TraitEntryOf[V]) CreatedAt() time.Time {
+	if e.T == 0 {
+		return time.Time{}
+	}
+
+	return tsTime(e.T)
+}
+
+// untyped converts entry to TraitEntry, for example for custom Serializer.
+func (e TraitEntryOf[V]) untyped() TraitEntry {
+	return TraitEntry{K: e.K, V: e.V, E: e.E, G: e.G, N: e.N, T: e.T}
+}
+
+// setUntyped sets entry decoded by custom Serializer, it fails if value is not of type V.
+func (e *TraitEntryOf[V]) setUntyped(te TraitEntry) error {
+	v, ok := te.V.(V)
+	if !ok {
+		return fmt.Errorf("%w: %T for key %q", ErrUnexpectedType, te.V, te.K)
+	}
+
+	*e = TraitEntryOf[V]{K: te.K, V: v, E: te.E, G: te.G, N: te.N, T: te.T}
+
+	return nil
+}
+
 var _ ErrWithExpiredItemOf[any] = errExpiredOf[any]{}
 
 type errExpiredOf[V any] struct {
@@ -137,3 +203,27 @@ func (e errExpiredOf[V]) ExpiredAt() time.Time {
 func (e errExpiredOf[V]) Is(err error) bool {
 	return errors.Is(err, ErrExpired)
 }
+
+var _ ErrWithExpiredItemOf[any] = errSoftExpiredOf[any]{}
+
+type errSoftExpiredOf[V any] struct {
+	entry *TraitEntryOf[V]
+	at    int64
+}
+
+func (e errSoftExpiredOf[V]) Error() string {
+	return ErrSoftExpired.Error()
+}
+
+func (e errSoftExpiredOf[V]) Value() V {
+	return e.entry.V
+}
+
+// ExpiredAt returns time of soft expiration.
+func (e errSoftExpiredOf[V]) ExpiredAt() time.Time {
+	return tsTime(e.at)
+}
+
+func (e errSoftExpiredOf[V]) Is(err error) bool {
+	return errors.Is(err, ErrSoftExpired)
+}
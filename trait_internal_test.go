@@ -0,0 +1,57 @@
+package cache //nolint:testpackage // Testing internals.
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bool64/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrait_reportItemsCount_noLen(t *testing.T) {
+	st := &stats.TrackerMock{}
+	tr := NewTrait(Config{Stats: st})
+
+	tr.Close()
+
+	assert.NotPanics(t, tr.reportItemsCount)
+	assert.Equal(t, 0, st.Int(MetricItems))
+}
+
+func TestTrait_intervalJitter(t *testing.T) {
+	tr := NewTrait(Config{})
+	defer tr.Close()
+
+	seen := map[time.Duration]bool{}
+
+	for i := 0; i < 100; i++ {
+		d := tr.intervalJitter(time.Minute)
+		assert.GreaterOrEqual(t, d, 57*time.Second)
+		assert.LessOrEqual(t, d, 63*time.Second)
+
+		seen[d] = true
+	}
+
+	assert.Greater(t, len(seen), 1)
+
+	tr = NewTrait(Config{IntervalJitter: -1})
+	defer tr.Close()
+
+	assert.Equal(t, time.Minute, tr.intervalJitter(time.Minute))
+}
+
+func TestTrait_readMemStats(t *testing.T) {
+	tr := NewTrait(Config{MemStatsCacheTTL: time.Hour})
+	defer tr.Close()
+
+	m1 := tr.readMemStats()
+	_ = make([]byte, 1e6)
+	m2 := tr.readMemStats()
+
+	assert.Equal(t, m1.Mallocs, m2.Mallocs)
+
+	tr.Config.MemStatsCacheTTL = -1
+
+	m3 := tr.readMemStats()
+	assert.Greater(t, m3.Mallocs, m1.Mallocs)
+}
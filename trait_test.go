@@ -0,0 +1,178 @@
+package cache_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/ctxd"
+	"github.com/bool64/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrait_TTL_randSource(t *testing.T) {
+	ctx := context.Background()
+
+	newTrait := func() *cache.Trait {
+		return cache.NewTrait(cache.Config{
+			TimeToLive:       time.Minute,
+			ExpirationJitter: 0.5,
+			RandSource:       rand.NewSource(1),
+		})
+	}
+
+	t1, t2 := newTrait(), newTrait()
+
+	for i := 0; i < 10; i++ {
+		ttl := t1.TTL(ctx)
+
+		assert.Equal(t, ttl, t2.TTL(ctx))
+		assert.InDelta(t, float64(time.Minute), float64(ttl), float64(15*time.Second))
+	}
+}
+
+func TestConfig_SoftTTLFraction(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	start := clock.Now()
+
+	cfg := func(cfg *cache.Config) {
+		cfg.Clock = clock
+		cfg.TimeToLive = 10 * time.Minute
+		cfg.ExpirationJitter = -1
+		cfg.SoftTTLFraction = 0.8
+	}
+
+	c := cache.NewShardedMap(cfg)
+
+	assert.NoError(t, c.Write(ctx, []byte("foo"), 1))
+
+	clock.Add(7 * time.Minute)
+
+	v, err := c.Read(ctx, []byte("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	clock.Add(2 * time.Minute)
+
+	_, err = c.Read(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrSoftExpired)
+	assert.NotErrorIs(t, err, cache.ErrExpired)
+
+	v, at, ok := cache.StaleValue(err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.True(t, start.Add(8*time.Minute).Equal(at))
+
+	clock.Add(2 * time.Minute)
+
+	_, err = c.Read(ctx, []byte("foo"))
+	assert.ErrorIs(t, err, cache.ErrExpired)
+}
+
+func TestConfig_RecoverCallbacks(t *testing.T) {
+	ctx := context.Background()
+	logger := ctxd.LoggerMock{}
+	evicted := 0
+
+	c := cache.NewShardedMap(func(cfg *cache.Config) {
+		cfg.Logger = &logger
+		cfg.RecoverCallbacks = true
+		cfg.MaxBytes = 1 << 20
+		cfg.Sizer = func(v interface{}) int {
+			panic("sizer failed")
+		}
+		cfg.KeyFunc = func(key []byte) []byte {
+			panic("key func failed")
+		}
+		cfg.OnEvicted = func(key []byte, value interface{}, reason cache.EvictReason) {
+			evicted++
+
+			panic("on evicted failed")
+		}
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	require.NoError(t, c.Delete(ctx, []byte("foo")))
+	assert.Equal(t, 1, evicted)
+
+	// Shard locks are released after recovered panics.
+	require.NoError(t, c.Write(ctx, []byte("foo"), "baz"))
+	require.NoError(t, c.Delete(ctx, []byte("foo")))
+	assert.Equal(t, 2, evicted)
+
+	logger.Lock()
+	defer logger.Unlock()
+
+	assert.Contains(t, logger.String(), `"callback":"OnEvicted"`)
+	assert.Contains(t, logger.String(), `"callback":"KeyFunc"`)
+	assert.Contains(t, logger.String(), `"callback":"Sizer"`)
+}
+
+func TestConfig_SkipEqualWrites(t *testing.T) {
+	ctx := context.Background()
+
+	for _, newCache := range []func(cfg cache.Config) cache.ReadWriter{
+		func(cfg cache.Config) cache.ReadWriter { return cache.NewSyncMap(cfg.Use) },
+		func(cfg cache.Config) cache.ReadWriter { return cache.NewShardedMap(cfg.Use) },
+	} {
+		st := &stats.TrackerMock{}
+		clock := newFakeClock()
+		c := newCache(cache.Config{
+			Stats:            st,
+			Clock:            clock,
+			TimeToLive:       time.Minute,
+			ExpirationJitter: -1,
+			SkipEqualWrites:  true,
+		})
+
+		v := []int{1, 2}
+
+		require.NoError(t, c.Write(ctx, []byte("foo"), v))
+
+		clock.Add(50 * time.Second)
+
+		// Equal value refreshes expiration and keeps stored value.
+		require.NoError(t, c.Write(ctx, []byte("foo"), []int{1, 2}))
+		assert.Equal(t, 1, st.Int(cache.MetricWrite))
+
+		clock.Add(50 * time.Second)
+
+		got, err := c.Read(ctx, []byte("foo"))
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, got)
+
+		v[0] = 3
+
+		assert.Equal(t, []int{3, 2}, got, "first written value is kept")
+
+		require.NoError(t, c.Write(ctx, []byte("foo"), []int{4}))
+		assert.Equal(t, 2, st.Int(cache.MetricWrite))
+
+		// Custom comparison.
+		st = &stats.TrackerMock{}
+		c = newCache(cache.Config{
+			Stats:           st,
+			SkipEqualWrites: true,
+			Equal: func(a, b interface{}) bool {
+				return a.(int)/10 == b.(int)/10
+			},
+		})
+
+		require.NoError(t, c.Write(ctx, []byte("foo"), 11))
+		require.NoError(t, c.Write(ctx, []byte("foo"), 12))
+		assert.Equal(t, 1, st.Int(cache.MetricWrite))
+
+		got, err = c.Read(ctx, []byte("foo"))
+		require.NoError(t, err)
+		assert.Equal(t, 11, got)
+	}
+}
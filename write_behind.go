@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// WriteBehindConfig is optional configuration for NewWriteBehind.
+type WriteBehindConfig struct {
+	// Name is added to logs and stats.
+	Name string
+
+	// FlushInterval is a time interval to persist pending writes to backend, default 1 second.
+	FlushInterval time.Duration
+
+	// BatchSize is a number of pending writes that triggers flush before FlushInterval, default 100.
+	BatchSize int
+
+	// Logger collects messages with context.
+	Logger Logger
+
+	// Stats tracks stats.
+	Stats StatsTracker
+}
+
+// Use is a functional option for NewWriteBehind to apply configuration.
+func (wc WriteBehindConfig) Use(cfg *WriteBehindConfig) {
+	*cfg = wc
+}
+
+var (
+	_ ReadWriter = &WriteBehind{}
+	_ Deleter    = &WriteBehind{}
+)
+
+// WriteBehind is a cache that buffers writes in memory and persists them to a slow backend in batches.
+//
+// Write returns before value is persisted, so that latency of backend does not affect callers.
+// Pending values are served by Read immediately. Values are lost if backend fails to store them
+// (failures are logged and counted with MetricWriteBehindFailed) or if application stops without Close.
+//
+// Please use NewWriteBehind to create instance.
+type WriteBehind struct {
+	backend ReadWriter
+	config  WriteBehindConfig
+
+	// Pending writes are keyed by key with context prefix, see WithKeyPrefix.
+	mu       sync.Mutex
+	pending  map[string]pendingWrite
+	flushing map[string]pendingWrite
+
+	// flushMu serializes flushes and deletes, so that deleted key is not resurrected by an in-flight flush.
+	flushMu sync.Mutex
+
+	flushNow  chan struct{}
+	closed    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	logTrait
+
+	stat StatsTracker
+}
+
+type pendingWrite struct {
+	ctx   context.Context //nolint:containedctx // Context of write is kept to persist value with its options.
+	key   []byte
+	value interface{}
+}
+
+// NewWriteBehind creates a write-behind cache instance and starts background flusher.
+//
+// Please call Close to persist pending writes and stop background flusher.
+func NewWriteBehind(backend ReadWriter, options ...func(cfg *WriteBehindConfig)) *WriteBehind {
+	cfg := WriteBehindConfig{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = time.Second
+	}
+
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+
+	c := &WriteBehind{
+		backend:  backend,
+		config:   cfg,
+		pending:  make(map[string]pendingWrite),
+		flushNow: make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+		stat:     cfg.Stats,
+	}
+
+	c.logTrait.setup(cfg.Logger)
+
+	go c.run()
+
+	return c
+}
+
+func (c *WriteBehind) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.flushNow:
+		case <-c.closed:
+			return
+		}
+
+		_ = c.Flush() //nolint:errcheck // Failures are logged.
+	}
+}
+
+// Read gets pending value or value from backend.
+func (c *WriteBehind) Read(ctx context.Context, key []byte) (interface{}, error) {
+	if !SkipRead(ctx) {
+		k := lockKey(ctx, key)
+
+		c.mu.Lock()
+		p, found := c.pending[k]
+
+		if !found {
+			p, found = c.flushing[k]
+		}
+		c.mu.Unlock()
+
+		if found {
+			return p.value, nil
+		}
+	}
+
+	return c.backend.Read(ctx, key)
+}
+
+// Write adds value to pending writes, it is persisted synchronously if WriteBehind is closed.
+func (c *WriteBehind) Write(ctx context.Context, key []byte, v interface{}) error {
+	if SkipWrite(ctx) {
+		return nil
+	}
+
+	select {
+	case <-c.closed:
+		return c.backend.Write(ctx, key, v)
+	default:
+	}
+
+	c.mu.Lock()
+	c.pending[lockKey(ctx, key)] = pendingWrite{ctx: detachedContext{ctx}, key: append([]byte(nil), key...), value: v}
+	n := len(c.pending)
+	c.mu.Unlock()
+
+	if n >= c.config.BatchSize {
+		select {
+		case c.flushNow <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Delete removes pending value and value from backend.
+//
+// It fails with ErrNotFound if key is neither pending nor exists in backend.
+func (c *WriteBehind) Delete(ctx context.Context, key []byte) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	k := lockKey(ctx, key)
+
+	c.mu.Lock()
+	_, found := c.pending[k]
+	delete(c.pending, k)
+	c.mu.Unlock()
+
+	d, ok := c.backend.(Deleter)
+	if !ok {
+		return nil
+	}
+
+	err := d.Delete(ctx, key)
+	if found && errors.Is(err, ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// Pending returns number of writes that are not yet persisted.
+func (c *WriteBehind) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.pending) + len(c.flushing)
+}
+
+// Flush persists pending writes to backend, last backend error is returned.
+func (c *WriteBehind) Flush() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+
+		return nil
+	}
+
+	batch := c.pending
+	c.flushing = batch
+	c.pending = make(map[string]pendingWrite)
+	c.mu.Unlock()
+
+	var (
+		lastErr error
+		failed  int
+	)
+
+	for k, p := range batch {
+		if err := c.backend.Write(p.ctx, p.key, p.value); err != nil {
+			lastErr = err
+			failed++
+
+			if c.logError != nil {
+				c.logError(p.ctx, "failed to persist pending cache write",
+					"error", err,
+					"key", k,
+					"name", c.config.Name)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.flushing = nil
+	c.mu.Unlock()
+
+	if c.stat != nil {
+		if flushed := len(batch) - failed; flushed > 0 {
+			c.stat.Add(bgCtx, MetricWriteBehindFlushed, float64(flushed), "name", c.config.Name)
+		}
+
+		if failed > 0 {
+			c.stat.Add(bgCtx, MetricWriteBehindFailed, float64(failed), "name", c.config.Name)
+		}
+	}
+
+	return lastErr
+}
+
+// Close stops background flusher and persists pending writes, it is safe to call it multiple times.
+//
+// Writes after Close are persisted synchronously.
+func (c *WriteBehind) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		<-c.done
+	})
+
+	return c.Flush()
+}
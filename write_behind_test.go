@@ -0,0 +1,111 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bool64/cache"
+	"github.com/bool64/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBehind(t *testing.T) {
+	ctx := context.Background()
+	st := stats.TrackerMock{}
+	backend := cache.NewShardedMap()
+
+	c := cache.NewWriteBehind(backend, func(cfg *cache.WriteBehindConfig) {
+		cfg.Name = "wb"
+		cfg.Stats = &st
+		cfg.FlushInterval = time.Hour
+		cfg.BatchSize = 3
+	})
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+	require.NoError(t, c.Write(ctx, []byte("baz"), "qux"))
+
+	// Pending value is readable before it is persisted.
+	v, err := c.Read(ctx, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+	assert.Equal(t, 0, backend.Len())
+	assert.Equal(t, 2, c.Pending())
+
+	require.NoError(t, c.Delete(ctx, []byte("baz")))
+	assert.True(t, errors.Is(c.Delete(ctx, []byte("baz")), cache.ErrNotFound))
+
+	// Batch size triggers flush.
+	require.NoError(t, c.Write(ctx, []byte("quux"), 1))
+	require.NoError(t, c.Write(ctx, []byte("corge"), 2))
+
+	assert.Eventually(t, func() bool {
+		return backend.Len() == 3
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, c.Write(ctx, []byte("grault"), 3))
+	require.NoError(t, c.Close())
+
+	assert.Equal(t, 4, backend.Len())
+	assert.Equal(t, 0, c.Pending())
+	assert.Equal(t, 4, st.Int(cache.MetricWriteBehindFlushed, "name", "wb"))
+
+	v, err = backend.Read(ctx, []byte("grault"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	// Closed instance writes synchronously.
+	require.NoError(t, c.Write(ctx, []byte("garply"), 4))
+	assert.Equal(t, 5, backend.Len())
+}
+
+func TestWriteBehind_FlushInterval(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.NewShardedMap()
+
+	c := cache.NewWriteBehind(backend, cache.WriteBehindConfig{FlushInterval: time.Millisecond}.Use)
+	defer func() {
+		require.NoError(t, c.Close())
+	}()
+
+	require.NoError(t, c.Write(ctx, []byte("foo"), "bar"))
+
+	assert.Eventually(t, func() bool {
+		v, err := backend.Read(ctx, []byte("foo"))
+
+		return err == nil && v == "bar"
+	}, time.Second, time.Millisecond)
+}
+
+func TestWriteBehind_keyPrefix(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.NewShardedMap()
+
+	c := cache.NewWriteBehind(backend, func(cfg *cache.WriteBehindConfig) {
+		cfg.FlushInterval = time.Hour
+	})
+
+	a := cache.WithKeyPrefix(ctx, []byte("a:"))
+	b := cache.WithKeyPrefix(ctx, []byte("b:"))
+
+	require.NoError(t, c.Write(a, []byte("foo"), "bar"))
+	assert.Equal(t, 1, c.Pending())
+
+	_, err := c.Read(b, []byte("foo"))
+	assert.True(t, errors.Is(err, cache.ErrNotFound))
+
+	assert.True(t, errors.Is(c.Delete(b, []byte("foo")), cache.ErrNotFound))
+	assert.Equal(t, 1, c.Pending())
+
+	v, err := c.Read(a, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+
+	require.NoError(t, c.Close())
+
+	v, err = backend.Read(ctx, []byte("a:foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", v)
+}